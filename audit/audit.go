@@ -0,0 +1,218 @@
+// Package audit records every evaluation to an in-memory ring buffer and an optional rotating JSONL file.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/michimani/evidentlylocal/logger"
+)
+
+// Defaults of Options.
+const (
+	DefaultBufferSize = 1000
+	DefaultMaxSizeMB  = 10
+	DefaultMaxBackups = 3
+)
+
+// Record is a result of an evaluation.
+type Record struct {
+	Timestamp         time.Time `json:"timestamp"`
+	RequestID         string    `json:"requestId"`
+	Project           string    `json:"project"`
+	Feature           string    `json:"feature"`
+	EntityID          string    `json:"entityId"`
+	EvaluationContext string    `json:"evaluationContext,omitempty"`
+	Variation         string    `json:"variation"`
+	Reason            string    `json:"reason"`
+}
+
+// Recorder records results of evaluations.
+type Recorder interface {
+	Record(r Record)
+}
+
+// Query is a condition of records. Empty fields match all records.
+type Query struct {
+	Project  string
+	Feature  string
+	EntityID string
+	// Limit is the maximum number of the latest records to return. 0 means no limit.
+	Limit int
+}
+
+// Options is options of Log. File is the path of the JSONL file, and records are only kept in memory if it is empty.
+// The file is rotated when it exceeds MaxSizeMB, and MaxBackups rotated files are kept.
+// Zero values mean the defaults. Logger logs errors of writing the file, and can be nil.
+type Options struct {
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	BufferSize int
+	Logger     logger.Logger
+}
+
+var _ Recorder = (*Log)(nil)
+
+// Log is an audit log of evaluations. All methods of a nil Log do nothing.
+type Log struct {
+	mu     sync.Mutex
+	buffer []Record
+	next   int
+	full   bool
+	file   *rotatingFile
+	l      logger.Logger
+	// failing is true while records cannot be written to the file, so that the error is logged once.
+	failing bool
+}
+
+func New(opts Options) (*Log, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+
+	l := &Log{
+		buffer: make([]Record, opts.BufferSize),
+		l:      opts.Logger,
+	}
+
+	if len(opts.File) > 0 {
+		if opts.MaxSizeMB <= 0 {
+			opts.MaxSizeMB = DefaultMaxSizeMB
+		}
+		if opts.MaxBackups <= 0 {
+			opts.MaxBackups = DefaultMaxBackups
+		}
+
+		f, err := openRotatingFile(opts.File, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.file = f
+	}
+
+	return l, nil
+}
+
+// Record adds the record to the ring buffer and the file.
+// An error of writing the file does not fail the evaluation. It is logged when writing starts to fail,
+// and again when writing recovers after it.
+func (l *Log) Record(r Record) {
+	if l == nil {
+		return
+	}
+
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buffer[l.next] = r
+	l.next = (l.next + 1) % len(l.buffer)
+	if l.next == 0 {
+		l.full = true
+	}
+
+	if l.file != nil {
+		b, err := json.Marshal(r)
+		if err == nil {
+			err = l.file.writeLine(b)
+		}
+		l.observeWrite(err)
+	}
+}
+
+func (l *Log) observeWrite(err error) {
+	switch {
+	case err != nil && !l.failing:
+		l.failing = true
+		if l.l != nil {
+			l.l.Error("Failed to write audit log", err, "file", l.file.path)
+		}
+	case err == nil && l.failing:
+		l.failing = false
+		if l.l != nil {
+			l.l.Info("Audit log is written again", "file", l.file.path)
+		}
+	default:
+		// noop
+	}
+}
+
+// Query returns records in the ring buffer that match the query, from the oldest to the latest.
+func (l *Log) Query(q Query) []Record {
+	res := []Record{}
+	if l == nil {
+		return res
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start, n := 0, l.next
+	if l.full {
+		start, n = l.next, len(l.buffer)
+	}
+
+	for i := 0; i < n; i++ {
+		r := l.buffer[(start+i)%len(l.buffer)]
+		if q.matches(r) {
+			res = append(res, r)
+		}
+	}
+
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[len(res)-q.Limit:]
+	}
+
+	return res
+}
+
+// Close closes the file. It can be used as a shutdown hook of the server.
+func (l *Log) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.close()
+	l.file = nil
+
+	return err
+}
+
+func (q Query) matches(r Record) bool {
+	return (len(q.Project) == 0 || q.Project == r.Project) &&
+		(len(q.Feature) == 0 || q.Feature == r.Feature) &&
+		(len(q.EntityID) == 0 || q.EntityID == r.EntityID)
+}
+
+// Recorders returns a Recorder that records to all non-nil recorders.
+func Recorders(recorders ...Recorder) Recorder {
+	rs := multiRecorder{}
+	for _, r := range recorders {
+		if r != nil {
+			rs = append(rs, r)
+		}
+	}
+
+	return rs
+}
+
+type multiRecorder []Recorder
+
+func (m multiRecorder) Record(r Record) {
+	for _, rec := range m {
+		rec.Record(r)
+	}
+}
@@ -0,0 +1,195 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func record(feature, entityID string) audit.Record {
+	return audit.Record{
+		Project:   "test-project",
+		Feature:   feature,
+		EntityID:  entityID,
+		Variation: "On",
+		Reason:    "DEFAULT",
+	}
+}
+
+func Test_Query(t *testing.T) {
+	cases := []struct {
+		name       string
+		bufferSize int
+		records    []audit.Record
+		query      audit.Query
+		expect     []string
+	}{
+		{
+			name:       "all",
+			bufferSize: 10,
+			records:    []audit.Record{record("f1", "u1"), record("f2", "u1"), record("f1", "u2")},
+			query:      audit.Query{},
+			expect:     []string{"f1/u1", "f2/u1", "f1/u2"},
+		},
+		{
+			name:       "by feature and entity ID",
+			bufferSize: 10,
+			records:    []audit.Record{record("f1", "u1"), record("f2", "u1"), record("f1", "u2"), record("f1", "u1")},
+			query:      audit.Query{Feature: "f1", EntityID: "u1"},
+			expect:     []string{"f1/u1", "f1/u1"},
+		},
+		{
+			name:       "by other project",
+			bufferSize: 10,
+			records:    []audit.Record{record("f1", "u1")},
+			query:      audit.Query{Project: "other-project"},
+			expect:     []string{},
+		},
+		{
+			name:       "latest records with limit",
+			bufferSize: 10,
+			records:    []audit.Record{record("f1", "u1"), record("f2", "u1"), record("f3", "u1")},
+			query:      audit.Query{Limit: 2},
+			expect:     []string{"f2/u1", "f3/u1"},
+		},
+		{
+			name:       "oldest records are dropped from the ring buffer",
+			bufferSize: 2,
+			records:    []audit.Record{record("f1", "u1"), record("f2", "u1"), record("f3", "u1")},
+			query:      audit.Query{},
+			expect:     []string{"f2/u1", "f3/u1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			l, err := audit.New(audit.Options{BufferSize: c.bufferSize})
+			asst.NoError(err)
+
+			for _, r := range c.records {
+				l.Record(r)
+			}
+
+			got := []string{}
+			for _, r := range l.Query(c.query) {
+				got = append(got, r.Feature+"/"+r.EntityID)
+				asst.False(r.Timestamp.IsZero())
+			}
+			asst.Equal(c.expect, got)
+		})
+	}
+}
+
+func Test_File(t *testing.T) {
+	cases := []struct {
+		name       string
+		count      int
+		maxBackups int
+		// files that should exist after recording
+		expectFiles []string
+	}{
+		{
+			name:        "not rotated",
+			count:       10,
+			maxBackups:  2,
+			expectFiles: []string{"evaluations.jsonl"},
+		},
+		{
+			name:        "rotated",
+			count:       15000,
+			maxBackups:  2,
+			expectFiles: []string{"evaluations.jsonl", "evaluations.jsonl.1", "evaluations.jsonl.2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			dir := tt.TempDir()
+			file := filepath.Join(dir, "evaluations.jsonl")
+
+			l, err := audit.New(audit.Options{File: file, MaxSizeMB: 1, MaxBackups: c.maxBackups})
+			asst.NoError(err)
+
+			for i := 0; i < c.count; i++ {
+				r := record("f1", fmt.Sprintf("user-%d", i))
+				// make a record large enough to rotate the file
+				r.EvaluationContext = fmt.Sprintf(`{"padding":"%0200d"}`, i)
+				l.Record(r)
+			}
+			asst.NoError(l.Close(context.Background()))
+
+			entries, err := os.ReadDir(dir)
+			asst.NoError(err)
+			names := []string{}
+			for _, e := range entries {
+				names = append(names, e.Name())
+				info, err := e.Info()
+				asst.NoError(err)
+				asst.LessOrEqual(info.Size(), int64(1024*1024))
+			}
+			asst.Equal(c.expectFiles, names)
+
+			// the latest record is the last line of the current file
+			f, err := os.Open(file)
+			asst.NoError(err)
+			defer f.Close()
+
+			last := audit.Record{}
+			s := bufio.NewScanner(f)
+			for s.Scan() {
+				asst.NoError(json.Unmarshal(s.Bytes(), &last))
+			}
+			asst.Equal(fmt.Sprintf("user-%d", c.count-1), last.EntityID)
+		})
+	}
+}
+
+func Test_File_RotationFailed(t *testing.T) {
+	asst := assert.New(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "evaluations.jsonl")
+	// the file cannot be renamed to the backup
+	asst.NoError(os.MkdirAll(filepath.Join(file+".1", "not-empty"), 0o755))
+
+	out := &bytes.Buffer{}
+	l, _ := logger.NewEvidentlyLocalLogger(out)
+	al, err := audit.New(audit.Options{File: file, MaxSizeMB: 1, MaxBackups: 1, Logger: l})
+	asst.NoError(err)
+
+	count := 6000
+	for i := 0; i < count; i++ {
+		r := record("f1", fmt.Sprintf("user-%d", i))
+		r.EvaluationContext = fmt.Sprintf(`{"padding":"%0200d"}`, i)
+		al.Record(r)
+	}
+	asst.NoError(al.Close(context.Background()))
+
+	// records are appended to the file that is not rotated, and the error is logged once
+	b, err := os.ReadFile(file)
+	asst.NoError(err)
+	asst.Equal(count, bytes.Count(b, []byte("\n")))
+	asst.Equal(1, bytes.Count(out.Bytes(), []byte("Failed to write audit log")))
+}
+
+func Test_Nil(t *testing.T) {
+	asst := assert.New(t)
+
+	var l *audit.Log
+	l.Record(record("f1", "u1"))
+	asst.Empty(l.Query(audit.Query{}))
+	asst.NoError(l.Close(context.Background()))
+}
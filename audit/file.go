@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// rotatingFile is a file of lines that is rotated when it exceeds maxSize.
+// Rotated files are renamed to <path>.1, <path>.2, ... from the newest one.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		f:          f,
+		size:       info.Size(),
+	}, nil
+}
+
+func (r *rotatingFile) writeLine(b []byte) error {
+	line := append(b, '\n')
+
+	// the line is still written if rotation fails, because the file is opened again
+	var rotateErr error
+	if r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		rotateErr = r.rotate()
+	}
+
+	n, err := r.f.Write(line)
+	r.size += int64(n)
+
+	return errors.Join(rotateErr, err)
+}
+
+// rotate renames the file to a backup and opens a new file.
+// If it fails, the file is opened again in append mode, so that records are still written to the path.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return errors.Join(err, r.reopen())
+	}
+
+	if err := r.backup(); err != nil {
+		return errors.Join(err, r.reopen())
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Join(err, r.reopen())
+	}
+
+	r.f = f
+	r.size = 0
+
+	return nil
+}
+
+func (r *rotatingFile) backup() error {
+	if r.maxBackups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// the oldest backup is overwritten
+	for i := r.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(backupName(r.path, i), backupName(r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(r.path, backupName(r.path, 1))
+}
+
+func (r *rotatingFile) reopen() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	r.f = f
+	r.size = info.Size()
+
+	return nil
+}
+
+func (r *rotatingFile) close() error {
+	return r.f.Close()
+}
+
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
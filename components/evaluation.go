@@ -1,35 +1,21 @@
 package components
 
 import (
+	"context"
+
 	"github.com/michimani/evidentlylocal/models"
 	"github.com/michimani/evidentlylocal/types"
 )
 
-func EvaluateFeature(feature *models.Feature, entityID string) (types.EvaluationReason, models.Variation, error) {
-	// check override rules
-	for overrideEntityID, overrideVariationName := range feature.EntityOverrides {
-		if overrideEntityID == entityID {
-			value := feature.GetValue(overrideVariationName)
-
-			v := models.Variation{
-				Name: overrideVariationName,
-				Value: map[types.VariableValueType]any{
-					feature.VariableValueType(): value,
-				},
-			}
-			return types.EvaluationReasonOverride, v, nil
-		}
+// EvaluateFeature compiles the feature and evaluates it for the entity. Each step of the evaluation is traced as a child span of ctx.
+// Use Index to evaluate features that are compiled once.
+func EvaluateFeature(ctx context.Context, feature *models.Feature, entityID string) (types.EvaluationReason, models.Variation, error) {
+	plan, err := Compile(feature)
+	if err != nil {
+		return "", models.Variation{}, err
 	}
 
-	// TODO: check percentage rules
-
-	// return default variation
-	v := models.Variation{
-		Name: feature.DefaultVariation,
-		Value: map[types.VariableValueType]any{
-			feature.VariableValueType(): feature.GetDefaultValue(),
-		},
-	}
+	reason, v := plan.Evaluate(ctx, entityID)
 
-	return types.EvaluationReasonDefault, v, nil
+	return reason, models.Variation{Name: v.Name, Value: v.Value}, nil
 }
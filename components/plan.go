@@ -0,0 +1,187 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/tracing"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// Variation is a variation of a compiled feature. It is shared by all evaluations, so it must not be changed.
+type Variation struct {
+	Name  string
+	Value types.VariableValue
+	// Encoded is Value encoded in JSON, to write responses without encoding the value for each evaluation.
+	Encoded json.RawMessage
+}
+
+// NewVariation returns the variation with the encoded value.
+func NewVariation(name string, value types.VariableValue) (*Variation, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value of variation %s: %w", name, err)
+	}
+
+	return &Variation{Name: name, Value: value, Encoded: encoded}, nil
+}
+
+// Plan is a feature compiled for evaluation. Override rules are looked up in O(1),
+// and values of variations are built and encoded once.
+type Plan struct {
+	project          string
+	feature          string
+	overrides        map[string]*Variation
+	defaultVariation *Variation
+}
+
+// Compile compiles the feature into a plan.
+func Compile(feature *models.Feature) (*Plan, error) {
+	if feature == nil {
+		return nil, errors.New("feature is nil")
+	}
+
+	variations := map[string]*Variation{}
+	variation := func(name string) (*Variation, error) {
+		if v, ok := variations[name]; ok {
+			return v, nil
+		}
+
+		v, err := NewVariation(name, types.VariableValue{feature.VariableValueType(): feature.GetValue(name)})
+		if err != nil {
+			return nil, err
+		}
+
+		variations[name] = v
+		return v, nil
+	}
+
+	p := &Plan{
+		project:   feature.Project,
+		feature:   feature.Name,
+		overrides: make(map[string]*Variation, len(feature.EntityOverrides)),
+	}
+
+	for entityID, name := range feature.EntityOverrides {
+		v, err := variation(name)
+		if err != nil {
+			return nil, err
+		}
+		p.overrides[entityID] = v
+	}
+
+	v, err := variation(feature.DefaultVariation)
+	if err != nil {
+		return nil, err
+	}
+	p.defaultVariation = v
+
+	return p, nil
+}
+
+// Evaluate evaluates the plan for the entity. Each step of the evaluation is traced as a child span of ctx.
+func (p *Plan) Evaluate(ctx context.Context, entityID string) (types.EvaluationReason, *Variation) {
+	ctx, span := tracing.Start(ctx, "evaluate feature",
+		tracing.AttributeProject.String(p.project),
+		tracing.AttributeFeature.String(p.feature),
+	)
+	defer span.End()
+
+	reason, v := p.evaluate(ctx, entityID)
+	span.SetAttributes(
+		tracing.AttributeVariation.String(v.Name),
+		tracing.AttributeReason.String(string(reason)),
+	)
+
+	return reason, v
+}
+
+func (p *Plan) evaluate(ctx context.Context, entityID string) (types.EvaluationReason, *Variation) {
+	// check override rules
+	_, span := tracing.Start(ctx, "check override rules")
+	v, ok := p.overrides[entityID]
+	span.End()
+	if ok {
+		return types.EvaluationReasonOverride, v
+	}
+
+	// TODO: check percentage rules
+
+	// return default variation
+	_, span = tracing.Start(ctx, "select default variation")
+	defer span.End()
+
+	return types.EvaluationReasonDefault, p.defaultVariation
+}
+
+// Index is plans of all features. It is built when the data is loaded or changed, so that evaluations do not read files.
+// A nil Index has no plans.
+type Index struct {
+	mu    sync.RWMutex
+	plans map[string]*Plan
+	built bool
+}
+
+func NewIndex() *Index {
+	return &Index{
+		plans: map[string]*Plan{},
+	}
+}
+
+// Build compiles all features in the bundle, and replaces the plans at once.
+// Features that cannot be compiled are not in the index, and reported as an error.
+// Plans are keyed by Project and Name of each feature, that repositories set to the directory and the file name in Snapshot.
+func (x *Index) Build(bundle *models.Bundle) error {
+	plans := make(map[string]*Plan, len(bundle.Features))
+	errs := []error{}
+	for i := range bundle.Features {
+		f := &bundle.Features[i]
+		p, err := Compile(f)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("feature %s/%s: %w", f.Project, f.Name, err))
+			continue
+		}
+		plans[indexKey(f.Project, f.Name)] = p
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.plans = plans
+	x.built = true
+
+	return errors.Join(errs...)
+}
+
+// Built reports whether the index has been built.
+func (x *Index) Built() bool {
+	if x == nil {
+		return false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.built
+}
+
+// Plan returns the plan of the feature.
+func (x *Index) Plan(project, feature string) (*Plan, bool) {
+	if x == nil {
+		return nil, false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	p, ok := x.plans[indexKey(project, feature)]
+	return p, ok
+}
+
+func indexKey(project, feature string) string {
+	return project + "/" + feature
+}
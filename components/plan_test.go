@@ -0,0 +1,137 @@
+package components_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/components"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFeature(overrides int) *models.Feature {
+	f := &models.Feature{
+		Project:          "test-project",
+		Name:             "test-feature",
+		ValueType:        types.FeatureValueTypeString,
+		DefaultVariation: "off",
+		EntityOverrides:  models.EntityOverride{"force-on": "on", "force-unknown": "unknown"},
+		Variations: []models.Variation{
+			{Name: "on", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "on-value"}},
+			{Name: "off", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "off-value"}},
+		},
+	}
+
+	for i := 0; i < overrides; i++ {
+		f.EntityOverrides[fmt.Sprintf("entity-%d", i)] = "on"
+	}
+
+	return f
+}
+
+func Test_Plan_Evaluate(t *testing.T) {
+	cases := []struct {
+		name            string
+		entityID        string
+		expectedReason  types.EvaluationReason
+		expectedName    string
+		expectedEncoded string
+	}{
+		{
+			name:            "default",
+			entityID:        "user-1",
+			expectedReason:  types.EvaluationReasonDefault,
+			expectedName:    "off",
+			expectedEncoded: `{"stringValue":"off-value"}`,
+		},
+		{
+			name:            "override",
+			entityID:        "force-on",
+			expectedReason:  types.EvaluationReasonOverride,
+			expectedName:    "on",
+			expectedEncoded: `{"stringValue":"on-value"}`,
+		},
+		{
+			name:            "override to unknown variation",
+			entityID:        "force-unknown",
+			expectedReason:  types.EvaluationReasonOverride,
+			expectedName:    "unknown",
+			expectedEncoded: `{"stringValue":null}`,
+		},
+	}
+
+	p, err := components.Compile(testFeature(0))
+	assert.NoError(t, err)
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			reason, v := p.Evaluate(context.Background(), c.entityID)
+			asst.Equal(c.expectedReason, reason)
+			asst.Equal(c.expectedName, v.Name)
+			asst.JSONEq(c.expectedEncoded, string(v.Encoded))
+
+			// the same result as evaluating the feature without compiling it
+			reason, mv, err := components.EvaluateFeature(context.Background(), testFeature(0), c.entityID)
+			asst.NoError(err)
+			asst.Equal(c.expectedReason, reason)
+			asst.Equal(models.Variation{Name: v.Name, Value: v.Value}, mv)
+		})
+	}
+}
+
+func Test_Index(t *testing.T) {
+	asst := assert.New(t)
+
+	var nilIndex *components.Index
+	asst.False(nilIndex.Built())
+	_, ok := nilIndex.Plan("test-project", "test-feature")
+	asst.False(ok)
+
+	ix := components.NewIndex()
+	asst.False(ix.Built())
+
+	asst.NoError(ix.Build(&models.Bundle{Features: []models.Feature{*testFeature(0)}}))
+	asst.True(ix.Built())
+
+	_, ok = ix.Plan("test-project", "test-feature")
+	asst.True(ok)
+	_, ok = ix.Plan("test-project", "not-exists")
+	asst.False(ok)
+
+	// plans are replaced when the index is built again
+	asst.NoError(ix.Build(&models.Bundle{}))
+	_, ok = ix.Plan("test-project", "test-feature")
+	asst.False(ok)
+}
+
+// BenchmarkEvaluate compares evaluations of a feature with many overrides, with and without compiling it.
+func BenchmarkEvaluate(b *testing.B) {
+	feature := testFeature(2500)
+	ctx := context.Background()
+
+	b.Run("feature", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := components.EvaluateFeature(ctx, feature, "user-1"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		p, err := components.Compile(feature)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.Evaluate(ctx, "user-1")
+		}
+	})
+}
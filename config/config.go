@@ -0,0 +1,431 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/quota"
+	"gopkg.in/yaml.v3"
+)
+
+// Proxy modes.
+const (
+	ProxyModeRecord = "record"
+	ProxyModeReplay = "replay"
+)
+
+// Storage backends.
+const (
+	StorageDataDir = "datadir"
+	StorageBundle  = "bundle"
+)
+
+// Log levels and formats.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// Environment variables.
+const (
+	EnvConfigFile      = "EVIDENTLY_LOCAL_CONFIG_FILE"
+	EnvPort            = "EVIDENTLY_LOCAL_PORT"
+	EnvListenAddress   = "EVIDENTLY_LOCAL_LISTEN_ADDRESS"
+	EnvStorage         = "EVIDENTLY_LOCAL_STORAGE"
+	EnvDataDirs        = "EVIDENTLY_LOCAL_DATA_DIRS"
+	EnvBundleFile      = "EVIDENTLY_LOCAL_BUNDLE_FILE"
+	EnvLogLevel        = "EVIDENTLY_LOCAL_LOG_LEVEL"
+	EnvLogFormat       = "EVIDENTLY_LOCAL_LOG_FORMAT"
+	EnvAccountID       = "EVIDENTLY_LOCAL_ACCOUNT_ID"
+	EnvRegion          = "EVIDENTLY_LOCAL_REGION"
+	EnvTLSCertFile     = "EVIDENTLY_LOCAL_TLS_CERT_FILE"
+	EnvTLSKeyFile      = "EVIDENTLY_LOCAL_TLS_KEY_FILE"
+	EnvOTLPEndpoint    = "EVIDENTLY_LOCAL_OTLP_ENDPOINT"
+	EnvAuditFile       = "EVIDENTLY_LOCAL_AUDIT_FILE"
+	EnvCoverageDir     = "EVIDENTLY_LOCAL_COVERAGE_DIR"
+	EnvProxyMode       = "EVIDENTLY_LOCAL_PROXY_MODE"
+	EnvProxyUpstream   = "EVIDENTLY_LOCAL_PROXY_UPSTREAM"
+	EnvProxyCassette   = "EVIDENTLY_LOCAL_PROXY_CASSETTE"
+	EnvEvaluationIndex = "EVIDENTLY_LOCAL_EVALUATION_INDEX"
+)
+
+const (
+	DefaultPort      = "2306"
+	DefaultDataDir   = "./data"
+	DefaultAccountID = "123456789012"
+	DefaultRegion    = "us-east-1"
+)
+
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// Config is the configuration of the server.
+type Config struct {
+	ListenAddress string     `yaml:"listenAddress"`
+	Storage       string     `yaml:"storage"`
+	DataDirs      []string   `yaml:"dataDirs"`
+	BundleFile    string     `yaml:"bundleFile,omitempty"`
+	Log           LogConfig  `yaml:"log"`
+	AccountID     string     `yaml:"accountId"`
+	Region        string     `yaml:"region"`
+	TLS           TLSConfig  `yaml:"tls"`
+	Tracing       Tracing    `yaml:"tracing"`
+	Audit         Audit      `yaml:"audit"`
+	Coverage      Coverage   `yaml:"coverage"`
+	Proxy         Proxy      `yaml:"proxy"`
+	Evaluation    Evaluation `yaml:"evaluation"`
+	// Faults are rules of fault injection. They can only be set in the config file or by /_admin/faults.
+	Faults []fault.Rule `yaml:"faults,omitempty"`
+	// Quotas are limits of resources that are checked when resources are loaded (and reloaded) and imported.
+	// Unset values mean the limits of AWS.
+	Quotas quota.Limits `yaml:"quotas,omitempty"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TLSConfig is the configuration of TLS. The server serves HTTPS if both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty"`
+}
+
+// Tracing is the configuration of OpenTelemetry tracing. Tracing is enabled if the OTLP endpoint is set.
+type Tracing struct {
+	// OTLPEndpoint is the URL of the OTLP/HTTP endpoint, e.g. http://localhost:4318
+	OTLPEndpoint string `yaml:"otlpEndpoint,omitempty"`
+}
+
+// Audit is the configuration of the evaluation audit log. Evaluations are always kept in memory,
+// and also written to the JSONL file if it is set. Zero values mean the defaults of the audit package.
+type Audit struct {
+	File       string `yaml:"file,omitempty"`
+	MaxSizeMB  int    `yaml:"maxSizeMB,omitempty"`
+	MaxBackups int    `yaml:"maxBackups,omitempty"`
+	BufferSize int    `yaml:"bufferSize,omitempty"`
+}
+
+// Coverage is the configuration of the flag coverage report.
+type Coverage struct {
+	// ReportDir is the directory to write the coverage report at shutdown. The report is not written if it is empty.
+	ReportDir string `yaml:"reportDir,omitempty"`
+}
+
+// Proxy is the configuration of the record/replay proxy. The server evaluates features by itself if the mode is empty.
+type Proxy struct {
+	// Mode is record or replay.
+	Mode string `yaml:"mode,omitempty"`
+	// Upstream is the URL of the Evidently endpoint to forward requests to in the record mode.
+	Upstream string `yaml:"upstream,omitempty"`
+	// Cassette is the file of recorded interactions.
+	Cassette string `yaml:"cassette,omitempty"`
+}
+
+// Evaluation is the configuration of evaluations.
+type Evaluation struct {
+	// Index compiles all features when the data is loaded, instead of reading files for each evaluation.
+	// It is rebuilt when files in the data directories are changed, so changes are applied with a delay of up to a second.
+	Index bool `yaml:"index,omitempty"`
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		ListenAddress: ":" + DefaultPort,
+		Storage:       StorageDataDir,
+		DataDirs:      []string{DefaultDataDir},
+		Log: LogConfig{
+			Level:  LogLevelInfo,
+			Format: LogFormatJSON,
+		},
+		AccountID: DefaultAccountID,
+		Region:    DefaultRegion,
+	}
+}
+
+// Load loads the configuration. Later sources override earlier ones.
+//
+//  1. default values
+//  2. config file (YAML or JSON) specified by -config flag or EVIDENTLY_LOCAL_CONFIG_FILE
+//  3. environment variables
+//  4. command-line flags
+func Load(name string, args []string, getenv func(string) string, stderr io.Writer) (*Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	configFile := fs.String("config", getenv(EnvConfigFile), "config file (YAML or JSON)")
+	port := fs.String("port", "", "port to listen on (same as -listen-address :<port>)")
+	listenAddress := fs.String("listen-address", "", "address to listen on (default \":"+DefaultPort+"\")")
+	storage := fs.String("storage", "", "storage backend (datadir or bundle)")
+	dataDirs := fs.String("data-dir", "", "data directories separated by the OS path list separator (default \""+DefaultDataDir+"\")")
+	bundleFile := fs.String("bundle-file", "", "bundle file for bundle storage")
+	logLevel := fs.String("log-level", "", "log level (debug, info, warn or error)")
+	logFormat := fs.String("log-format", "", "log format (json or text)")
+	accountID := fs.String("account-id", "", "AWS account ID of the emulated environment")
+	region := fs.String("region", "", "AWS region of the emulated environment")
+	tlsCertFile := fs.String("tls-cert-file", "", "certificate file to serve HTTPS")
+	tlsKeyFile := fs.String("tls-key-file", "", "private key file to serve HTTPS")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "URL of the OTLP/HTTP endpoint to export traces (e.g. http://localhost:4318)")
+	auditFile := fs.String("audit-file", "", "JSONL file to write the evaluation audit log")
+	coverageDir := fs.String("coverage-dir", "", "directory to write the flag coverage report at shutdown")
+	proxyMode := fs.String("proxy-mode", "", "proxy mode (record or replay)")
+	proxyUpstream := fs.String("proxy-upstream", "", "URL of the upstream Evidently endpoint to record")
+	proxyCassette := fs.String("proxy-cassette", "", "cassette file to record to or replay from")
+	evaluationIndex := fs.Bool("evaluation-index", false, "compile all features when files are loaded or changed, instead of reading files for each evaluation")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	c := Default()
+	// storage is decided after all sources are loaded, unless it is set explicitly
+	c.Storage = ""
+
+	if len(*configFile) > 0 {
+		if err := c.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	overrides := []struct {
+		env  string
+		flag string
+		set  func(string)
+	}{
+		{EnvPort, *port, func(v string) { c.ListenAddress = ":" + v }},
+		{EnvListenAddress, *listenAddress, func(v string) { c.ListenAddress = v }},
+		{EnvStorage, *storage, func(v string) { c.Storage = v }},
+		{EnvDataDirs, *dataDirs, func(v string) { c.DataDirs = SplitDataDirs(v) }},
+		{EnvBundleFile, *bundleFile, func(v string) { c.BundleFile = v }},
+		{EnvLogLevel, *logLevel, func(v string) { c.Log.Level = strings.ToLower(v) }},
+		{EnvLogFormat, *logFormat, func(v string) { c.Log.Format = strings.ToLower(v) }},
+		{EnvAccountID, *accountID, func(v string) { c.AccountID = v }},
+		{EnvRegion, *region, func(v string) { c.Region = v }},
+		{EnvTLSCertFile, *tlsCertFile, func(v string) { c.TLS.CertFile = v }},
+		{EnvTLSKeyFile, *tlsKeyFile, func(v string) { c.TLS.KeyFile = v }},
+		{EnvOTLPEndpoint, *otlpEndpoint, func(v string) { c.Tracing.OTLPEndpoint = v }},
+		{EnvAuditFile, *auditFile, func(v string) { c.Audit.File = v }},
+		{EnvCoverageDir, *coverageDir, func(v string) { c.Coverage.ReportDir = v }},
+		{EnvProxyMode, *proxyMode, func(v string) { c.Proxy.Mode = strings.ToLower(v) }},
+		{EnvProxyUpstream, *proxyUpstream, func(v string) { c.Proxy.Upstream = v }},
+		{EnvProxyCassette, *proxyCassette, func(v string) { c.Proxy.Cassette = v }},
+	}
+
+	for _, o := range overrides {
+		if v := getenv(o.env); len(v) > 0 {
+			o.set(v)
+		}
+	}
+
+	for _, o := range overrides {
+		if len(o.flag) > 0 {
+			o.set(o.flag)
+		}
+	}
+
+	if v := getenv(EnvEvaluationIndex); len(v) > 0 {
+		index, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", EnvEvaluationIndex, v)
+		}
+		c.Evaluation.Index = index
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "evaluation-index" {
+			c.Evaluation.Index = *evaluationIndex
+		}
+	})
+
+	c.decideStorage()
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadFile loads the configuration from the config file only, for subcommands that do not run the server.
+func LoadFile(configFile string) (*Config, error) {
+	c := Default()
+	c.Storage = ""
+
+	if err := c.loadFile(configFile); err != nil {
+		return nil, err
+	}
+
+	c.decideStorage()
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// decideStorage sets the storage if it is not set explicitly.
+func (c *Config) decideStorage() {
+	// for compatibility, the bundle file is used if it is set
+	if len(c.Storage) == 0 {
+		c.Storage = StorageDataDir
+		if len(c.BundleFile) > 0 {
+			c.Storage = StorageBundle
+		}
+	}
+}
+
+// loadFile overrides the configuration with the values in the config file.
+// JSON files are also loaded, because JSON is a subset of YAML.
+func (c *Config) loadFile(configFile string) error {
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	if err := d.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to load config file %s: %w", configFile, err)
+	}
+
+	return nil
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	errs := []error{}
+
+	if len(c.ListenAddress) == 0 {
+		errs = append(errs, errors.New("listen address is empty"))
+	}
+
+	switch c.Storage {
+	case StorageDataDir:
+		if len(c.DataDirs) == 0 || slices.Contains(c.DataDirs, "") {
+			errs = append(errs, errors.New("data directories must not be empty for datadir storage"))
+		}
+	case StorageBundle:
+		if len(c.BundleFile) == 0 {
+			errs = append(errs, errors.New("bundle file is required for bundle storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported storage: %s", c.Storage))
+	}
+
+	if !slices.Contains([]string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("unsupported log level: %s", c.Log.Level))
+	}
+
+	if !slices.Contains([]string{LogFormatJSON, LogFormatText}, c.Log.Format) {
+		errs = append(errs, fmt.Errorf("unsupported log format: %s", c.Log.Format))
+	}
+
+	if !accountIDPattern.MatchString(c.AccountID) {
+		errs = append(errs, fmt.Errorf("account ID must be 12 digits: %s", c.AccountID))
+	}
+
+	if len(c.Region) == 0 {
+		errs = append(errs, errors.New("region is empty"))
+	}
+
+	if (len(c.TLS.CertFile) == 0) != (len(c.TLS.KeyFile) == 0) {
+		errs = append(errs, errors.New("both of TLS certificate file and key file must be set"))
+	}
+
+	if len(c.Tracing.OTLPEndpoint) > 0 {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("OTLP endpoint must be an http or https URL: %s", c.Tracing.OTLPEndpoint))
+		}
+	}
+
+	switch c.Proxy.Mode {
+	case "":
+		// noop
+	case ProxyModeRecord:
+		if u, err := url.Parse(c.Proxy.Upstream); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("proxy upstream must be an http or https URL in the record mode: %s", c.Proxy.Upstream))
+		}
+		fallthrough
+	case ProxyModeReplay:
+		if len(c.Proxy.Cassette) == 0 {
+			errs = append(errs, fmt.Errorf("proxy cassette is required in the %s mode", c.Proxy.Mode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported proxy mode: %s", c.Proxy.Mode))
+	}
+
+	if err := c.Quotas.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := fault.Validate(c.Faults); err != nil {
+		errs = append(errs, fmt.Errorf("invalid faults: %w", err))
+	}
+
+	if c.Audit.MaxSizeMB < 0 || c.Audit.MaxBackups < 0 || c.Audit.BufferSize < 0 {
+		errs = append(errs, errors.New("audit maxSizeMB, maxBackups and bufferSize must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// TLSEnabled reports whether the server serves HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return len(c.TLS.CertFile) > 0 && len(c.TLS.KeyFile) > 0
+}
+
+// YAML returns the configuration in YAML, that can be used as a config file.
+func (c *Config) YAML() string {
+	buf := &bytes.Buffer{}
+	e := yaml.NewEncoder(buf)
+	e.SetIndent(2)
+	if err := e.Encode(c); err != nil {
+		return c.String()
+	}
+	_ = e.Close()
+
+	return buf.String()
+}
+
+// String returns the configuration in one line to print it in logs.
+func (c *Config) String() string {
+	return fmt.Sprintf("listenAddress=%s storage=%s dataDirs=%s bundleFile=%s log.level=%s log.format=%s accountId=%s region=%s tls.certFile=%s tls.keyFile=%s tracing.otlpEndpoint=%s audit.file=%s coverage.reportDir=%s proxy.mode=%s proxy.upstream=%s proxy.cassette=%s evaluation.index=%t faults=%d",
+		c.ListenAddress, c.Storage, strings.Join(c.DataDirs, string(filepath.ListSeparator)), c.BundleFile,
+		c.Log.Level, c.Log.Format, c.AccountID, c.Region, c.TLS.CertFile, c.TLS.KeyFile, c.Tracing.OTLPEndpoint, c.Audit.File, c.Coverage.ReportDir,
+		c.Proxy.Mode, c.Proxy.Upstream, c.Proxy.Cassette, c.Evaluation.Index, len(c.Faults))
+}
+
+// SplitDataDirs splits the list of data directories separated by the OS path list separator (":" on Unix).
+// Later directories override earlier ones.
+func SplitDataDirs(list string) []string {
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(list) {
+		if len(dir) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
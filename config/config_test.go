@@ -0,0 +1,406 @@
+package config_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Load(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr bool
+		expect  *config.Config
+	}{
+		{
+			name:    "default",
+			args:    []string{},
+			env:     map[string]string{},
+			wantErr: false,
+			expect:  config.Default(),
+		},
+		{
+			name: "config file: yaml",
+			args: []string{"-config", "../testdata/config/config.yaml"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: "127.0.0.1:8080",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{"./data", "./data.local"},
+				Log:           config.LogConfig{Level: config.LogLevelDebug, Format: config.LogFormatText},
+				AccountID:     config.DefaultAccountID,
+				Region:        "ap-northeast-1",
+			},
+		},
+		{
+			name: "config file: json from env",
+			args: []string{},
+			env:  map[string]string{config.EnvConfigFile: "../testdata/config/config.json"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageBundle,
+				DataDirs:      []string{config.DefaultDataDir},
+				BundleFile:    "./bundle.yaml",
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     "000000000000",
+				Region:        config.DefaultRegion,
+			},
+		},
+		{
+			name: "env overrides config file, and flags override env",
+			args: []string{"-config", "../testdata/config/config.yaml", "-log-level", "warn", "-data-dir", "./flag"},
+			env: map[string]string{
+				config.EnvPort:     "3000",
+				config.EnvLogLevel: "ERROR",
+				config.EnvDataDirs: "./env1:./env2",
+			},
+			expect: &config.Config{
+				ListenAddress: ":3000",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{"./flag"},
+				Log:           config.LogConfig{Level: config.LogLevelWarn, Format: config.LogFormatText},
+				AccountID:     config.DefaultAccountID,
+				Region:        "ap-northeast-1",
+			},
+		},
+		{
+			name: "bundle file selects bundle storage",
+			args: []string{},
+			env:  map[string]string{config.EnvBundleFile: "./bundle.yaml"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageBundle,
+				DataDirs:      []string{config.DefaultDataDir},
+				BundleFile:    "./bundle.yaml",
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+			},
+		},
+		{
+			name: "storage is set explicitly",
+			args: []string{"-storage", "datadir"},
+			env:  map[string]string{config.EnvBundleFile: "./bundle.yaml"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				BundleFile:    "./bundle.yaml",
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+			},
+		},
+		{
+			name: "tls",
+			args: []string{"-tls-cert-file", "cert.pem", "-tls-key-file", "key.pem", "-listen-address", ":8443"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":8443",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				TLS:           config.TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"},
+			},
+		},
+		{
+			name: "otlp endpoint",
+			args: []string{},
+			env:  map[string]string{config.EnvOTLPEndpoint: "http://localhost:4318"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Tracing:       config.Tracing{OTLPEndpoint: "http://localhost:4318"},
+			},
+		},
+		{
+			name: "audit file",
+			args: []string{"-audit-file", "./audit/evaluations.jsonl"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Audit:         config.Audit{File: "./audit/evaluations.jsonl"},
+			},
+		},
+		{
+			name: "coverage report directory",
+			args: []string{},
+			env:  map[string]string{config.EnvCoverageDir: "./coverage"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Coverage:      config.Coverage{ReportDir: "./coverage"},
+			},
+		},
+		{
+			name: "proxy record mode",
+			args: []string{"-proxy-mode", "record", "-proxy-upstream", "https://evidently.us-east-1.amazonaws.com", "-proxy-cassette", "./cassette.json"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Proxy:         config.Proxy{Mode: config.ProxyModeRecord, Upstream: "https://evidently.us-east-1.amazonaws.com", Cassette: "./cassette.json"},
+			},
+		},
+		{
+			name: "evaluation index",
+			args: []string{"-evaluation-index"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Evaluation:    config.Evaluation{Index: true},
+			},
+		},
+		{
+			name: "flag overrides evaluation index of env",
+			args: []string{"-evaluation-index=false"},
+			env:  map[string]string{config.EnvEvaluationIndex: "true"},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+			},
+		},
+		{
+			name:    "invalid evaluation index",
+			args:    []string{},
+			env:     map[string]string{config.EnvEvaluationIndex: "yes"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-unknown"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected arguments",
+			args:    []string{"./data"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "config file not found",
+			args:    []string{"-config", "../testdata/config/not-exists.yaml"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "config file has unknown field",
+			args:    []string{"-config", "../testdata/config/unknown-field.yaml"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "bundle storage without bundle file",
+			args:    []string{"-storage", "bundle"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported storage",
+			args:    []string{"-storage", "s3"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid log level",
+			args:    []string{},
+			env:     map[string]string{config.EnvLogLevel: "verbose"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid log format",
+			args:    []string{"-log-format", "xml"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid account ID",
+			args:    []string{"-account-id", "1234"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid OTLP endpoint",
+			args:    []string{"-otlp-endpoint", "localhost:4318"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name: "faults",
+			args: []string{"-config", "../testdata/config/faults.yaml"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Faults: []fault.Rule{
+					{
+						Operation: "EvaluateFeature",
+						Project:   "test-project",
+						Feature:   "test-feature-1",
+						Latency:   &fault.Latency{Distribution: fault.DistributionNormal, MeanMs: 200, StddevMs: 50},
+						ErrorRate: 0.1,
+						Error:     fault.ErrorThrottling,
+					},
+					{
+						Operation: "BatchEvaluateFeature",
+						ErrorRate: 1,
+						Error:     fault.ErrorServiceUnavailable,
+					},
+				},
+			},
+		},
+		{
+			name:    "invalid faults",
+			args:    []string{"-config", "../testdata/config/invalid-faults.yaml"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name: "quotas",
+			args: []string{"-config", "../testdata/config/quotas.yaml"},
+			env:  map[string]string{},
+			expect: &config.Config{
+				ListenAddress: ":2306",
+				Storage:       config.StorageDataDir,
+				DataDirs:      []string{config.DefaultDataDir},
+				Log:           config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatJSON},
+				AccountID:     config.DefaultAccountID,
+				Region:        config.DefaultRegion,
+				Quotas:        quota.Limits{VariationsPerFeature: quota.Limit(2), FeaturesPerProject: quota.Limit(0)},
+			},
+		},
+		{
+			name:    "invalid quotas",
+			args:    []string{"-config", "../testdata/config/invalid-quotas.yaml"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "proxy record mode without upstream",
+			args:    []string{"-proxy-mode", "record", "-proxy-cassette", "./cassette.json"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "proxy replay mode without cassette",
+			args:    []string{},
+			env:     map[string]string{config.EnvProxyMode: "replay"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported proxy mode",
+			args:    []string{"-proxy-mode", "passthrough", "-proxy-cassette", "./cassette.json"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "only TLS certificate file",
+			args:    []string{"-tls-cert-file", "cert.pem"},
+			env:     map[string]string{},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			getenv := func(key string) string {
+				return c.env[key]
+			}
+
+			got, err := config.Load("test", c.args, getenv, io.Discard)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, got)
+		})
+	}
+}
+
+func Test_LoadFile(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		configFile string
+		wantErr    bool
+		expect     quota.Limits
+	}{
+		{
+			name:       "quotas",
+			configFile: "../testdata/config/quotas.yaml",
+			expect:     quota.Limits{VariationsPerFeature: quota.Limit(2), FeaturesPerProject: quota.Limit(0)},
+		},
+		{
+			name:       "invalid quotas",
+			configFile: "../testdata/config/invalid-quotas.yaml",
+			wantErr:    true,
+		},
+		{
+			name:       "not exists",
+			configFile: "../testdata/config/not-exists.yaml",
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			got, err := config.LoadFile(c.configFile)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, got.Quotas)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package coverage tracks which features, variations and overrides are served during the server's lifetime,
+// to find flags that are not covered by tests.
+package coverage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// Names of report files written by WriteReport.
+const (
+	JSONReportFileName = "coverage.json"
+	TextReportFileName = "coverage.txt"
+)
+
+// Report is a coverage report of features in the repository.
+type Report struct {
+	GeneratedAt        time.Time         `json:"generatedAt"`
+	Summary            Summary           `json:"summary"`
+	Features           []FeatureCoverage `json:"features"`
+	NeverEvaluated     []FeatureRef      `json:"neverEvaluatedFeatures"`
+	UnservedVariations []VariationRef    `json:"unservedVariations"`
+	UnhitOverrides     []OverrideRef     `json:"unhitOverrides"`
+}
+
+// Summary is the number of covered items out of all items.
+type Summary struct {
+	Features          int `json:"features"`
+	EvaluatedFeatures int `json:"evaluatedFeatures"`
+	Variations        int `json:"variations"`
+	ServedVariations  int `json:"servedVariations"`
+	Overrides         int `json:"overrides"`
+	HitOverrides      int `json:"hitOverrides"`
+}
+
+// FeatureCoverage is the number of evaluations of a feature, and the number of times each variation is served.
+type FeatureCoverage struct {
+	Project     string         `json:"project"`
+	Feature     string         `json:"feature"`
+	Evaluations int            `json:"evaluations"`
+	Variations  map[string]int `json:"variations"`
+}
+
+type FeatureRef struct {
+	Project string `json:"project"`
+	Feature string `json:"feature"`
+}
+
+type VariationRef struct {
+	Project   string `json:"project"`
+	Feature   string `json:"feature"`
+	Variation string `json:"variation"`
+}
+
+type OverrideRef struct {
+	Project   string `json:"project"`
+	Feature   string `json:"feature"`
+	EntityID  string `json:"entityId"`
+	Variation string `json:"variation"`
+}
+
+var _ audit.Recorder = (*Tracker)(nil)
+
+// Tracker records evaluations, and reports coverage of features in the repository.
+type Tracker struct {
+	repo repository.FeatureRepository
+
+	mu       sync.Mutex
+	features map[FeatureRef]*featureStats
+}
+
+type featureStats struct {
+	evaluations int
+	variations  map[string]int
+	overrides   map[string]int
+}
+
+func NewTracker(repo repository.FeatureRepository) *Tracker {
+	return &Tracker{
+		repo:     repo,
+		features: map[FeatureRef]*featureStats{},
+	}
+}
+
+// Record records the result of an evaluation.
+func (t *Tracker) Record(r audit.Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := FeatureRef{Project: r.Project, Feature: r.Feature}
+	s, ok := t.features[key]
+	if !ok {
+		s = &featureStats{
+			variations: map[string]int{},
+			overrides:  map[string]int{},
+		}
+		t.features[key] = s
+	}
+
+	s.evaluations++
+	s.variations[r.Variation]++
+	if r.Reason == string(types.EvaluationReasonOverride) {
+		s.overrides[r.EntityID]++
+	}
+}
+
+// Report returns the coverage of features that are currently in the repository.
+// Evaluations of features that have been removed from the repository are not reported.
+func (t *Tracker) Report() (*Report, error) {
+	bundle, err := t.repo.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := &Report{
+		GeneratedAt:        time.Now(),
+		Features:           []FeatureCoverage{},
+		NeverEvaluated:     []FeatureRef{},
+		UnservedVariations: []VariationRef{},
+		UnhitOverrides:     []OverrideRef{},
+	}
+
+	features := bundle.Features
+	slices.SortFunc(features, func(a, b models.Feature) int {
+		return strings.Compare(a.Project+"/"+a.Name, b.Project+"/"+b.Name)
+	})
+
+	for _, f := range features {
+		key := FeatureRef{Project: f.Project, Feature: f.Name}
+		s, ok := t.features[key]
+		if !ok {
+			s = &featureStats{}
+		}
+
+		fc := FeatureCoverage{
+			Project:     f.Project,
+			Feature:     f.Name,
+			Evaluations: s.evaluations,
+			Variations:  map[string]int{},
+		}
+
+		r.Summary.Features++
+		if s.evaluations > 0 {
+			r.Summary.EvaluatedFeatures++
+		} else {
+			r.NeverEvaluated = append(r.NeverEvaluated, key)
+		}
+
+		for _, v := range f.Variations {
+			fc.Variations[v.Name] = s.variations[v.Name]
+
+			r.Summary.Variations++
+			if s.variations[v.Name] > 0 {
+				r.Summary.ServedVariations++
+			} else {
+				r.UnservedVariations = append(r.UnservedVariations, VariationRef{Project: f.Project, Feature: f.Name, Variation: v.Name})
+			}
+		}
+
+		entityIDs := make([]string, 0, len(f.EntityOverrides))
+		for entityID := range f.EntityOverrides {
+			entityIDs = append(entityIDs, entityID)
+		}
+		slices.Sort(entityIDs)
+
+		for _, entityID := range entityIDs {
+			r.Summary.Overrides++
+			if s.overrides[entityID] > 0 {
+				r.Summary.HitOverrides++
+			} else {
+				r.UnhitOverrides = append(r.UnhitOverrides, OverrideRef{Project: f.Project, Feature: f.Name, EntityID: entityID, Variation: f.EntityOverrides[entityID]})
+			}
+		}
+
+		r.Features = append(r.Features, fc)
+	}
+
+	return r, nil
+}
+
+// Covered reports whether all features are evaluated, all variations are served and all overrides are hit.
+func (r *Report) Covered() bool {
+	return len(r.NeverEvaluated) == 0 && len(r.UnservedVariations) == 0 && len(r.UnhitOverrides) == 0
+}
+
+// WriteText writes the report in a human-readable format.
+func (r *Report) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "Flag coverage: %d/%d features evaluated, %d/%d variations served, %d/%d overrides hit\n",
+		r.Summary.EvaluatedFeatures, r.Summary.Features,
+		r.Summary.ServedVariations, r.Summary.Variations,
+		r.Summary.HitOverrides, r.Summary.Overrides)
+
+	b.WriteString("\nFeatures never evaluated:\n")
+	for _, f := range r.NeverEvaluated {
+		fmt.Fprintf(b, "  %s/%s\n", f.Project, f.Feature)
+	}
+	writeNone(b, len(r.NeverEvaluated))
+
+	b.WriteString("\nVariations never served:\n")
+	for _, v := range r.UnservedVariations {
+		fmt.Fprintf(b, "  %s/%s: %s\n", v.Project, v.Feature, v.Variation)
+	}
+	writeNone(b, len(r.UnservedVariations))
+
+	b.WriteString("\nOverrides never hit:\n")
+	for _, o := range r.UnhitOverrides {
+		fmt.Fprintf(b, "  %s/%s: %s -> %s\n", o.Project, o.Feature, o.EntityID, o.Variation)
+	}
+	writeNone(b, len(r.UnhitOverrides))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeNone(b *strings.Builder, n int) {
+	if n == 0 {
+		b.WriteString("  (none)\n")
+	}
+}
+
+// WriteReport writes the report to coverage.json and coverage.txt in the directory.
+func WriteReport(dir string, r *Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	text := &strings.Builder{}
+	if err := r.WriteText(text); err != nil {
+		return err
+	}
+
+	return errors.Join(
+		os.WriteFile(filepath.Join(dir, JSONReportFileName), append(b, '\n'), 0o644),
+		os.WriteFile(filepath.Join(dir, TextReportFileName), []byte(text.String()), 0o644),
+	)
+}
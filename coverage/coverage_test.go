@@ -0,0 +1,179 @@
+package coverage_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRepository(t *testing.T) repository.FeatureRepository {
+	t.Helper()
+
+	l, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	feature := func(name string, overrides models.EntityOverride) models.Feature {
+		return models.Feature{
+			DefaultVariation: "Off",
+			EntityOverrides:  overrides,
+			Name:             name,
+			Project:          "p",
+			Status:           "AVAILABLE",
+			ValueType:        types.FeatureValueTypeBoolean,
+			Variations: []models.Variation{
+				{Name: "On", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+				{Name: "Off", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+			},
+		}
+	}
+
+	repo, err := repository.NewFeatureRepositoryWithBundle(&models.Bundle{
+		Projects: []models.Project{{Name: "p", Status: "AVAILABLE"}},
+		Features: []models.Feature{
+			feature("f1", models.EntityOverride{"force-on": "On", "tester": "On"}),
+			feature("f2", models.EntityOverride{}),
+		},
+	}, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func evaluation(feature, entityID, variation string, reason types.EvaluationReason) audit.Record {
+	return audit.Record{Project: "p", Feature: feature, EntityID: entityID, Variation: variation, Reason: string(reason)}
+}
+
+func Test_Tracker_Report(t *testing.T) {
+	cases := []struct {
+		name               string
+		evaluations        []audit.Record
+		expectSummary      coverage.Summary
+		neverEvaluated     []coverage.FeatureRef
+		unservedVariations []coverage.VariationRef
+		unhitOverrides     []coverage.OverrideRef
+		covered            bool
+	}{
+		{
+			name:          "no evaluations",
+			expectSummary: coverage.Summary{Features: 2, Variations: 4, Overrides: 2},
+			neverEvaluated: []coverage.FeatureRef{
+				{Project: "p", Feature: "f1"},
+				{Project: "p", Feature: "f2"},
+			},
+			unservedVariations: []coverage.VariationRef{
+				{Project: "p", Feature: "f1", Variation: "On"},
+				{Project: "p", Feature: "f1", Variation: "Off"},
+				{Project: "p", Feature: "f2", Variation: "On"},
+				{Project: "p", Feature: "f2", Variation: "Off"},
+			},
+			unhitOverrides: []coverage.OverrideRef{
+				{Project: "p", Feature: "f1", EntityID: "force-on", Variation: "On"},
+				{Project: "p", Feature: "f1", EntityID: "tester", Variation: "On"},
+			},
+		},
+		{
+			name: "partially covered",
+			evaluations: []audit.Record{
+				evaluation("f1", "user", "Off", types.EvaluationReasonDefault),
+				evaluation("f1", "force-on", "On", types.EvaluationReasonOverride),
+				evaluation("f1", "user", "Off", types.EvaluationReasonDefault),
+				// evaluations of features that are not in the repository are ignored
+				evaluation("removed", "user", "Off", types.EvaluationReasonDefault),
+			},
+			expectSummary:  coverage.Summary{Features: 2, EvaluatedFeatures: 1, Variations: 4, ServedVariations: 2, Overrides: 2, HitOverrides: 1},
+			neverEvaluated: []coverage.FeatureRef{{Project: "p", Feature: "f2"}},
+			unservedVariations: []coverage.VariationRef{
+				{Project: "p", Feature: "f2", Variation: "On"},
+				{Project: "p", Feature: "f2", Variation: "Off"},
+			},
+			unhitOverrides: []coverage.OverrideRef{
+				{Project: "p", Feature: "f1", EntityID: "tester", Variation: "On"},
+			},
+		},
+		{
+			name: "fully covered",
+			evaluations: []audit.Record{
+				evaluation("f1", "force-on", "On", types.EvaluationReasonOverride),
+				evaluation("f1", "tester", "On", types.EvaluationReasonOverride),
+				evaluation("f1", "user", "Off", types.EvaluationReasonDefault),
+				evaluation("f2", "user", "On", types.EvaluationReasonDefault),
+				evaluation("f2", "user", "Off", types.EvaluationReasonDefault),
+			},
+			expectSummary:      coverage.Summary{Features: 2, EvaluatedFeatures: 2, Variations: 4, ServedVariations: 4, Overrides: 2, HitOverrides: 2},
+			neverEvaluated:     []coverage.FeatureRef{},
+			unservedVariations: []coverage.VariationRef{},
+			unhitOverrides:     []coverage.OverrideRef{},
+			covered:            true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			tracker := coverage.NewTracker(testRepository(t))
+			for _, e := range c.evaluations {
+				tracker.Record(e)
+			}
+
+			report, err := tracker.Report()
+			if !asst.NoError(err) {
+				return
+			}
+
+			asst.Equal(c.expectSummary, report.Summary)
+			asst.Equal(c.neverEvaluated, report.NeverEvaluated)
+			asst.Equal(c.unservedVariations, report.UnservedVariations)
+			asst.Equal(c.unhitOverrides, report.UnhitOverrides)
+			asst.Equal(c.covered, report.Covered())
+			asst.Len(report.Features, 2)
+		})
+	}
+}
+
+func Test_Report_WriteText(t *testing.T) {
+	asst := assert.New(t)
+
+	tracker := coverage.NewTracker(testRepository(t))
+	tracker.Record(evaluation("f1", "force-on", "On", types.EvaluationReasonOverride))
+	tracker.Record(evaluation("f1", "user", "Off", types.EvaluationReasonDefault))
+
+	report, err := tracker.Report()
+	asst.NoError(err)
+
+	text := &strings.Builder{}
+	asst.NoError(report.WriteText(text))
+	asst.Equal(`Flag coverage: 1/2 features evaluated, 2/4 variations served, 1/2 overrides hit
+
+Features never evaluated:
+  p/f2
+
+Variations never served:
+  p/f2: On
+  p/f2: Off
+
+Overrides never hit:
+  p/f1: tester -> On
+`, text.String())
+
+	dir := filepath.Join(t.TempDir(), "coverage")
+	asst.NoError(coverage.WriteReport(dir, report))
+
+	b, err := os.ReadFile(filepath.Join(dir, coverage.TextReportFileName))
+	asst.NoError(err)
+	asst.Equal(text.String(), string(b))
+
+	b, err = os.ReadFile(filepath.Join(dir, coverage.JSONReportFileName))
+	asst.NoError(err)
+	asst.Contains(string(b), `"neverEvaluatedFeatures": [`)
+}
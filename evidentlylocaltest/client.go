@@ -0,0 +1,354 @@
+package evidentlylocaltest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/evidently"
+	evidentlytypes "github.com/aws/aws-sdk-go-v2/service/evidently/types"
+	"github.com/gofrs/uuid"
+	"github.com/michimani/evidentlylocal/components"
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+var (
+	_ EvidentlyClient = (*evidently.Client)(nil)
+	_ EvidentlyClient = (*Client)(nil)
+)
+
+// EvidentlyClient is the method set of *evidently.Client that Client implements.
+// Depend on it instead of *evidently.Client to replace the client with Client in unit tests.
+type EvidentlyClient interface {
+	EvaluateFeature(ctx context.Context, params *evidently.EvaluateFeatureInput, optFns ...func(*evidently.Options)) (*evidently.EvaluateFeatureOutput, error)
+	BatchEvaluateFeature(ctx context.Context, params *evidently.BatchEvaluateFeatureInput, optFns ...func(*evidently.Options)) (*evidently.BatchEvaluateFeatureOutput, error)
+	PutProjectEvents(ctx context.Context, params *evidently.PutProjectEventsInput, optFns ...func(*evidently.Options)) (*evidently.PutProjectEventsOutput, error)
+}
+
+// Client evaluates features in-process without HTTP.
+// It returns the same output structs and typed errors (e.g. *types.ValidationException) as *evidently.Client.
+// Options given to each method are ignored.
+type Client struct {
+	repo repository.FeatureRepository
+
+	mu     sync.Mutex
+	events map[string][]evidentlytypes.Event
+}
+
+// NewClient returns a client that loads the fixtures.
+func NewClient(t testing.TB, fixtures ...Fixture) *Client {
+	t.Helper()
+
+	bundle, err := buildBundle(fixtures)
+	if err != nil {
+		t.Fatalf("evidentlylocaltest: failed to load fixtures: %v", err)
+	}
+
+	repo, err := repository.NewFeatureRepositoryWithBundle(bundle, discardLogger())
+	if err != nil {
+		t.Fatalf("evidentlylocaltest: invalid fixtures: %v", err)
+	}
+
+	return NewClientWithRepository(repo)
+}
+
+// NewClientWithRepository returns a client that evaluates features in the repository.
+func NewClientWithRepository(repo repository.FeatureRepository) *Client {
+	return &Client{
+		repo:   repo,
+		events: map[string][]evidentlytypes.Event{},
+	}
+}
+
+func (c *Client) EvaluateFeature(ctx context.Context, params *evidently.EvaluateFeatureInput, optFns ...func(*evidently.Options)) (*evidently.EvaluateFeatureOutput, error) {
+	if params == nil {
+		params = &evidently.EvaluateFeatureInput{}
+	}
+
+	project := projectName(aws.ToString(params.Project))
+	featureName := aws.ToString(params.Feature)
+
+	if err := validate(
+		func() error { return internal.ValidateProjectName(project) },
+		func() error { return internal.ValidateFeatureName(featureName) },
+		func() error {
+			return internal.ValidateEvaluateFeatureRequest(&types.EvaluateFeatureRequest{
+				EntityID:          aws.ToString(params.EntityId),
+				EvaluationContext: aws.ToString(params.EvaluationContext),
+			})
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	feature, err := c.repo.Get(project, featureName)
+	if errors.Is(err, repository.ErrFeatureNotFound) {
+		return nil, newResourceNotFoundException(project, featureName, err)
+	}
+	if err != nil {
+		return nil, &evidentlytypes.InternalServerException{Message: aws.String(err.Error())}
+	}
+
+	reason, variation, err := components.EvaluateFeature(ctx, feature, aws.ToString(params.EntityId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &evidently.EvaluateFeatureOutput{
+		Details:   aws.String("{}"),
+		Reason:    aws.String(string(reason)),
+		Value:     toVariableValue(feature, variation),
+		Variation: aws.String(variation.Name),
+	}, nil
+}
+
+func (c *Client) BatchEvaluateFeature(ctx context.Context, params *evidently.BatchEvaluateFeatureInput, optFns ...func(*evidently.Options)) (*evidently.BatchEvaluateFeatureOutput, error) {
+	if params == nil {
+		params = &evidently.BatchEvaluateFeatureInput{}
+	}
+
+	project := projectName(aws.ToString(params.Project))
+
+	var requests []types.EvaluationRequest
+	if params.Requests != nil {
+		requests = make([]types.EvaluationRequest, len(params.Requests))
+		for i, r := range params.Requests {
+			requests[i] = types.EvaluationRequest{
+				EntityID:          aws.ToString(r.EntityId),
+				EvaluationContext: aws.ToString(r.EvaluationContext),
+				Feature:           aws.ToString(r.Feature),
+			}
+		}
+	}
+
+	if err := validate(
+		func() error { return internal.ValidateProjectName(project) },
+		func() error {
+			return internal.ValidateBatchEvaluateFeatureRequest(&types.BatchEvaluateFeatureRequest{Requests: requests})
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	results := make([]evidentlytypes.EvaluationResult, len(requests))
+	for i, r := range requests {
+		results[i] = evidentlytypes.EvaluationResult{
+			EntityId: aws.String(r.EntityID),
+			Feature:  aws.String(r.Feature),
+			Project:  aws.String(project),
+		}
+
+		// same as the server, an error of each request is set to the reason
+		feature, err := c.repo.Get(project, r.Feature)
+		if errors.Is(err, repository.ErrFeatureNotFound) {
+			results[i].Reason = aws.String("Feature not found")
+			continue
+		}
+		if err != nil {
+			results[i].Reason = aws.String("Failed to evaluate feature")
+			continue
+		}
+
+		reason, variation, err := components.EvaluateFeature(ctx, feature, r.EntityID)
+		if err != nil {
+			results[i].Reason = aws.String("Failed to evaluate feature")
+			continue
+		}
+
+		results[i].Details = aws.String("{}")
+		results[i].Reason = aws.String(string(reason))
+		results[i].Value = toVariableValue(feature, variation)
+		results[i].Variation = aws.String(variation.Name)
+	}
+
+	return &evidently.BatchEvaluateFeatureOutput{
+		Results: results,
+	}, nil
+}
+
+// PutProjectEvents validates and keeps the events. They can be read by Events.
+func (c *Client) PutProjectEvents(ctx context.Context, params *evidently.PutProjectEventsInput, optFns ...func(*evidently.Options)) (*evidently.PutProjectEventsOutput, error) {
+	if params == nil {
+		params = &evidently.PutProjectEventsInput{}
+	}
+
+	project := projectName(aws.ToString(params.Project))
+
+	var events []types.Event
+	if params.Events != nil {
+		events = make([]types.Event, len(params.Events))
+		for i, e := range params.Events {
+			events[i] = types.Event{
+				Data: aws.ToString(e.Data),
+				Type: types.EventType(e.Type),
+			}
+			if e.Timestamp != nil {
+				events[i].Timestamp = float64(e.Timestamp.UnixMilli()) / 1000
+			}
+		}
+	}
+
+	if err := validate(
+		func() error { return internal.ValidateProjectName(project) },
+		func() error {
+			return internal.ValidatePutProjectEventsRequest(&types.PutProjectEventsRequest{Events: events})
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.repo.List(project); err != nil {
+		return nil, &evidentlytypes.ResourceNotFoundException{
+			Message:      aws.String(err.Error()),
+			ResourceId:   aws.String(project),
+			ResourceType: aws.String("project"),
+		}
+	}
+
+	results := make([]evidentlytypes.PutProjectEventsResultEntry, len(params.Events))
+	for i := range params.Events {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		results[i] = evidentlytypes.PutProjectEventsResultEntry{EventId: aws.String(id.String())}
+	}
+
+	c.mu.Lock()
+	c.events[project] = append(c.events[project], params.Events...)
+	c.mu.Unlock()
+
+	return &evidently.PutProjectEventsOutput{
+		EventResults:     results,
+		FailedEventCount: aws.Int32(0),
+	}, nil
+}
+
+// Events returns the events put to the project.
+func (c *Client) Events(project string) []evidentlytypes.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]evidentlytypes.Event{}, c.events[projectName(project)]...)
+}
+
+// validate runs validations in order, and returns the first error as *types.ValidationException.
+func validate(validations ...func() error) error {
+	for _, v := range validations {
+		err := v()
+		if err == nil {
+			continue
+		}
+
+		ex := &evidentlytypes.ValidationException{
+			Message: aws.String(err.Error()),
+			Reason:  evidentlytypes.ValidationExceptionReasonOther,
+		}
+
+		ve := &internal.ValidationError{}
+		if errors.As(err, &ve) {
+			ex.Reason = evidentlytypes.ValidationExceptionReason(ve.Reason)
+			for _, f := range ve.Fields {
+				ex.FieldList = append(ex.FieldList, evidentlytypes.ValidationExceptionField{
+					Message: aws.String(f.Message),
+					Name:    aws.String(f.Name),
+				})
+			}
+		}
+
+		return ex
+	}
+
+	return nil
+}
+
+func newResourceNotFoundException(project, featureName string, err error) error {
+	return &evidentlytypes.ResourceNotFoundException{
+		Message:      aws.String(err.Error()),
+		ResourceId:   aws.String(fmt.Sprintf("%s/feature/%s", project, featureName)),
+		ResourceType: aws.String("feature"),
+	}
+}
+
+// projectName returns the project name of the name or ARN of a project.
+func projectName(nameOrARN string) string {
+	if i := strings.LastIndex(nameOrARN, ":project/"); strings.HasPrefix(nameOrARN, "arn:") && i >= 0 {
+		return nameOrARN[i+len(":project/"):]
+	}
+
+	return nameOrARN
+}
+
+// toVariableValue converts the value of the variation into the union type of the AWS SDK.
+func toVariableValue(feature *models.Feature, variation models.Variation) evidentlytypes.VariableValue {
+	value := variation.Value[feature.VariableValueType()]
+
+	switch feature.VariableValueType() {
+	case types.VariableValueTypeBool:
+		v, _ := value.(bool)
+		return &evidentlytypes.VariableValueMemberBoolValue{Value: v}
+	case types.VariableValueTypeString:
+		v, _ := value.(string)
+		return &evidentlytypes.VariableValueMemberStringValue{Value: v}
+	case types.VariableValueTypeLong:
+		return &evidentlytypes.VariableValueMemberLongValue{Value: toInt64(value)}
+	case types.VariableValueTypeDouble:
+		return &evidentlytypes.VariableValueMemberDoubleValue{Value: toFloat64(value)}
+	default:
+		// noop
+	}
+
+	return nil
+}
+
+// toInt64 converts a long value in a feature. Integers and json.Number are converted without float64,
+// so that values above 2^53 are kept as they are.
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+	default:
+		// noop
+	}
+
+	return int64(toFloat64(value))
+}
+
+// toFloat64 converts a number in a feature, that is float64 if it is loaded from a file.
+func toFloat64(value any) float64 {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		// noop
+	}
+
+	return 0
+}
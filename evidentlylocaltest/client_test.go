@@ -0,0 +1,219 @@
+package evidentlylocaltest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/evidently"
+	evidentlytypes "github.com/aws/aws-sdk-go-v2/service/evidently/types"
+	"github.com/michimani/evidentlylocal/evidentlylocaltest"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_EvaluateFeature(t *testing.T) {
+	client := evidentlylocaltest.NewClient(t,
+		evidentlylocaltest.Features(testFeature("p", "f", "Off")),
+		evidentlylocaltest.DataDir("../testdata"),
+	)
+
+	cases := []struct {
+		name          string
+		input         *evidently.EvaluateFeatureInput
+		wantVariation string
+		wantReason    string
+		wantValue     evidentlytypes.VariableValue
+		wantErr       any
+	}{
+		{
+			name:          "default rule",
+			input:         &evidently.EvaluateFeatureInput{Project: aws.String("p"), Feature: aws.String("f"), EntityId: aws.String("entity")},
+			wantVariation: "Off",
+			wantReason:    "DEFAULT",
+			wantValue:     &evidentlytypes.VariableValueMemberStringValue{Value: "off"},
+		},
+		{
+			name:          "override rule",
+			input:         &evidently.EvaluateFeatureInput{Project: aws.String("p"), Feature: aws.String("f"), EntityId: aws.String("force-on")},
+			wantVariation: "On",
+			wantReason:    "OVERRIDE_RULE",
+			wantValue:     &evidentlytypes.VariableValueMemberStringValue{Value: "on"},
+		},
+		{
+			name:          "project ARN",
+			input:         &evidently.EvaluateFeatureInput{Project: aws.String("arn:aws:evidently:us-east-1:123456789012:project/test-project"), Feature: aws.String("test-feature-1"), EntityId: aws.String("force-true")},
+			wantVariation: "True",
+			wantReason:    "OVERRIDE_RULE",
+			wantValue:     &evidentlytypes.VariableValueMemberBoolValue{Value: true},
+		},
+		{
+			name:    "feature not found",
+			input:   &evidently.EvaluateFeatureInput{Project: aws.String("p"), Feature: aws.String("not-exists"), EntityId: aws.String("entity")},
+			wantErr: &evidentlytypes.ResourceNotFoundException{},
+		},
+		{
+			name:    "empty entity ID",
+			input:   &evidently.EvaluateFeatureInput{Project: aws.String("p"), Feature: aws.String("f")},
+			wantErr: &evidentlytypes.ValidationException{},
+		},
+		{
+			name:    "invalid feature name",
+			input:   &evidently.EvaluateFeatureInput{Project: aws.String("p"), Feature: aws.String("../f"), EntityId: aws.String("entity")},
+			wantErr: &evidentlytypes.ValidationException{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := client.EvaluateFeature(context.Background(), c.input)
+			if c.wantErr != nil {
+				asst.Nil(out)
+				asst.Error(err)
+				asst.IsType(c.wantErr, err)
+				return
+			}
+
+			if !asst.NoError(err) {
+				return
+			}
+			asst.Equal(c.wantVariation, aws.ToString(out.Variation))
+			asst.Equal(c.wantReason, aws.ToString(out.Reason))
+			asst.Equal(c.wantValue, out.Value)
+		})
+	}
+}
+
+func Test_Client_EvaluateFeature_LongValue(t *testing.T) {
+	// 2^53 + 1 cannot be represented in float64
+	const value = 9007199254740993
+
+	longFeature := func(name string, v any) models.Feature {
+		return models.Feature{
+			DefaultVariation: "Long",
+			Name:             name,
+			Project:          "p",
+			ValueType:        types.FeatureValueTypeLong,
+			Variations: []models.Variation{
+				{Name: "Long", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: v}},
+			},
+		}
+	}
+
+	client := evidentlylocaltest.NewClient(t, evidentlylocaltest.Features(
+		longFeature("int64", int64(value)),
+		longFeature("json-number", json.Number("9007199254740993")),
+	))
+
+	for _, feature := range []string{"int64", "json-number"} {
+		t.Run(feature, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out, err := client.EvaluateFeature(context.Background(), &evidently.EvaluateFeatureInput{
+				Project:  aws.String("p"),
+				Feature:  aws.String(feature),
+				EntityId: aws.String("entity"),
+			})
+			if !asst.NoError(err) {
+				return
+			}
+			asst.Equal(&evidentlytypes.VariableValueMemberLongValue{Value: value}, out.Value)
+		})
+	}
+}
+
+func Test_Client_BatchEvaluateFeature(t *testing.T) {
+	asst := assert.New(t)
+
+	client := evidentlylocaltest.NewClient(t, evidentlylocaltest.Features(testFeature("p", "f", "Off")))
+
+	out, err := client.BatchEvaluateFeature(context.Background(), &evidently.BatchEvaluateFeatureInput{
+		Project: aws.String("p"),
+		Requests: []evidentlytypes.EvaluationRequest{
+			{Feature: aws.String("f"), EntityId: aws.String("force-on")},
+			{Feature: aws.String("not-exists"), EntityId: aws.String("entity")},
+		},
+	})
+	if asst.NoError(err) && asst.Len(out.Results, 2) {
+		asst.Equal("On", aws.ToString(out.Results[0].Variation))
+		asst.Equal("OVERRIDE_RULE", aws.ToString(out.Results[0].Reason))
+		asst.Equal("Feature not found", aws.ToString(out.Results[1].Reason))
+	}
+
+	_, err = client.BatchEvaluateFeature(context.Background(), &evidently.BatchEvaluateFeatureInput{Project: aws.String("p")})
+	ve := &evidentlytypes.ValidationException{}
+	if asst.True(errors.As(err, &ve)) {
+		asst.Equal(evidentlytypes.ValidationExceptionReasonFieldValidationFailed, ve.Reason)
+		asst.Equal("requests", aws.ToString(ve.FieldList[0].Name))
+	}
+}
+
+func Test_Client_PutProjectEvents(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name    string
+		input   *evidently.PutProjectEventsInput
+		wantErr any
+	}{
+		{
+			name: "ok",
+			input: &evidently.PutProjectEventsInput{
+				Project: aws.String("p"),
+				Events: []evidentlytypes.Event{
+					{Data: aws.String(`{"details":{"clicks":1}}`), Timestamp: &now, Type: evidentlytypes.EventTypeCustom},
+				},
+			},
+		},
+		{
+			name: "project not found",
+			input: &evidently.PutProjectEventsInput{
+				Project: aws.String("not-exists"),
+				Events: []evidentlytypes.Event{
+					{Data: aws.String(`{}`), Timestamp: &now, Type: evidentlytypes.EventTypeCustom},
+				},
+			},
+			wantErr: &evidentlytypes.ResourceNotFoundException{},
+		},
+		{
+			name: "invalid event",
+			input: &evidently.PutProjectEventsInput{
+				Project: aws.String("p"),
+				Events: []evidentlytypes.Event{
+					{Data: aws.String(`not json`), Type: "unknown"},
+				},
+			},
+			wantErr: &evidentlytypes.ValidationException{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			client := evidentlylocaltest.NewClient(tt, evidentlylocaltest.Features(testFeature("p", "f", "Off")))
+
+			out, err := client.PutProjectEvents(context.Background(), c.input)
+			if c.wantErr != nil {
+				asst.Nil(out)
+				asst.IsType(c.wantErr, err)
+				asst.Empty(client.Events(aws.ToString(c.input.Project)))
+				return
+			}
+
+			if !asst.NoError(err) {
+				return
+			}
+			asst.Equal(int32(0), aws.ToInt32(out.FailedEventCount))
+			asst.Len(out.EventResults, len(c.input.Events))
+			asst.NotEmpty(aws.ToString(out.EventResults[0].EventId))
+			asst.Equal(c.input.Events, client.Events(aws.ToString(c.input.Project)))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package evidentlylocaltest
+
+import (
+	"io"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+)
+
+// Fixture is a set of resources that the test server loads.
+// When fixtures define the same resource, the later one replaces the earlier one.
+type Fixture func() (*models.Bundle, error)
+
+// Bundle is a fixture of all resources in the bundle.
+func Bundle(bundle *models.Bundle) Fixture {
+	return func() (*models.Bundle, error) {
+		return bundle, nil
+	}
+}
+
+// Projects is a fixture of projects.
+func Projects(projects ...models.Project) Fixture {
+	return func() (*models.Bundle, error) {
+		return &models.Bundle{Projects: projects}, nil
+	}
+}
+
+// Features is a fixture of features. Projects of the features are created if they are not defined by other fixtures.
+func Features(features ...models.Feature) Fixture {
+	return func() (*models.Bundle, error) {
+		return &models.Bundle{Features: features}, nil
+	}
+}
+
+// DataDir is a fixture of all resources in the data directory (e.g. "testdata").
+func DataDir(dir string) Fixture {
+	return func() (*models.Bundle, error) {
+		repo, err := repository.NewFeatureRepositoryWithJSONFile(dir, discardLogger())
+		if err != nil {
+			return nil, err
+		}
+		return repo.Snapshot()
+	}
+}
+
+// BundleFile is a fixture of all resources in the JSON or YAML bundle file.
+func BundleFile(bundleFile string) Fixture {
+	return func() (*models.Bundle, error) {
+		repo, err := repository.NewFeatureRepositoryWithBundleFile(bundleFile, discardLogger())
+		if err != nil {
+			return nil, err
+		}
+		return repo.Snapshot()
+	}
+}
+
+// buildBundle merges all fixtures into a bundle.
+func buildBundle(fixtures []Fixture) (*models.Bundle, error) {
+	bundle := &models.Bundle{}
+	for _, fixture := range fixtures {
+		b, err := fixture()
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			continue
+		}
+
+		bundle.Projects = merge(bundle.Projects, b.Projects, func(p models.Project) string { return p.Name })
+		bundle.Features = merge(bundle.Features, b.Features, func(f models.Feature) string { return f.Project + "/" + f.Name })
+		bundle.Launches = merge(bundle.Launches, b.Launches, func(l models.Launch) string { return l.Project + "/" + l.Name })
+		bundle.Experiments = merge(bundle.Experiments, b.Experiments, func(e models.Experiment) string { return e.Project + "/" + e.Name })
+		bundle.Segments = merge(bundle.Segments, b.Segments, func(s models.Segment) string { return s.Name })
+	}
+
+	projects := map[string]bool{}
+	for _, p := range bundle.Projects {
+		projects[p.Name] = true
+	}
+	for _, f := range bundle.Features {
+		if !projects[f.Project] {
+			bundle.Projects = append(bundle.Projects, models.Project{Name: f.Project, Status: "AVAILABLE"})
+			projects[f.Project] = true
+		}
+	}
+
+	return bundle, nil
+}
+
+// merge appends resources in overlay to base. A resource with the same key replaces the one in base.
+func merge[T any](base, overlay []T, key func(T) string) []T {
+	for _, o := range overlay {
+		replaced := false
+		for i, b := range base {
+			if key(b) == key(o) {
+				base[i] = o
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			base = append(base, o)
+		}
+	}
+
+	return base
+}
+
+func discardLogger() logger.Logger {
+	l, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	return l
+}
@@ -0,0 +1,124 @@
+// Package evidentlylocaltest starts an in-process Evidently-Local server for tests.
+//
+//	func TestSomething(t *testing.T) {
+//		s := evidentlylocaltest.NewServer(t, evidentlylocaltest.DataDir("testdata"))
+//		client := evidently.NewFromConfig(s.AWSConfig())
+//		...
+//	}
+package evidentlylocaltest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/evidently"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/server"
+)
+
+// Server is an Evidently-Local server running on an httptest.Server.
+type Server struct {
+	httpServer *httptest.Server
+	region     string
+	srv        *server.Server
+}
+
+// NewServer starts a server that loads the fixtures. The server is closed when the test finishes.
+func NewServer(t testing.TB, fixtures ...Fixture) *Server {
+	t.Helper()
+
+	bundle, err := buildBundle(fixtures)
+	if err != nil {
+		t.Fatalf("evidentlylocaltest: failed to load fixtures: %v", err)
+	}
+
+	l := discardLogger()
+
+	repo, err := repository.NewFeatureRepositoryWithBundle(bundle, l)
+	if err != nil {
+		t.Fatalf("evidentlylocaltest: invalid fixtures: %v", err)
+	}
+
+	cfg := config.Default()
+	srv, err := server.New(cfg, l, repo)
+	if err != nil {
+		t.Fatalf("evidentlylocaltest: failed to create server: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return &Server{
+		httpServer: ts,
+		region:     cfg.Region,
+		srv:        srv,
+	}
+}
+
+// URL returns the endpoint URL of the server.
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// AWSConfig returns aws.Config to create an Evidently client that sends requests to the server.
+// It has dummy static credentials, so it does not read any credentials in the environment.
+func (s *Server) AWSConfig() aws.Config {
+	return aws.Config{
+		Region:       s.region,
+		BaseEndpoint: aws.String(s.URL()),
+		APIOptions:   []func(*middleware.Stack) error{disableHostPrefix},
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     "evidentlylocaltest",
+				SecretAccessKey: "evidentlylocaltest",
+				Source:          "evidentlylocaltest",
+			}, nil
+		}),
+	}
+}
+
+// ClientOptions sets the server as BaseEndpoint of an Evidently client that is created from another aws.Config,
+// e.g. evidently.NewFromConfig(cfg, s.ClientOptions).
+func (s *Server) ClientOptions(o *evidently.Options) {
+	o.BaseEndpoint = aws.String(s.URL())
+	o.APIOptions = append(o.APIOptions, disableHostPrefix)
+}
+
+// disableHostPrefix disables the "dataplane." host prefix that the SDK adds to BaseEndpoint for evaluations and events,
+// because the server has no host for them.
+func disableHostPrefix(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EvidentlyLocalDisableHostPrefix",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			return next.HandleInitialize(smithyhttp.DisableEndpointHostPrefix(ctx, true), in)
+		},
+	), middleware.Before)
+}
+
+// Evaluations returns evaluations served by the server that match the query, from the oldest to the latest.
+// It is the same as GET /_admin/evaluations.
+func (s *Server) Evaluations(q audit.Query) []audit.Record {
+	return s.srv.AuditLog().Query(q)
+}
+
+// Coverage returns features never evaluated, variations never served and overrides never hit by the server.
+// It is the same as GET /_admin/coverage.
+func (s *Server) Coverage() (*coverage.Report, error) {
+	return s.srv.Coverage()
+}
+
+// InjectFaults replaces rules of fault injection, e.g. to return ThrottlingException to test retries of clients.
+// Calling it without rules stops injecting faults.
+func (s *Server) InjectFaults(rules ...fault.Rule) error {
+	return s.srv.Faults().SetRules(rules)
+}
@@ -0,0 +1,201 @@
+package evidentlylocaltest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/evidently"
+	evidentlytypes "github.com/aws/aws-sdk-go-v2/service/evidently/types"
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/evidentlylocaltest"
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFeature(project, name, defaultVariation string) models.Feature {
+	return models.Feature{
+		DefaultVariation: defaultVariation,
+		EntityOverrides:  models.EntityOverride{"force-on": "On"},
+		Name:             name,
+		Project:          project,
+		Status:           "AVAILABLE",
+		ValueType:        types.FeatureValueTypeString,
+		Variations: []models.Variation{
+			{Name: "On", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "on"}},
+			{Name: "Off", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "off"}},
+		},
+	}
+}
+
+func Test_NewServer(t *testing.T) {
+	cases := []struct {
+		name          string
+		fixtures      []evidentlylocaltest.Fixture
+		project       string
+		feature       string
+		entityID      string
+		wantErr       bool
+		wantVariation string
+		wantReason    string
+	}{
+		{
+			name:          "features from structs",
+			fixtures:      []evidentlylocaltest.Fixture{evidentlylocaltest.Features(testFeature("p", "f", "Off"))},
+			project:       "p",
+			feature:       "f",
+			entityID:      "entity",
+			wantVariation: "Off",
+			wantReason:    "DEFAULT",
+		},
+		{
+			name:          "override rule",
+			fixtures:      []evidentlylocaltest.Fixture{evidentlylocaltest.Features(testFeature("p", "f", "Off"))},
+			project:       "p",
+			feature:       "f",
+			entityID:      "force-on",
+			wantVariation: "On",
+			wantReason:    "OVERRIDE_RULE",
+		},
+		{
+			name:          "data directory",
+			fixtures:      []evidentlylocaltest.Fixture{evidentlylocaltest.DataDir("../testdata")},
+			project:       "test-project",
+			feature:       "test-feature-1",
+			entityID:      "force-true",
+			wantVariation: "True",
+			wantReason:    "OVERRIDE_RULE",
+		},
+		{
+			name: "later fixture replaces the feature",
+			fixtures: []evidentlylocaltest.Fixture{
+				evidentlylocaltest.Features(testFeature("p", "f", "Off")),
+				evidentlylocaltest.Features(testFeature("p", "f", "On")),
+			},
+			project:       "p",
+			feature:       "f",
+			entityID:      "entity",
+			wantVariation: "On",
+			wantReason:    "DEFAULT",
+		},
+		{
+			name:     "feature not found",
+			fixtures: []evidentlylocaltest.Fixture{evidentlylocaltest.Features(testFeature("p", "f", "Off"))},
+			project:  "p",
+			feature:  "not-exists",
+			entityID: "entity",
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			s := evidentlylocaltest.NewServer(tt, c.fixtures...)
+			client := evidently.NewFromConfig(s.AWSConfig())
+
+			out, err := client.EvaluateFeature(context.Background(), &evidently.EvaluateFeatureInput{
+				Project:  aws.String(c.project),
+				Feature:  aws.String(c.feature),
+				EntityId: aws.String(c.entityID),
+			})
+			if c.wantErr {
+				// the same error as Client returns
+				nf := &evidentlytypes.ResourceNotFoundException{}
+				asst.ErrorAs(err, &nf)
+				return
+			}
+
+			if !asst.NoError(err) {
+				return
+			}
+			asst.Equal(c.wantVariation, aws.ToString(out.Variation))
+			asst.Equal(c.wantReason, aws.ToString(out.Reason))
+		})
+	}
+}
+
+func Test_NewServer_BatchEvaluateFeature(t *testing.T) {
+	asst := assert.New(t)
+
+	s := evidentlylocaltest.NewServer(t, evidentlylocaltest.Features(
+		testFeature("p", "f1", "Off"),
+		testFeature("p", "f2", "On"),
+	))
+	client := evidently.NewFromConfig(s.AWSConfig())
+
+	out, err := client.BatchEvaluateFeature(context.Background(), &evidently.BatchEvaluateFeatureInput{
+		Project: aws.String("p"),
+		Requests: []evidentlytypes.EvaluationRequest{
+			{Feature: aws.String("f1"), EntityId: aws.String("entity")},
+			{Feature: aws.String("f2"), EntityId: aws.String("entity")},
+		},
+	})
+	if !asst.NoError(err) {
+		return
+	}
+
+	if asst.Len(out.Results, 2) {
+		asst.Equal("Off", aws.ToString(out.Results[0].Variation))
+		asst.Equal("On", aws.ToString(out.Results[1].Variation))
+	}
+
+	evaluations := s.Evaluations(audit.Query{EntityID: "entity", Feature: "f2"})
+	if asst.Len(evaluations, 1) {
+		asst.Equal("On", evaluations[0].Variation)
+		asst.Equal("DEFAULT", evaluations[0].Reason)
+	}
+
+	report, err := s.Coverage()
+	if asst.NoError(err) {
+		asst.Equal(2, report.Summary.EvaluatedFeatures)
+		// "Off" of f2, "On" of f1 and the overrides are not served
+		asst.Len(report.UnservedVariations, 2)
+		asst.Len(report.UnhitOverrides, 2)
+	}
+}
+
+func Test_Server_ClientOptions(t *testing.T) {
+	asst := assert.New(t)
+
+	s := evidentlylocaltest.NewServer(t, evidentlylocaltest.Features(testFeature("p", "f", "Off")))
+	cfg := aws.Config{Region: "us-east-1", Credentials: aws.AnonymousCredentials{}}
+	client := evidently.NewFromConfig(cfg, s.ClientOptions)
+
+	out, err := client.EvaluateFeature(context.Background(), &evidently.EvaluateFeatureInput{
+		Project:  aws.String("p"),
+		Feature:  aws.String("f"),
+		EntityId: aws.String("entity"),
+	})
+	if asst.NoError(err) {
+		asst.Equal("Off", aws.ToString(out.Variation))
+	}
+}
+
+func Test_Server_InjectFaults(t *testing.T) {
+	asst := assert.New(t)
+
+	s := evidentlylocaltest.NewServer(t, evidentlylocaltest.Features(testFeature("p", "f", "Off")))
+	client := evidently.NewFromConfig(s.AWSConfig(), func(o *evidently.Options) {
+		o.RetryMaxAttempts = 1
+	})
+
+	input := &evidently.EvaluateFeatureInput{
+		Project:  aws.String("p"),
+		Feature:  aws.String("f"),
+		EntityId: aws.String("entity"),
+	}
+
+	asst.NoError(s.InjectFaults(fault.Rule{Operation: "EvaluateFeature", ErrorRate: 1, Error: fault.ErrorThrottling}))
+	_, err := client.EvaluateFeature(context.Background(), input)
+	te := &evidentlytypes.ThrottlingException{}
+	asst.ErrorAs(err, &te)
+
+	// faults are not injected after they are cleared
+	asst.NoError(s.InjectFaults())
+	_, err = client.EvaluateFeature(context.Background(), input)
+	asst.NoError(err)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/repository"
+)
+
+// runExport runs export subcommand, and returns the exit code.
+// It dumps all resources that the server loads as a bundle that can be loaded with EVIDENTLY_LOCAL_BUNDLE_FILE.
+//
+//	evidently-local export [-data-dir ./data] [-bundle-file <file>] [-format json|yaml] [-o <file>]
+func runExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	exportDataDirs := fs.String("data-dir", config.DefaultDataDir, "data directories to export, separated by the OS path list separator")
+	bundleFile := fs.String("bundle-file", os.Getenv(config.EnvBundleFile), "bundle file to export instead of the data directory")
+	format := fs.String("format", repository.BundleFormatJSON, "output format (json or yaml)")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: evidently-local export [-data-dir <dir>] [-bundle-file <file>] [-format <format>] [-o <file>]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	l, err := logger.NewEvidentlyLocalLogger(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create logger: %v\n", err)
+		return 1
+	}
+
+	cfg := config.Default()
+	cfg.DataDirs = config.SplitDataDirs(*exportDataDirs)
+	if len(*bundleFile) > 0 {
+		cfg.Storage = config.StorageBundle
+		cfg.BundleFile = *bundleFile
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(stderr, "invalid options: %v\n", err)
+		return 2
+	}
+
+	repo, err := newFeatureRepository(cfg, l)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load resources: %v\n", err)
+		return 1
+	}
+
+	bundle, err := repo.Snapshot()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to export: %v\n", err)
+		return 1
+	}
+
+	b, err := repository.MarshalBundle(bundle, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to export: %v\n", err)
+		return 1
+	}
+
+	if len(*output) == 0 {
+		_, _ = stdout.Write(b)
+		return 0
+	}
+
+	if err := os.WriteFile(*output, b, 0o644); err != nil {
+		fmt.Fprintf(stderr, "failed to write %s: %v\n", *output, err)
+		return 1
+	}
+
+	return 0
+}
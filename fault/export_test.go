@@ -0,0 +1,7 @@
+package fault
+
+// SetRandForTest replaces random number generators of the injector.
+func SetRandForTest(i *Injector, rand, norm func() float64) {
+	i.rand = rand
+	i.norm = norm
+}
@@ -0,0 +1,228 @@
+// Package fault injects latency and AWS errors into API operations, to test retry, timeout and fallback logic of clients.
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Latency distributions.
+const (
+	DistributionFixed   = "fixed"
+	DistributionUniform = "uniform"
+	DistributionNormal  = "normal"
+)
+
+// AWS errors that can be injected.
+const (
+	ErrorThrottling         = "ThrottlingException"
+	ErrorInternalServer     = "InternalServerException"
+	ErrorServiceUnavailable = "ServiceUnavailableException"
+)
+
+// errorStatuses are HTTP status codes of the errors.
+var errorStatuses = map[string]int{
+	ErrorThrottling:         http.StatusTooManyRequests,
+	ErrorInternalServer:     http.StatusInternalServerError,
+	ErrorServiceUnavailable: http.StatusServiceUnavailable,
+}
+
+// Operations are names of operations that rules can match. They are the same as the names in metrics and traces.
+var Operations = []string{
+	"ListProjects", "CreateProject", "GetProject", "UpdateProject", "DeleteProject",
+	"EvaluateFeature", "BatchEvaluateFeature",
+	"ListFeatures", "CreateFeature", "GetFeature", "UpdateFeature", "DeleteFeature",
+	"ListLaunches", "CreateLaunch", "GetLaunch", "UpdateLaunch", "DeleteLaunch",
+	"ListExperiments", "CreateExperiment", "GetExperiment", "UpdateExperiment", "DeleteExperiment",
+	"ListSegments", "CreateSegment", "GetSegment", "DeleteSegment", "ListSegmentReferences",
+}
+
+// Rule is a fault of requests that match the operation, the project and the feature. Empty fields match all requests.
+// Requests of BatchEvaluateFeature do not have a feature, so rules with a feature do not match them.
+type Rule struct {
+	Operation string   `json:"operation,omitempty" yaml:"operation,omitempty"`
+	Project   string   `json:"project,omitempty" yaml:"project,omitempty"`
+	Feature   string   `json:"feature,omitempty" yaml:"feature,omitempty"`
+	Latency   *Latency `json:"latency,omitempty" yaml:"latency,omitempty"`
+	// ErrorRate is the probability from 0 to 1 to return Error.
+	ErrorRate float64 `json:"errorRate,omitempty" yaml:"errorRate,omitempty"`
+	Error     string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Latency is a distribution of latency in milliseconds.
+//
+//   - fixed: FixedMs
+//   - uniform: from MinMs to MaxMs
+//   - normal: MeanMs and StddevMs, and negative values are 0
+type Latency struct {
+	Distribution string  `json:"distribution" yaml:"distribution"`
+	FixedMs      float64 `json:"fixedMs,omitempty" yaml:"fixedMs,omitempty"`
+	MinMs        float64 `json:"minMs,omitempty" yaml:"minMs,omitempty"`
+	MaxMs        float64 `json:"maxMs,omitempty" yaml:"maxMs,omitempty"`
+	MeanMs       float64 `json:"meanMs,omitempty" yaml:"meanMs,omitempty"`
+	StddevMs     float64 `json:"stddevMs,omitempty" yaml:"stddevMs,omitempty"`
+}
+
+// Fault is a fault to inject into a request.
+type Fault struct {
+	Latency time.Duration
+	// Error is empty if no error is injected.
+	Error string
+}
+
+// Status returns the HTTP status code of the error.
+func (f Fault) Status() int {
+	return errorStatuses[f.Error]
+}
+
+// Injector decides faults of requests by rules. Rules can be replaced while the server is running.
+type Injector struct {
+	mu    sync.RWMutex
+	rules []Rule
+	rand  func() float64
+	norm  func() float64
+}
+
+func NewInjector(rules []Rule) (*Injector, error) {
+	i := &Injector{
+		rules: []Rule{},
+		rand:  rand.Float64,
+		norm:  rand.NormFloat64,
+	}
+
+	if err := i.SetRules(rules); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Rules returns the current rules.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	res := make([]Rule, len(i.rules))
+	copy(res, i.rules)
+
+	return res
+}
+
+// SetRules validates and replaces the rules. Nil or empty rules disable fault injection.
+func (i *Injector) SetRules(rules []Rule) error {
+	if err := Validate(rules); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = append([]Rule{}, rules...)
+
+	return nil
+}
+
+// Decide returns the fault of the first rule that matches the request, or no fault.
+func (i *Injector) Decide(operation, project, feature string) Fault {
+	if i == nil {
+		return Fault{}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, r := range i.rules {
+		if !r.matches(operation, project, feature) {
+			continue
+		}
+
+		f := Fault{}
+		if r.Latency != nil {
+			f.Latency = i.latency(*r.Latency)
+		}
+		if r.ErrorRate > 0 && i.rand() < r.ErrorRate {
+			f.Error = r.Error
+		}
+
+		return f
+	}
+
+	return Fault{}
+}
+
+func (i *Injector) latency(l Latency) time.Duration {
+	ms := 0.0
+	switch l.Distribution {
+	case DistributionFixed:
+		ms = l.FixedMs
+	case DistributionUniform:
+		ms = l.MinMs + i.rand()*(l.MaxMs-l.MinMs)
+	case DistributionNormal:
+		ms = max(l.MeanMs+i.norm()*l.StddevMs, 0)
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (r Rule) matches(operation, project, feature string) bool {
+	return (len(r.Operation) == 0 || r.Operation == operation) &&
+		(len(r.Project) == 0 || r.Project == project) &&
+		(len(r.Feature) == 0 || r.Feature == feature)
+}
+
+// Validate validates the rules.
+func Validate(rules []Rule) error {
+	errs := []error{}
+
+	for n, r := range rules {
+		if len(r.Operation) > 0 && !slices.Contains(Operations, r.Operation) {
+			errs = append(errs, fmt.Errorf("rule %d: unknown operation: %q", n, r.Operation))
+		}
+
+		if r.ErrorRate < 0 || r.ErrorRate > 1 {
+			errs = append(errs, fmt.Errorf("rule %d: error rate must be from 0 to 1: %v", n, r.ErrorRate))
+		}
+
+		if r.ErrorRate > 0 {
+			if _, ok := errorStatuses[r.Error]; !ok {
+				errs = append(errs, fmt.Errorf("rule %d: unsupported error: %q", n, r.Error))
+			}
+		} else if len(r.Error) > 0 {
+			errs = append(errs, fmt.Errorf("rule %d: error %q is never returned without a positive errorRate", n, r.Error))
+		}
+
+		if r.Latency != nil {
+			if err := r.Latency.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: %w", n, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l Latency) validate() error {
+	switch l.Distribution {
+	case DistributionFixed:
+		if l.FixedMs < 0 {
+			return errors.New("fixedMs must not be negative")
+		}
+	case DistributionUniform:
+		if l.MinMs < 0 || l.MaxMs < l.MinMs {
+			return errors.New("minMs must not be negative, and maxMs must not be less than minMs")
+		}
+	case DistributionNormal:
+		if l.MeanMs < 0 || l.StddevMs < 0 {
+			return errors.New("meanMs and stddevMs must not be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported latency distribution: %q", l.Distribution)
+	}
+
+	return nil
+}
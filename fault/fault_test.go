@@ -0,0 +1,183 @@
+package fault_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Injector_Decide(t *testing.T) {
+	rules := []fault.Rule{
+		{
+			Operation: "EvaluateFeature",
+			Project:   "p",
+			Feature:   "slow",
+			Latency:   &fault.Latency{Distribution: fault.DistributionFixed, FixedMs: 100},
+		},
+		{
+			Operation: "EvaluateFeature",
+			Project:   "p",
+			Latency:   &fault.Latency{Distribution: fault.DistributionUniform, MinMs: 10, MaxMs: 20},
+			ErrorRate: 0.5,
+			Error:     fault.ErrorThrottling,
+		},
+		{
+			Project:   "unstable",
+			Latency:   &fault.Latency{Distribution: fault.DistributionNormal, MeanMs: 100, StddevMs: 40},
+			ErrorRate: 1,
+			Error:     fault.ErrorServiceUnavailable,
+		},
+	}
+
+	cases := []struct {
+		name      string
+		rand      float64
+		norm      float64
+		operation string
+		project   string
+		feature   string
+		expect    fault.Fault
+		status    int
+	}{
+		{
+			name:      "first matched rule is used",
+			operation: "EvaluateFeature",
+			project:   "p",
+			feature:   "slow",
+			expect:    fault.Fault{Latency: 100 * time.Millisecond},
+		},
+		{
+			name:      "uniform latency and error",
+			rand:      0.25,
+			operation: "EvaluateFeature",
+			project:   "p",
+			feature:   "f",
+			expect:    fault.Fault{Latency: 12500 * time.Microsecond, Error: fault.ErrorThrottling},
+			status:    429,
+		},
+		{
+			name:      "error rate is not hit",
+			rand:      0.5,
+			operation: "EvaluateFeature",
+			project:   "p",
+			feature:   "f",
+			expect:    fault.Fault{Latency: 15 * time.Millisecond},
+		},
+		{
+			name:      "normal latency",
+			norm:      1.5,
+			operation: "BatchEvaluateFeature",
+			project:   "unstable",
+			expect:    fault.Fault{Latency: 160 * time.Millisecond, Error: fault.ErrorServiceUnavailable},
+			status:    503,
+		},
+		{
+			name:      "normal latency is not negative",
+			norm:      -3,
+			operation: "GetFeature",
+			project:   "unstable",
+			feature:   "f",
+			expect:    fault.Fault{Error: fault.ErrorServiceUnavailable},
+			status:    503,
+		},
+		{
+			name:      "no rules match",
+			operation: "BatchEvaluateFeature",
+			project:   "p",
+			expect:    fault.Fault{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			inj, err := fault.NewInjector(rules)
+			asst.NoError(err)
+			fault.SetRandForTest(inj, func() float64 { return c.rand }, func() float64 { return c.norm })
+
+			got := inj.Decide(c.operation, c.project, c.feature)
+			asst.Equal(c.expect, got)
+			asst.Equal(c.status, got.Status())
+		})
+	}
+}
+
+func Test_Injector_SetRules(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   []fault.Rule
+		wantErr bool
+	}{
+		{
+			name:  "no rules",
+			rules: nil,
+		},
+		{
+			name: "valid",
+			rules: []fault.Rule{
+				{Latency: &fault.Latency{Distribution: fault.DistributionFixed, FixedMs: 0}},
+				{ErrorRate: 1, Error: fault.ErrorInternalServer},
+				{Operation: "EvaluateFeature", ErrorRate: 0.1, Error: fault.ErrorServiceUnavailable},
+			},
+		},
+		{
+			name:    "error without error rate",
+			rules:   []fault.Rule{{Error: fault.ErrorThrottling}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown operation",
+			rules:   []fault.Rule{{Operation: "EvaluateFeatures", ErrorRate: 1, Error: fault.ErrorThrottling}},
+			wantErr: true,
+		},
+		{
+			name:    "error rate is greater than 1",
+			rules:   []fault.Rule{{ErrorRate: 1.5, Error: fault.ErrorThrottling}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported error",
+			rules:   []fault.Rule{{ErrorRate: 0.5, Error: "AccessDeniedException"}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported distribution",
+			rules:   []fault.Rule{{Latency: &fault.Latency{Distribution: "exponential"}}},
+			wantErr: true,
+		},
+		{
+			name:    "max is less than min",
+			rules:   []fault.Rule{{Latency: &fault.Latency{Distribution: fault.DistributionUniform, MinMs: 20, MaxMs: 10}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative stddev",
+			rules:   []fault.Rule{{Latency: &fault.Latency{Distribution: fault.DistributionNormal, MeanMs: 20, StddevMs: -1}}},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			initial := []fault.Rule{{ErrorRate: 1, Error: fault.ErrorThrottling}}
+			inj, err := fault.NewInjector(initial)
+			asst.NoError(err)
+
+			err = inj.SetRules(c.rules)
+			if c.wantErr {
+				asst.Error(err)
+				// rules are not changed
+				asst.Equal(initial, inj.Rules())
+				return
+			}
+
+			asst.NoError(err)
+			asst.Len(inj.Rules(), len(c.rules))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/repository"
+)
+
+// AdminHandler handles endpoints under /_admin/ that are not part of the Evidently API.
+type AdminHandler struct {
+	l    logger.Logger
+	repo repository.FeatureRepository
+	al   *audit.Log
+}
+
+// NewAdminHandler returns a handler of /_admin/. al can be nil if the audit log is not used.
+func NewAdminHandler(l logger.Logger, repo repository.FeatureRepository, al *audit.Log) *AdminHandler {
+	return &AdminHandler{
+		l:    l,
+		repo: repo,
+		al:   al,
+	}
+}
+
+// Snapshot dumps all resources of the server as a bundle.
+//
+//	GET /_admin/snapshot[?format=json|yaml]
+func (h *AdminHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.l.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = repository.BundleFormatJSON
+	}
+
+	contentType := "application/json"
+	switch format {
+	case repository.BundleFormatJSON:
+		// noop
+	case repository.BundleFormatYAML:
+		contentType = "application/yaml"
+	default:
+		h.l.Error("Unsupported format", nil, "format", format)
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	bundle, err := h.repo.Snapshot()
+	if err != nil {
+		h.l.Error("Failed to get snapshot", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := repository.MarshalBundle(bundle, format)
+	if err != nil {
+		h.l.Error("Failed to marshal snapshot", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
+
+// Layers shows layers that define each feature, when the server loads multiple data directories.
+//
+//	GET /_admin/layers
+func (h *AdminHandler) Layers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.l.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reporter, ok := h.repo.(repository.FeatureOriginReporter)
+	if !ok {
+		h.l.Error("Repository does not have layers", nil)
+		http.Error(w, "Layers are not configured", http.StatusNotFound)
+		return
+	}
+
+	origins, err := reporter.FeatureOrigins()
+	if err != nil {
+		h.l.Error("Failed to get feature origins", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(map[string]any{"features": origins})
+	if err != nil {
+		h.l.Error("Failed to marshal feature origins", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
+
+// EvaluationsResponse is the response of /_admin/evaluations.
+type EvaluationsResponse struct {
+	Evaluations []audit.Record `json:"evaluations"`
+}
+
+// Evaluations returns recorded evaluations from the oldest to the latest, so that end-to-end tests can assert
+// which variation was served to which entity. All query parameters are optional.
+//
+//	GET /_admin/evaluations[?project=&feature=&entityId=&limit=]
+func (h *AdminHandler) Evaluations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.l.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.al == nil {
+		h.l.Error("Audit log is not configured", nil)
+		http.Error(w, "Audit log is not configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	q := audit.Query{
+		Project:  query.Get("project"),
+		Feature:  query.Get("feature"),
+		EntityID: query.Get("entityId"),
+	}
+
+	if limit := query.Get("limit"); len(limit) > 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			h.l.Error("Invalid limit", err, "limit", limit)
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+
+	bytes, err := json.Marshal(EvaluationsResponse{Evaluations: h.al.Query(q)})
+	if err != nil {
+		h.l.Error("Failed to marshal evaluations", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,245 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	ah := handler.NewAdminHandler(testLogger, handler.RepositoryForTest(), nil)
+
+	cases := []struct {
+		name                string
+		reqPath             string
+		method              string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name:                "json",
+			reqPath:             "/_admin/snapshot",
+			method:              http.MethodGet,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "yaml",
+			reqPath:             "/_admin/snapshot?format=yaml",
+			method:              http.MethodGet,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/yaml",
+		},
+		{
+			name:           "unsupported format",
+			reqPath:        "/_admin/snapshot?format=toml",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "method not allowed",
+			reqPath:        "/_admin/snapshot",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			req := httptest.NewRequest(c.method, c.reqPath, nil)
+			w := httptest.NewRecorder()
+
+			ah.Snapshot(w, req)
+
+			asst.Equal(c.expectedStatus, w.Code)
+			if c.expectedStatus != http.StatusOK {
+				return
+			}
+
+			asst.Equal(c.expectedContentType, w.Header().Get("Content-Type"))
+			if c.expectedContentType == "application/json" {
+				bundle := &models.Bundle{}
+				asst.NoError(json.Unmarshal(w.Body.Bytes(), bundle))
+				asst.Len(bundle.Projects, 5)
+				asst.Len(bundle.Features, 4)
+			}
+		})
+	}
+}
+
+func Test_Layers(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	cases := []struct {
+		name           string
+		prepare        func()
+		method         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "layers are not configured",
+			prepare:        func() { handler.PrepareForTest(testLogger) },
+			method:         http.MethodGet,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Layers are not configured\n",
+		},
+		{
+			name:           "method not allowed",
+			prepare:        func() { handler.PrepareForLayersTest(testLogger) },
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "Method not allowed\n",
+		},
+		{
+			name:           "success",
+			prepare:        func() { handler.PrepareForLayersTest(testLogger) },
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"features":[` +
+				`{"project":"test-project","name":"base-feature","layers":[{"dataDir":"../testdata/layers/base","file":"../testdata/layers/base/projects/test-project/features/base-feature.json","fields":["defaultVariation","entityOverrides","name","project","status","valueType","variations"]}]},` +
+				`{"project":"test-project","name":"layered-feature","layers":[{"dataDir":"../testdata/layers/base","file":"../testdata/layers/base/projects/test-project/features/layered-feature.json","fields":["defaultVariation","entityOverrides","name","project","status","valueType","variations"]},{"dataDir":"../testdata/layers/overlay","file":"../testdata/layers/overlay/projects/test-project/features/layered-feature.yaml","fields":["defaultVariation","entityOverrides"]}]},` +
+				`{"project":"test-project","name":"overlay-feature","layers":[{"dataDir":"../testdata/layers/overlay","file":"../testdata/layers/overlay/projects/test-project/features/overlay-feature.json","fields":["defaultVariation","entityOverrides","name","project","status","valueType","variations"]}]}` +
+				`]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			c.prepare()
+			ah := handler.NewAdminHandler(testLogger, handler.RepositoryForTest(), nil)
+
+			req := httptest.NewRequest(c.method, "/_admin/layers", nil)
+			w := httptest.NewRecorder()
+
+			ah.Layers(w, req)
+
+			asst.Equal(c.expectedStatus, w.Code)
+			asst.Equal(c.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func Test_Evaluations(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	al, err := audit.New(audit.Options{})
+	assert.NoError(t, err)
+
+	// evaluate through the project handler to record evaluations with request IDs
+	ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{Recorder: al})
+	evaluate := handler.AccessLog(testLogger, http.HandlerFunc(ph.Projects))
+	for _, entityID := range []string{"user-1", "user-2", "user-1"} {
+		body := strings.NewReader(`{"entityId":"` + entityID + `","evaluationContext":"{\"plan\":\"free\"}"}`)
+		req := httptest.NewRequest(http.MethodPost, "/projects/test-project/evaluations/test-feature-1", body)
+		w := httptest.NewRecorder()
+		evaluate.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	cases := []struct {
+		name           string
+		al             *audit.Log
+		method         string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "all",
+			al:             al,
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedCount:  3,
+		},
+		{
+			name:           "by entity ID and feature",
+			al:             al,
+			method:         http.MethodGet,
+			query:          "?entityId=user-1&feature=test-feature-1",
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:           "by other feature",
+			al:             al,
+			method:         http.MethodGet,
+			query:          "?feature=other-feature",
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+		{
+			name:           "limit",
+			al:             al,
+			method:         http.MethodGet,
+			query:          "?limit=1",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "invalid limit",
+			al:             al,
+			method:         http.MethodGet,
+			query:          "?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "method not allowed",
+			al:             al,
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "audit log is not configured",
+			al:             nil,
+			method:         http.MethodGet,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			ah := handler.NewAdminHandler(testLogger, handler.RepositoryForTest(), c.al)
+
+			req := httptest.NewRequest(c.method, "/_admin/evaluations"+c.query, nil)
+			w := httptest.NewRecorder()
+
+			ah.Evaluations(w, req)
+
+			asst.Equal(c.expectedStatus, w.Code)
+			if c.expectedStatus != http.StatusOK {
+				return
+			}
+
+			res := handler.EvaluationsResponse{}
+			asst.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+			asst.Len(res.Evaluations, c.expectedCount)
+			for _, e := range res.Evaluations {
+				asst.Equal("test-project", e.Project)
+				asst.Equal("test-feature-1", e.Feature)
+				asst.Equal(`{"plan":"free"}`, e.EvaluationContext)
+				asst.NotEmpty(e.Variation)
+				asst.NotEmpty(e.Reason)
+				asst.NotEmpty(e.RequestID)
+				asst.False(e.Timestamp.IsZero())
+			}
+		})
+	}
+}
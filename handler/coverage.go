@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/logger"
+)
+
+// Formats of the coverage report.
+const (
+	CoverageFormatJSON = "json"
+	CoverageFormatText = "text"
+)
+
+// CoverageHandler handles the coverage report of features.
+type CoverageHandler struct {
+	l logger.Logger
+	t *coverage.Tracker
+}
+
+func NewCoverageHandler(l logger.Logger, t *coverage.Tracker) *CoverageHandler {
+	return &CoverageHandler{
+		l: l,
+		t: t,
+	}
+}
+
+// Report returns features never evaluated, variations never served and overrides never hit since the server started.
+//
+//	GET /_admin/coverage[?format=json|text]
+func (h *CoverageHandler) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.l.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = CoverageFormatJSON
+	}
+
+	if format != CoverageFormatJSON && format != CoverageFormatText {
+		h.l.Error("Unsupported format", nil, "format", format)
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.t.Report()
+	if err != nil {
+		h.l.Error("Failed to generate coverage report", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if format == CoverageFormatText {
+		text := &strings.Builder{}
+		_ = report.WriteText(text)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(text.String()))
+		return
+	}
+
+	bytes, err := json.Marshal(report)
+	if err != nil {
+		h.l.Error("Failed to marshal coverage report", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,87 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Coverage(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	tracker := coverage.NewTracker(handler.RepositoryForTest())
+	tracker.Record(audit.Record{Project: "test-project", Feature: "test-feature-1", EntityID: "user", Variation: "False", Reason: "DEFAULT"})
+
+	ch := handler.NewCoverageHandler(testLogger, tracker)
+
+	cases := []struct {
+		name                string
+		reqPath             string
+		method              string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name:                "json",
+			reqPath:             "/_admin/coverage",
+			method:              http.MethodGet,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "text",
+			reqPath:             "/_admin/coverage?format=text",
+			method:              http.MethodGet,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:           "unsupported format",
+			reqPath:        "/_admin/coverage?format=html",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "method not allowed",
+			reqPath:        "/_admin/coverage",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			req := httptest.NewRequest(c.method, c.reqPath, nil)
+			w := httptest.NewRecorder()
+
+			ch.Report(w, req)
+
+			asst.Equal(c.expectedStatus, w.Code)
+			if c.expectedStatus != http.StatusOK {
+				return
+			}
+
+			asst.Equal(c.expectedContentType, w.Header().Get("Content-Type"))
+			if c.expectedContentType == "application/json" {
+				report := &coverage.Report{}
+				asst.NoError(json.Unmarshal(w.Body.Bytes(), report))
+				asst.Equal(4, report.Summary.Features)
+				asst.Equal(1, report.Summary.EvaluatedFeatures)
+				asst.Equal(1, report.Summary.ServedVariations)
+			} else {
+				asst.Contains(w.Body.String(), "Flag coverage: 1/4 features evaluated")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// writeErrorResponse writes an error response in the same form as AWS.
+// The error type is set to the x-amzn-ErrorType header.
+func writeErrorResponse(w http.ResponseWriter, l logger.Logger, status int, errorType types.ErrorType, body types.ErrorResponse) {
+	bytes, requestID, err := internal.GenerateResponseBody(body)
+	if err != nil {
+		l.Error("Failed to generate error response body", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setRequestIDHeader(w, requestID)
+	w.Header().Set("x-amzn-ErrorType", string(errorType))
+	w.WriteHeader(status)
+	_, _ = w.Write(bytes)
+}
+
+func writeValidationException(w http.ResponseWriter, l logger.Logger, err error) {
+	body := types.ErrorResponse{
+		Message: err.Error(),
+		Reason:  types.ValidationExceptionReasonOther,
+	}
+
+	ve := &internal.ValidationError{}
+	if errors.As(err, &ve) {
+		body.Reason = ve.Reason
+		body.FieldList = ve.Fields
+	}
+
+	writeErrorResponse(w, l, http.StatusBadRequest, types.ErrorTypeValidationException, body)
+}
@@ -1,32 +1,74 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/michimani/evidentlylocal/audit"
 	"github.com/michimani/evidentlylocal/components"
 	"github.com/michimani/evidentlylocal/internal"
 	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/metrics"
+	"github.com/michimani/evidentlylocal/models"
 	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/tracing"
 	"github.com/michimani/evidentlylocal/types"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type evaluationHandler struct {
-	l logger.Logger
+	l    logger.Logger
+	repo repository.FeatureRepository
+	m    *metrics.Metrics
+	rec  audit.Recorder
+	ix   *components.Index
 }
 
-func newEvaluationHandler(l logger.Logger) *evaluationHandler {
+func newEvaluationHandler(l logger.Logger, repo repository.FeatureRepository, m *metrics.Metrics, rec audit.Recorder, ix *components.Index) *evaluationHandler {
 	return &evaluationHandler{
-		l: l,
+		l:    l,
+		repo: repo,
+		m:    m,
+		rec:  rec,
+		ix:   ix,
 	}
 }
 
+// evaluateFeatureResponse is types.EvaluateFeatureResponse with the pre-encoded value of the variation.
+type evaluateFeatureResponse struct {
+	Details   string                 `json:"details"`
+	Reason    types.EvaluationReason `json:"reason"`
+	Value     json.RawMessage        `json:"value"`
+	Variation string                 `json:"variation"`
+}
+
+// batchEvaluateFeatureResponse is types.BatchEvaluateFeatureResponse with pre-encoded values of variations.
+type batchEvaluateFeatureResponse struct {
+	Results []evaluationResult `json:"results"`
+}
+
+type evaluationResult struct {
+	Details   string                 `json:"details"`
+	EntityID  string                 `json:"entityId"`
+	Feature   string                 `json:"feature"`
+	Project   string                 `json:"project"`
+	Reason    types.EvaluationReason `json:"reason"`
+	Variation string                 `json:"variation"`
+	Value     json.RawMessage        `json:"value"`
+}
+
 func (h *evaluationHandler) evaluateFeature(w http.ResponseWriter, r *http.Request) {
+	rl := requestLogger(h.l, r)
+
 	if r.Method != http.MethodPost {
-		h.l.Error("Method not allowed: "+r.Method, nil)
+		rl.Error("Method not allowed", nil, "method", r.Method)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -35,7 +77,7 @@ func (h *evaluationHandler) evaluateFeature(w http.ResponseWriter, r *http.Reque
 	parts := strings.Split(path, "/")
 
 	if len(parts) != 5 {
-		h.l.Error("Invalid path: "+path, nil)
+		rl.Error("Invalid path", nil, "path", path)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
@@ -43,55 +85,89 @@ func (h *evaluationHandler) evaluateFeature(w http.ResponseWriter, r *http.Reque
 	project := parts[2]
 	featureName := parts[4]
 
-	feature, err := repository.FeatureRepositoryInstance().Get(project, featureName)
-	if err != nil {
-		h.l.Error("Failed to get feature", err)
-		http.Error(w, "Not found", http.StatusNotFound)
+	if err := validateProjectAndFeatureNames(project, featureName); err != nil {
+		rl.Error("Invalid path parameter", err, "path", path)
+		writeValidationException(w, rl, err)
 		return
 	}
 
+	l := rl.With("project", project, "feature", featureName)
+
+	ctx := r.Context()
+	trace.SpanFromContext(ctx).SetAttributes(
+		tracing.AttributeProject.String(project),
+		tracing.AttributeFeature.String(featureName),
+	)
+
 	request := &types.EvaluateFeatureRequest{}
-	err = json.NewDecoder(r.Body).Decode(request)
-	if err != nil {
-		h.l.Error("Failed to decode request body", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := decodeAndValidate(r, request, func() error {
+		return internal.ValidateEvaluateFeatureRequest(request)
+	}); err != nil {
+		l.Error("Invalid request body", err)
+		writeValidationException(w, l, err)
 		return
 	}
 
 	entityID := request.EntityID
 
-	reason, variation, err := components.EvaluateFeature(feature, entityID)
+	reason, variation, err := h.evaluate(ctx, project, featureName, entityID)
+	if nf := (*featureNotFoundError)(nil); errors.As(err, &nf) {
+		l.Error("Failed to get feature", err)
+		writeErrorResponse(w, l, http.StatusNotFound, types.ErrorTypeResourceNotFoundException, types.ErrorResponse{
+			Message:      nf.Error(),
+			ResourceID:   featureResourceID(project, featureName),
+			ResourceType: "feature",
+		})
+		return
+	}
 	if err != nil {
-		h.l.Error("Failed to evaluate feature", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		l.Error("Failed to evaluate feature", err)
+		writeErrorResponse(w, l, http.StatusInternalServerError, types.ErrorTypeInternalServerException, types.ErrorResponse{
+			Message: "Failed to evaluate feature: " + featureName,
+		})
 		return
 	}
 
-	h.l.Info(fmt.Sprintf("return variation: %+v", variation))
+	l.Info("Evaluated feature", "entityId", entityID, "variation", variation.Name, "reason", reason, "value", variation.Value)
+	h.m.ObserveEvaluation(project, featureName, variation.Name, string(reason))
+	h.record(ctx, audit.Record{
+		Project:           project,
+		Feature:           featureName,
+		EntityID:          entityID,
+		EvaluationContext: request.EvaluationContext,
+		Variation:         variation.Name,
+		Reason:            string(reason),
+	})
+	trace.SpanFromContext(ctx).SetAttributes(
+		tracing.AttributeVariation.String(variation.Name),
+		tracing.AttributeReason.String(string(reason)),
+	)
 
-	res := types.EvaluateFeatureResponse{
+	res := evaluateFeatureResponse{
 		Details:   "{}",
 		Reason:    reason,
-		Value:     variation.Value,
+		Value:     variation.Encoded,
 		Variation: variation.Name,
 	}
 
-	bytes, requestID, err := internal.GenerateResponseBody(res)
+	bytes, requestID, err := encodeResponse(ctx, res)
 	if err != nil {
-		h.l.Error("Failed to generate response body", err)
+		l.Error("Failed to generate response body", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("x-amzn-RequestId", requestID)
+	setRequestIDHeader(w, requestID)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(bytes)
 }
 
 func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.Request) {
+	rl := requestLogger(h.l, r)
+
 	if r.Method != http.MethodPost {
-		h.l.Error("Method not allowed: "+r.Method, nil)
+		rl.Error("Method not allowed", nil, "method", r.Method)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -100,22 +176,34 @@ func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.
 	parts := strings.Split(path, "/")
 
 	if len(parts) != 4 {
-		h.l.Error("Invalid path: "+path, nil)
+		rl.Error("Invalid path", nil, "path", path)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
 	project := parts[2]
 
+	if err := internal.ValidateProjectName(project); err != nil {
+		rl.Error("Invalid path parameter", err, "path", path)
+		writeValidationException(w, rl, err)
+		return
+	}
+
+	pl := rl.With("project", project)
+
+	ctx := r.Context()
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttributeProject.String(project))
+
 	request := &types.BatchEvaluateFeatureRequest{}
-	err := json.NewDecoder(r.Body).Decode(request)
-	if err != nil {
-		h.l.Error("Failed to decode request body", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := decodeAndValidate(r, request, func() error {
+		return internal.ValidateBatchEvaluateFeatureRequest(request)
+	}); err != nil {
+		pl.Error("Invalid request body", err)
+		writeValidationException(w, pl, err)
 		return
 	}
 
-	results := make([]types.EvaluationResult, len(request.Requests))
+	results := make([]evaluationResult, len(request.Requests))
 
 	wg := sync.WaitGroup{}
 	for i, req := range request.Requests {
@@ -123,10 +211,15 @@ func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.
 
 		go func(i int, req types.EvaluationRequest) {
 			defer wg.Done()
-			feature, err := repository.FeatureRepositoryInstance().Get(project, req.Feature)
-			if err != nil {
-				h.l.Error("Failed to get feature", err)
-				results[i] = types.EvaluationResult{
+			l := pl.With("feature", req.Feature)
+
+			ctx, span := tracing.Start(ctx, "evaluation request", tracing.AttributeFeature.String(req.Feature))
+			defer span.End()
+
+			reason, variation, err := h.evaluate(ctx, project, req.Feature, req.EntityID)
+			if nf := (*featureNotFoundError)(nil); errors.As(err, &nf) {
+				l.Error("Failed to get feature", err)
+				results[i] = evaluationResult{
 					EntityID: req.EntityID,
 					Feature:  req.Feature,
 					Project:  project,
@@ -134,12 +227,9 @@ func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.
 				}
 				return
 			}
-
-			reason, variation, err := components.EvaluateFeature(feature, req.EntityID)
 			if err != nil {
-				h.l.Error("Failed to evaluate feature", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				results[i] = types.EvaluationResult{
+				l.Error("Failed to evaluate feature", err)
+				results[i] = evaluationResult{
 					EntityID: req.EntityID,
 					Feature:  req.Feature,
 					Project:  project,
@@ -148,13 +238,24 @@ func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.
 				return
 			}
 
-			res := types.EvaluationResult{
+			l.Info("Evaluated feature", "entityId", req.EntityID, "variation", variation.Name, "reason", reason, "value", variation.Value)
+			h.m.ObserveEvaluation(project, req.Feature, variation.Name, string(reason))
+			h.record(ctx, audit.Record{
+				Project:           project,
+				Feature:           req.Feature,
+				EntityID:          req.EntityID,
+				EvaluationContext: req.EvaluationContext,
+				Variation:         variation.Name,
+				Reason:            string(reason),
+			})
+
+			res := evaluationResult{
 				Details:   "{}",
 				EntityID:  req.EntityID,
 				Feature:   req.Feature,
 				Project:   project,
 				Reason:    reason,
-				Value:     variation.Value,
+				Value:     variation.Encoded,
 				Variation: variation.Name,
 			}
 
@@ -164,19 +265,125 @@ func (h *evaluationHandler) batchEvaluateFeature(w http.ResponseWriter, r *http.
 
 	wg.Wait()
 
-	res := types.BatchEvaluateFeatureResponse{
+	res := batchEvaluateFeatureResponse{
 		Results: results,
 	}
 
-	bytes, requestID, err := internal.GenerateResponseBody(res)
+	bytes, requestID, err := encodeResponse(ctx, res)
 	if err != nil {
-		h.l.Error("Failed to generate response body", err)
+		pl.Error("Failed to generate response body", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("x-amzn-RequestId", requestID)
+	setRequestIDHeader(w, requestID)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(bytes)
 }
+
+// record records the result of the evaluation with the request ID, if a recorder is set.
+func (h *evaluationHandler) record(ctx context.Context, r audit.Record) {
+	if h.rec == nil {
+		return
+	}
+
+	r.Timestamp = time.Now()
+	r.RequestID = RequestID(ctx)
+	h.rec.Record(r)
+}
+
+// featureNotFoundError is returned by evaluate if the feature is not defined.
+// The message is returned to clients in ResourceNotFoundException.
+type featureNotFoundError struct {
+	err error
+}
+
+func (e *featureNotFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e *featureNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// evaluate evaluates the feature with the compiled plan in the index.
+// If the index is not built, the feature is read from the repository and evaluated.
+func (h *evaluationHandler) evaluate(ctx context.Context, project, featureName, entityID string) (types.EvaluationReason, *components.Variation, error) {
+	if h.ix.Built() {
+		plan, ok := h.ix.Plan(project, featureName)
+		if !ok {
+			return "", nil, &featureNotFoundError{err: fmt.Errorf("Feature not found: %s", featureName)}
+		}
+
+		reason, v := plan.Evaluate(ctx, entityID)
+		return reason, v, nil
+	}
+
+	feature, err := h.getFeature(ctx, project, featureName)
+	if errors.Is(err, repository.ErrFeatureNotFound) {
+		return "", nil, &featureNotFoundError{err: err}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	reason, v, err := components.EvaluateFeature(ctx, feature, entityID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cv, err := components.NewVariation(v.Name, v.Value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return reason, cv, nil
+}
+
+// getFeature gets the feature from the repository in a span.
+func (h *evaluationHandler) getFeature(ctx context.Context, project, featureName string) (*models.Feature, error) {
+	_, span := tracing.Start(ctx, "repository get feature",
+		tracing.AttributeProject.String(project),
+		tracing.AttributeFeature.String(featureName),
+	)
+	defer span.End()
+
+	feature, err := h.repo.Get(project, featureName)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return feature, nil
+}
+
+// encodeResponse generates the response body in a span.
+func encodeResponse(ctx context.Context, res any) ([]byte, string, error) {
+	_, span := tracing.Start(ctx, "encode response")
+	defer span.End()
+
+	return internal.GenerateResponseBody(res)
+}
+
+// decodeAndValidate decodes the request body strictly into request, then validates it by validate.
+func decodeAndValidate(r *http.Request, request any, validate func() error) error {
+	if err := internal.DecodeRequestBody(r.Body, request); err != nil {
+		return err
+	}
+
+	return validate()
+}
+
+func validateProjectAndFeatureNames(project, feature string) error {
+	if err := internal.ValidateProjectName(project); err != nil {
+		return err
+	}
+
+	return internal.ValidateFeatureName(feature)
+}
+
+func featureResourceID(project, feature string) string {
+	return project + "/feature/" + feature
+}
@@ -5,8 +5,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/michimani/evidentlylocal/components"
 	"github.com/michimani/evidentlylocal/handler"
 	"github.com/michimani/evidentlylocal/logger"
 	"github.com/stretchr/testify/assert"
@@ -26,7 +28,7 @@ func Test_evaluateFeature(t *testing.T) {
 	}{
 		{
 			name:           "default rule",
-			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusOK,
@@ -34,7 +36,7 @@ func Test_evaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "override rule",
-			reqBody:        `{"entityId":"force-true", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"force-true", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusOK,
@@ -42,11 +44,43 @@ func Test_evaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "feature not found",
-			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/not-exists-feature",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "Not found\n",
+			expectedBody:   `{"message":"Feature not found: not-exists-feature","resourceId":"test-project/feature/not-exists-feature","resourceType":"feature"}`,
+		},
+		{
+			name:           "project not found",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
+			reqPath:        "/projects/not-exists-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"Project not found: not-exists-project","resourceId":"not-exists-project/feature/test-feature-1","resourceType":"feature"}`,
+		},
+		{
+			name:           "feature defined in multiple files",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
+			reqPath:        "/projects/has-yaml-features-project/evaluations/duplicated-feature",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"Failed to evaluate feature: duplicated-feature"}`,
+		},
+		{
+			name:           "invalid project name",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
+			reqPath:        "/projects/../evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'project' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"project"}]}`,
+		},
+		{
+			name:           "invalid feature name",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
+			reqPath:        "/projects/test-project/evaluations/test%20feature",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'feature' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"feature"}]}`,
 		},
 		{
 			name:           "invalid request body",
@@ -54,19 +88,67 @@ func Test_evaluateFeature(t *testing.T) {
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"Unable to parse request body: invalid character '/' looking for beginning of value","reason":"cannotParse"}`,
 		},
 		{
-			name:           "method not allowed: GET",
+			name:           "with evaluation context",
+			reqBody:        `{"entityId":"force-true", "evaluationContext":"{\"browser\":\"Chrome\"}"}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"details":"{}","reason":"OVERRIDE_RULE","value":{"boolValue":true},"variation":"True"}`,
+		},
+		{
+			name:           "unknown field",
 			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Unable to parse request body: json: unknown field \"evaluateContext\"","reason":"cannotParse"}`,
+		},
+		{
+			name:           "trailing data",
+			reqBody:        `{"entityId":"test-entity-id"}{}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Unable to parse request body: unexpected data after the top-level value","reason":"cannotParse"}`,
+		},
+		{
+			name:           "entityId is missing",
+			reqBody:        `{"evaluationContext":"{}"}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'entityId' failed to satisfy constraint: Member must not be null or empty","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null or empty","name":"entityId"}]}`,
+		},
+		{
+			name:           "entityId is too long",
+			reqBody:        `{"entityId":"` + strings.Repeat("x", 513) + `"}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'entityId' failed to satisfy constraint: Member must have length between 1 and 512","reason":"fieldValidationFailed","fieldList":[{"message":"Member must have length between 1 and 512","name":"entityId"}]}`,
+		},
+		{
+			name:           "evaluationContext is not a valid JSON",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":"{browser:Chrome}"}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'evaluationContext' failed to satisfy constraint: Member must be a valid JSON","reason":"fieldValidationFailed","fieldList":[{"message":"Member must be a valid JSON","name":"evaluationContext"}]}`,
+		},
+		{
+			name:           "method not allowed: GET",
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
+			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodGet,
 			expectedStatus: http.StatusMethodNotAllowed,
 			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "method not allowed: PUT",
-			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPut,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -74,7 +156,7 @@ func Test_evaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "method not allowed: PATCH",
-			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPatch,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -82,7 +164,7 @@ func Test_evaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "method not allowed: HEAD",
-			reqBody:        `{"entityId":"test-entity-id", "evaluateContext":""}`,
+			reqBody:        `{"entityId":"test-entity-id", "evaluationContext":""}`,
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodHead,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -111,6 +193,15 @@ func Test_evaluateFeature(t *testing.T) {
 
 			asst.Equal(c.expectedStatus, w.Code)
 			asst.Equal(c.expectedBody, w.Body.String())
+			if c.expectedStatus == http.StatusBadRequest {
+				asst.Equal("ValidationException", w.Header().Get("x-amzn-ErrorType"))
+			}
+			if c.expectedStatus == http.StatusNotFound && strings.HasPrefix(c.expectedBody, "{") {
+				asst.Equal("ResourceNotFoundException", w.Header().Get("x-amzn-ErrorType"))
+			}
+			if c.expectedStatus == http.StatusInternalServerError {
+				asst.Equal("InternalServerException", w.Header().Get("x-amzn-ErrorType"))
+			}
 		})
 	}
 }
@@ -129,7 +220,7 @@ func Test_batchEvaluateFeature(t *testing.T) {
 	}{
 		{
 			name:           "one request",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusOK,
@@ -137,7 +228,7 @@ func Test_batchEvaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "two requests (default and override)",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""},{"entityId":"force-true", "feature": "test-feature-1", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""},{"entityId":"force-true", "feature": "test-feature-1", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusOK,
@@ -145,31 +236,79 @@ func Test_batchEvaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "with feature not found",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""},{"entityId":"test-entity-id", "feature": "not-exists-feature", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""},{"entityId":"test-entity-id", "feature": "not-exists-feature", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"results":[{"details":"{}","entityId":"test-entity-id","feature":"test-feature-1","project":"test-project","reason":"DEFAULT","variation":"False","value":{"boolValue":false}},{"details":"","entityId":"test-entity-id","feature":"not-exists-feature","project":"test-project","reason":"Feature not found","variation":"","value":null}]}`,
 		},
+		{
+			name:           "invalid project name",
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
+			reqPath:        "/projects/../evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'project' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"project"}]}`,
+		},
+		{
+			name:           "invalid feature name",
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""},{"entityId":"test-entity-id", "feature": "../test-feature-1", "evaluationContext":""}]}`,
+			reqPath:        "/projects/test-project/evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'requests.2.member.feature' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"requests.2.member.feature"}]}`,
+		},
 		{
 			name:           "invalid request body",
 			reqBody:        `///`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"Unable to parse request body: invalid character '/' looking for beginning of value","reason":"cannotParse"}`,
 		},
 		{
-			name:           "method not allowed: GET",
+			name:           "unknown field",
 			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Unable to parse request body: json: unknown field \"evaluateContext\"","reason":"cannotParse"}`,
+		},
+		{
+			name:           "requests is empty",
+			reqBody:        `{"requests":[]}`,
+			reqPath:        "/projects/test-project/evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'requests' failed to satisfy constraint: Member must have length between 1 and 20","reason":"fieldValidationFailed","fieldList":[{"message":"Member must have length between 1 and 20","name":"requests"}]}`,
+		},
+		{
+			name:           "too many requests",
+			reqBody:        `{"requests":[` + strings.Repeat(`{"entityId":"test-entity-id", "feature": "test-feature-1"},`, 20) + `{"entityId":"test-entity-id", "feature": "test-feature-1"}]}`,
+			reqPath:        "/projects/test-project/evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'requests' failed to satisfy constraint: Member must have length between 1 and 20","reason":"fieldValidationFailed","fieldList":[{"message":"Member must have length between 1 and 20","name":"requests"}]}`,
+		},
+		{
+			name:           "multiple invalid fields",
+			reqBody:        `{"requests":[{"feature": "test-feature-1"},{"entityId":"test-entity-id", "evaluationContext":"///"}]}`,
+			reqPath:        "/projects/test-project/evaluations",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"3 validation errors detected: Value at 'requests.1.member.entityId' failed to satisfy constraint: Member must not be null or empty; Value at 'requests.2.member.evaluationContext' failed to satisfy constraint: Member must be a valid JSON; Value at 'requests.2.member.feature' failed to satisfy constraint: Member must not be null or empty","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null or empty","name":"requests.1.member.entityId"},{"message":"Member must be a valid JSON","name":"requests.2.member.evaluationContext"},{"message":"Member must not be null or empty","name":"requests.2.member.feature"}]}`,
+		},
+		{
+			name:           "method not allowed: GET",
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
+			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodGet,
 			expectedStatus: http.StatusMethodNotAllowed,
 			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "method not allowed: PUT",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPut,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -177,7 +316,7 @@ func Test_batchEvaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "method not allowed: PATCH",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPatch,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -185,7 +324,7 @@ func Test_batchEvaluateFeature(t *testing.T) {
 		},
 		{
 			name:           "method not allowed: HEAD",
-			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluateContext":""}]}`,
+			reqBody:        `{"requests":[{"entityId":"test-entity-id", "feature": "test-feature-1", "evaluationContext":""}]}`,
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodHead,
 			expectedStatus: http.StatusMethodNotAllowed,
@@ -214,6 +353,121 @@ func Test_batchEvaluateFeature(t *testing.T) {
 
 			asst.Equal(c.expectedStatus, w.Code)
 			asst.Equal(c.expectedBody, w.Body.String())
+			if c.expectedStatus == http.StatusBadRequest {
+				asst.Equal("ValidationException", w.Header().Get("x-amzn-ErrorType"))
+			}
+		})
+	}
+}
+
+func Test_evaluationIndex(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	bundle, err := handler.RepositoryForTest().Snapshot()
+	assert.NoError(t, err)
+	ix := components.NewIndex()
+	assert.NoError(t, ix.Build(bundle))
+
+	cases := []struct {
+		name     string
+		reqBody  string
+		reqPath  string
+		evaluate func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name:     "default rule",
+			reqBody:  `{"entityId":"test-entity-id"}`,
+			reqPath:  "/projects/test-project/evaluations/test-feature-1",
+			evaluate: handler.Exported_evaluateFeature,
+		},
+		{
+			name:     "override rule",
+			reqBody:  `{"entityId":"force-true"}`,
+			reqPath:  "/projects/test-project/evaluations/test-feature-1",
+			evaluate: handler.Exported_evaluateFeature,
+		},
+		{
+			name:     "feature not found",
+			reqBody:  `{"entityId":"test-entity-id"}`,
+			reqPath:  "/projects/test-project/evaluations/not-exists-feature",
+			evaluate: handler.Exported_evaluateFeature,
+		},
+		{
+			name:     "batch",
+			reqBody:  `{"requests":[{"entityId":"test-entity-id","feature":"test-feature-1"},{"entityId":"force-true","feature":"test-feature-1"},{"entityId":"test-entity-id","feature":"not-exists-feature"}]}`,
+			reqPath:  "/projects/test-project/evaluations",
+			evaluate: handler.Exported_batchEvaluateFeature,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			serve := func(ix *components.Index) *httptest.ResponseRecorder {
+				handler.SetIndexForTest(ix)
+				defer handler.SetIndexForTest(nil)
+
+				w := httptest.NewRecorder()
+				c.evaluate(w, httptest.NewRequest(http.MethodPost, c.reqPath, strings.NewReader(c.reqBody)))
+				return w
+			}
+
+			// the index returns the same responses as the repository
+			expected := serve(nil)
+			got := serve(ix)
+			asst.Equal(expected.Code, got.Code)
+			asst.Equal(expected.Body.String(), got.Body.String())
+		})
+	}
+}
+
+func BenchmarkEvaluateFeature(b *testing.B) {
+	benchmarkEvaluation(b, "/projects/test-project/evaluations/test-feature-1", `{"entityId":"test-entity-id"}`)
+}
+
+func BenchmarkBatchEvaluateFeature(b *testing.B) {
+	requests := make([]string, 20)
+	for i := range requests {
+		requests[i] = `{"entityId":"test-entity-id","feature":"test-feature-1"}`
+	}
+	benchmarkEvaluation(b, "/projects/test-project/evaluations", `{"requests":[`+strings.Join(requests, ",")+`]}`)
+}
+
+// benchmarkEvaluation benchmarks evaluations that read files from the repository, and evaluations with the index.
+func benchmarkEvaluation(b *testing.B, path, body string) {
+	testLogger, _ := logger.NewEvidentlyLocalLoggerWithOptions(io.Discard, logger.Options{Level: "error", Format: logger.FormatJSON})
+	handler.PrepareForTest(testLogger)
+
+	bundle, err := handler.RepositoryForTest().Snapshot()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ix := components.NewIndex()
+	if err := ix.Build(bundle); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name string
+		ix   *components.Index
+	}{
+		{name: "repository", ix: nil},
+		{name: "index", ix: ix},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{Index: bc.ix})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				ph.Projects(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+				if w.Code != http.StatusOK {
+					b.Fatalf("unexpected status: %d", w.Code)
+				}
+			}
 		})
 	}
 }
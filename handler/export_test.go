@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/michimani/evidentlylocal/components"
 	"github.com/michimani/evidentlylocal/logger"
 	"github.com/michimani/evidentlylocal/repository"
 )
@@ -12,36 +13,48 @@ const (
 	dataDir = "../testdata"
 )
 
-var testLogger logger.Logger
+var (
+	testLogger     logger.Logger
+	testRepository repository.FeatureRepository
+	testIndex      *components.Index
+)
 
 func PrepareForTest(l logger.Logger) {
 	testLogger = l
-	repos, _ := repository.NewFeatureRepositoryWithJSONFile(dataDir, l)
-	repository.SetFeatureRepositoryInstance(repos)
+	testRepository, _ = repository.NewFeatureRepositoryWithJSONFile(dataDir, l)
+	testIndex = nil
+}
+
+// SetIndexForTest sets the evaluation index that is used by Exported_evaluateFeature and Exported_batchEvaluateFeature.
+func SetIndexForTest(ix *components.Index) {
+	testIndex = ix
+}
+
+func PrepareForLayersTest(l logger.Logger) {
+	testLogger = l
+	testRepository, _ = repository.NewFeatureRepositoryWithLayers([]string{dataDir + "/layers/base", dataDir + "/layers/overlay"}, l)
+}
+
+func RepositoryForTest() repository.FeatureRepository {
+	return testRepository
 }
 
 func Exported_handleSomeResources(w http.ResponseWriter, r *http.Request) {
-	ph := NewProjectHandler(testLogger)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	ph.pathParts = parts
-	ph.handleSomeResources(w, r)
+	ph := NewProjectHandler(testLogger, testRepository, ProjectHandlerOptions{})
+	ph.handleSomeResources(w, r, strings.Split(r.URL.Path, "/"))
 }
 
 func Exported_handleSpecificResource(w http.ResponseWriter, r *http.Request) {
-	ph := NewProjectHandler(testLogger)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	ph.pathParts = parts
-	ph.handleSpecificResource(w, r)
+	ph := NewProjectHandler(testLogger, testRepository, ProjectHandlerOptions{})
+	ph.handleSpecificResource(w, r, strings.Split(r.URL.Path, "/"))
 }
 
 func Exported_evaluateFeature(w http.ResponseWriter, r *http.Request) {
-	eh := newEvaluationHandler(testLogger)
+	eh := newEvaluationHandler(testLogger, testRepository, nil, nil, testIndex)
 	eh.evaluateFeature(w, r)
 }
 
 func Exported_batchEvaluateFeature(w http.ResponseWriter, r *http.Request) {
-	eh := newEvaluationHandler(testLogger)
+	eh := newEvaluationHandler(testLogger, testRepository, nil, nil, testIndex)
 	eh.batchEvaluateFeature(w, r)
 }
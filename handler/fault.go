@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// InjectFaults delays requests and returns AWS errors by the rules of the injector.
+// Latency is injected before the error, and the request is not handled if an error is injected.
+func InjectFaults(l logger.Logger, inj *fault.Injector, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation := OperationName(r)
+		project, feature := faultTarget(r.URL.Path)
+
+		f := inj.Decide(operation, project, feature)
+		if f.Latency > 0 {
+			t := time.NewTimer(f.Latency)
+			select {
+			case <-t.C:
+				// noop
+			case <-r.Context().Done():
+				// the client gave up
+				t.Stop()
+				return
+			}
+		}
+
+		if len(f.Error) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rl := requestLogger(l, r)
+		rl.Info("Injected fault", "operation", operation, "error", f.Error, "latencyMs", float64(f.Latency.Microseconds())/1000)
+		writeErrorResponse(w, rl, f.Status(), types.ErrorType(f.Error), types.ErrorResponse{
+			Message: "Injected " + f.Error + " by Evidently-Local",
+		})
+	})
+}
+
+// faultTarget returns the project and the feature of the path.
+func faultTarget(path string) (string, string) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[1] != "projects" {
+		return "", ""
+	}
+
+	if len(parts) == 5 && (parts[3] == "evaluations" || parts[3] == "features") {
+		return parts[2], parts[4]
+	}
+
+	return parts[2], ""
+}
+
+// FaultHandler handles rules of fault injection.
+type FaultHandler struct {
+	l   logger.Logger
+	inj *fault.Injector
+}
+
+func NewFaultHandler(l logger.Logger, inj *fault.Injector) *FaultHandler {
+	return &FaultHandler{
+		l:   l,
+		inj: inj,
+	}
+}
+
+// FaultsBody is the request and response body of /_admin/faults.
+type FaultsBody struct {
+	Rules []fault.Rule `json:"rules"`
+}
+
+// Faults gets, replaces or clears rules of fault injection.
+//
+//	GET    /_admin/faults
+//	PUT    /_admin/faults {"rules":[...]}
+//	DELETE /_admin/faults
+func (h *FaultHandler) Faults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// noop
+	case http.MethodPut:
+		body := FaultsBody{}
+		if err := internal.DecodeRequestBody(r.Body, &body); err != nil {
+			h.l.Error("Invalid request body", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.inj.SetRules(body.Rules); err != nil {
+			h.l.Error("Invalid fault rules", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.l.Info("Updated fault rules", "rules", len(body.Rules))
+	case http.MethodDelete:
+		_ = h.inj.SetRules(nil)
+		h.l.Info("Cleared fault rules")
+	default:
+		h.l.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bytes, err := json.Marshal(FaultsBody{Rules: h.inj.Rules()})
+	if err != nil {
+		h.l.Error("Failed to marshal fault rules", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InjectFaults(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	inj, err := fault.NewInjector([]fault.Rule{
+		{
+			Operation: "EvaluateFeature",
+			Feature:   "test-feature-1",
+			Latency:   &fault.Latency{Distribution: fault.DistributionFixed, FixedMs: 50},
+			ErrorRate: 1,
+			Error:     fault.ErrorThrottling,
+		},
+		{
+			Operation: "BatchEvaluateFeature",
+			Project:   "test-project",
+			ErrorRate: 1,
+			Error:     fault.ErrorInternalServer,
+		},
+	})
+	assert.NoError(t, err)
+
+	ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{})
+	h := handler.InjectFaults(testLogger, inj, http.HandlerFunc(ph.Projects))
+
+	cases := []struct {
+		name              string
+		reqPath           string
+		expectedStatus    int
+		expectedErrorType string
+		minLatency        time.Duration
+	}{
+		{
+			name:              "latency and throttling",
+			reqPath:           "/projects/test-project/evaluations/test-feature-1",
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedErrorType: fault.ErrorThrottling,
+			minLatency:        50 * time.Millisecond,
+		},
+		{
+			name:              "batch",
+			reqPath:           "/projects/test-project/evaluations",
+			expectedStatus:    http.StatusInternalServerError,
+			expectedErrorType: fault.ErrorInternalServer,
+		},
+		{
+			name:           "no rules match",
+			reqPath:        "/projects/test-project/evaluations/test-feature-2",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			body := `{"entityId":"user-1"}`
+			if strings.HasSuffix(c.reqPath, "/evaluations") {
+				body = `{"requests":[{"entityId":"user-1","feature":"test-feature-1"}]}`
+			}
+			req := httptest.NewRequest(http.MethodPost, c.reqPath, strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			start := time.Now()
+			h.ServeHTTP(w, req)
+
+			asst.GreaterOrEqual(time.Since(start), c.minLatency)
+			asst.Equal(c.expectedStatus, w.Code)
+			asst.Equal(c.expectedErrorType, w.Header().Get("x-amzn-ErrorType"))
+		})
+	}
+}
+
+func Test_Faults(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	cases := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedRules  int
+	}{
+		{
+			name:           "get",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedRules:  1,
+		},
+		{
+			name:           "put",
+			method:         http.MethodPut,
+			body:           `{"rules":[{"operation":"EvaluateFeature","errorRate":0.5,"error":"ThrottlingException"},{"latency":{"distribution":"uniform","minMs":10,"maxMs":100}}]}`,
+			expectedStatus: http.StatusOK,
+			expectedRules:  2,
+		},
+		{
+			name:           "delete",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusOK,
+			expectedRules:  0,
+		},
+		{
+			name:           "invalid rules",
+			method:         http.MethodPut,
+			body:           `{"rules":[{"errorRate":2,"error":"ThrottlingException"}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown field",
+			method:         http.MethodPut,
+			body:           `{"rules":[{"errorPercent":50}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			inj, _ := fault.NewInjector([]fault.Rule{{ErrorRate: 1, Error: fault.ErrorThrottling}})
+			fh := handler.NewFaultHandler(testLogger, inj)
+
+			req := httptest.NewRequest(c.method, "/_admin/faults", strings.NewReader(c.body))
+			w := httptest.NewRecorder()
+
+			fh.Faults(w, req)
+
+			asst.Equal(c.expectedStatus, w.Code)
+			if c.expectedStatus != http.StatusOK {
+				asst.Len(inj.Rules(), 1)
+				return
+			}
+
+			res := handler.FaultsBody{}
+			asst.NoError(json.Unmarshal(w.Body.Bytes(), &res))
+			asst.Len(res.Rules, c.expectedRules)
+			asst.Equal(inj.Rules(), res.Rules)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/metrics"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/michimani/evidentlylocal/repository"
+)
+
+// Version is the version of Evidently-Local. It can be set at build time by
+//
+//	-ldflags "-X github.com/michimani/evidentlylocal/handler.Version=v0.1.0"
+//
+// If it is empty, the version of the main module in the build info is used.
+var Version = ""
+
+// Readiness statuses.
+const (
+	ReadyStatusLoading = "loading"
+	ReadyStatusReady   = "ready"
+	ReadyStatusInvalid = "invalid"
+)
+
+// HealthHandler handles endpoints to probe the server.
+// Probes are not logged, because they are called periodically.
+type HealthHandler struct {
+	l      logger.Logger
+	repo   repository.FeatureRepository
+	m      *metrics.Metrics
+	limits quota.Limits
+
+	mu         sync.RWMutex
+	loaded     bool
+	report     *repository.LoadReport
+	violations []quota.Violation
+	err        error
+}
+
+// ReadyResponse is the response of /_ready.
+type ReadyResponse struct {
+	Status   string                 `json:"status"`
+	Projects int                    `json:"projects"`
+	Features int                    `json:"features"`
+	Errors   []repository.LoadError `json:"errors"`
+	// QuotaViolations are resources that cannot be deployed to AWS because they exceed limits of Evidently.
+	QuotaViolations []quota.Violation `json:"quotaViolations"`
+	Message         string            `json:"message,omitempty"`
+}
+
+// VersionResponse is the response of /_version.
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// NewHealthHandler returns a handler of probes. m can be nil to disable metrics.
+// Resources are checked against the quota limits each time they are loaded.
+func NewHealthHandler(l logger.Logger, repo repository.FeatureRepository, m *metrics.Metrics, limits quota.Limits) *HealthHandler {
+	return &HealthHandler{
+		l:      l,
+		repo:   repo,
+		m:      m,
+		limits: limits,
+	}
+}
+
+// Load loads all resources in the repository to check that the server is ready.
+// It is called again when files are changed, so that the load report and quota violations reflect the current files.
+// If the repository is not a repository.Loader, the server is ready without loading files,
+// but resources are still checked against the quota limits.
+func (h *HealthHandler) Load() {
+	report := &repository.LoadReport{Errors: []repository.LoadError{}}
+	violations := []quota.Violation{}
+	var err error
+
+	if loader, ok := h.repo.(repository.Loader); ok {
+		report, err = loader.Load()
+	}
+	h.m.ObserveRepositoryLoad(report, err)
+
+	if err == nil {
+		violations, err = h.checkQuotas()
+	}
+
+	switch {
+	case err != nil:
+		h.l.Error("Failed to load repository", err)
+	case len(report.Errors) > 0:
+		for _, e := range report.Errors {
+			h.l.Warn("Failed to load file", "file", e.File, "error", e.Error)
+		}
+	default:
+		// noop
+	}
+
+	for _, v := range violations {
+		h.l.Warn("Quota exceeded", "resource", v.Resource, "quota", v.Quota, "limit", v.Limit, "actual", v.Actual, "errorType", v.ErrorType)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.loaded = true
+	h.report = report
+	h.violations = violations
+	h.err = err
+}
+
+func (h *HealthHandler) checkQuotas() ([]quota.Violation, error) {
+	bundle, err := h.repo.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return quota.Check(bundle, h.limits), nil
+}
+
+// Health reports that the server is running.
+//
+//	GET /_health
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready reports that all resources are loaded without errors.
+// It returns 503 Service Unavailable while loading, or if any files cannot be loaded or any resources exceed quotas.
+//
+//	GET /_ready
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	res := ReadyResponse{
+		Status:          ReadyStatusLoading,
+		Errors:          []repository.LoadError{},
+		QuotaViolations: []quota.Violation{},
+	}
+	status := http.StatusServiceUnavailable
+
+	switch {
+	case !h.loaded:
+		// noop
+	case h.err != nil:
+		res.Status = ReadyStatusInvalid
+		res.Message = h.err.Error()
+	default:
+		res.Projects = h.report.Projects
+		res.Features = h.report.Features
+		res.Errors = h.report.Errors
+		res.QuotaViolations = h.violations
+		res.Status = ReadyStatusInvalid
+		if len(h.report.Errors) == 0 && len(h.violations) == 0 {
+			res.Status = ReadyStatusReady
+			status = http.StatusOK
+		}
+	}
+	h.mu.RUnlock()
+
+	h.writeJSON(w, status, res)
+}
+
+// Version reports the version of the server.
+//
+//	GET /_version
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, versionInfo())
+}
+
+func (h *HealthHandler) writeJSON(w http.ResponseWriter, status int, v any) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		h.l.Error("Failed to marshal response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(bytes)
+}
+
+// ServerVersion returns the version of the server in the same way as /_version.
+func ServerVersion() string {
+	return versionInfo().Version
+}
+
+func versionInfo() VersionResponse {
+	res := VersionResponse{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		if len(res.Version) == 0 {
+			res.Version = "unknown"
+		}
+		return res
+	}
+
+	if len(res.Version) == 0 {
+		res.Version = info.Main.Version
+	}
+	if len(res.Version) == 0 {
+		res.Version = "(devel)"
+	}
+
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			res.Commit = s.Value
+		}
+	}
+
+	return res
+}
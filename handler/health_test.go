@@ -0,0 +1,133 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HealthHandler_Ready(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	jsonRepo, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata", testLogger)
+	bundleRepo, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	notFoundRepo, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata/not-exists", testLogger)
+
+	cases := []struct {
+		name            string
+		repo            repository.FeatureRepository
+		load            bool
+		expectedStatus  int
+		expectedReady   string
+		expectedProject int
+		expectedFeature int
+		limits          quota.Limits
+		expectedErrors  int
+		expectedQuota   int
+		expectedMessage bool
+	}{
+		{
+			name:           "loading",
+			repo:           bundleRepo,
+			load:           false,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReady:  handler.ReadyStatusLoading,
+		},
+		{
+			name:            "ready",
+			repo:            bundleRepo,
+			load:            true,
+			expectedStatus:  http.StatusOK,
+			expectedReady:   handler.ReadyStatusReady,
+			expectedProject: 1,
+			expectedFeature: 2,
+		},
+		{
+			name:            "quota exceeded",
+			repo:            bundleRepo,
+			load:            true,
+			limits:          quota.Limits{FeaturesPerProject: quota.Limit(1)},
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedReady:   handler.ReadyStatusInvalid,
+			expectedProject: 1,
+			expectedFeature: 2,
+			expectedQuota:   1,
+		},
+		{
+			name:            "invalid files",
+			repo:            jsonRepo,
+			load:            true,
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedReady:   handler.ReadyStatusInvalid,
+			expectedProject: 5,
+			expectedFeature: 4,
+			expectedErrors:  3,
+		},
+		{
+			name:            "data directory not found",
+			repo:            notFoundRepo,
+			load:            true,
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedReady:   handler.ReadyStatusInvalid,
+			expectedMessage: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			h := handler.NewHealthHandler(testLogger, c.repo, nil, c.limits)
+			if c.load {
+				h.Load()
+			}
+
+			rec := httptest.NewRecorder()
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/_ready", nil))
+
+			asst.Equal(c.expectedStatus, rec.Code)
+			asst.Equal("application/json", rec.Header().Get("Content-Type"))
+
+			got := handler.ReadyResponse{}
+			asst.NoError(json.Unmarshal(rec.Body.Bytes(), &got))
+			asst.Equal(c.expectedReady, got.Status)
+			asst.Equal(c.expectedProject, got.Projects)
+			asst.Equal(c.expectedFeature, got.Features)
+			asst.Len(got.Errors, c.expectedErrors)
+			asst.Len(got.QuotaViolations, c.expectedQuota)
+			asst.Equal(c.expectedMessage, len(got.Message) > 0)
+		})
+	}
+}
+
+func Test_HealthHandler_HealthAndVersion(t *testing.T) {
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	h := handler.NewHealthHandler(testLogger, nil, nil, quota.Limits{})
+
+	rec := httptest.NewRecorder()
+	h.Health(rec, httptest.NewRequest(http.MethodGet, "/_health", nil))
+	asst.Equal(http.StatusOK, rec.Code)
+	asst.JSONEq(`{"status":"ok"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.Version(rec, httptest.NewRequest(http.MethodGet, "/_version", nil))
+	asst.Equal(http.StatusOK, rec.Code)
+	got := handler.VersionResponse{}
+	asst.NoError(json.Unmarshal(rec.Body.Bytes(), &got))
+	asst.NotEmpty(got.Version)
+	asst.Equal(runtime.Version(), got.GoVersion)
+
+	rec = httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodPost, "/_ready", nil))
+	asst.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
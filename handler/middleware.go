@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "x-amzn-RequestId"
+
+type contextKey int
+
+const requestIDContextKey contextKey = iota
+
+// probePaths are paths of probes and scrapes, whose access logs are written at debug level
+// because they are called periodically.
+var probePaths = []string{"/_health", "/_ready", "/metrics"}
+
+// AccessLog assigns a request ID to every request before it is handled, and sets it to
+// x-amzn-RequestId header of all responses including errors.
+// It writes one access log per request with the request ID, so that the request ID reported by SDKs
+// can be found in the server log.
+func AccessLog(l logger.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := internal.NewRequestID()
+		w.Header().Set(requestIDHeader, requestID)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDContextKey, requestID))
+
+		sw := internal.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		args := []any{
+			"requestId", requestID,
+			"operation", OperationName(r),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.Status,
+			"latencyMs", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", sw.Bytes,
+		}
+
+		if isProbe(r.URL.Path) {
+			l.Debug("Access", args...)
+			return
+		}
+		l.Info("Access", args...)
+	})
+}
+
+// Trace starts a span of the API operation of each request. Spans of the operation are children of it.
+// If the request has a trace context (e.g. traceparent header), the span is a child of the caller's span.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		operation := OperationName(r)
+		ctx, span := tracing.Tracer().Start(ctx, operation,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				tracing.AttributeOperation.String(operation),
+				tracing.AttributeRequestID.String(RequestID(ctx)),
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := internal.NewStatusWriter(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.Status))
+		if sw.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.Status))
+		}
+	})
+}
+
+// RequestID returns the request ID assigned by AccessLog, or an empty string if it is not assigned.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// requestLogger returns a logger that adds the request ID to all logs of the request.
+func requestLogger(l logger.Logger, r *http.Request) logger.Logger {
+	if id := RequestID(r.Context()); len(id) > 0 {
+		return l.With("requestId", id)
+	}
+
+	return l
+}
+
+// setRequestIDHeader sets x-amzn-RequestId header, unless it is already set by AccessLog.
+func setRequestIDHeader(w http.ResponseWriter, requestID string) {
+	if len(w.Header().Get(requestIDHeader)) > 0 {
+		return
+	}
+
+	w.Header().Set(requestIDHeader, requestID)
+}
+
+func isProbe(path string) bool {
+	for _, p := range probePaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AccessLog(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		reqBody        string
+		logLevel       string
+		expectedStatus int
+		expectedOp     string
+		expectLog      bool
+	}{
+		{
+			name:           "success",
+			method:         http.MethodPost,
+			path:           "/projects/test-project/evaluations/test-feature-1",
+			reqBody:        `{"entityId":"test"}`,
+			logLevel:       "info",
+			expectedStatus: http.StatusOK,
+			expectedOp:     "EvaluateFeature",
+			expectLog:      true,
+		},
+		{
+			name:           "not found",
+			method:         http.MethodPost,
+			path:           "/projects/test-project/evaluations/not-exists",
+			reqBody:        `{"entityId":"test"}`,
+			logLevel:       "info",
+			expectedStatus: http.StatusNotFound,
+			expectedOp:     "EvaluateFeature",
+			expectLog:      true,
+		},
+		{
+			name:           "validation error",
+			method:         http.MethodPost,
+			path:           "/projects/test-project/evaluations/test-feature-1",
+			reqBody:        `{}`,
+			logLevel:       "info",
+			expectedStatus: http.StatusBadRequest,
+			expectedOp:     "EvaluateFeature",
+			expectLog:      true,
+		},
+		{
+			name:           "probe is not logged at info level",
+			method:         http.MethodGet,
+			path:           "/_health",
+			logLevel:       "info",
+			expectedStatus: http.StatusOK,
+			expectLog:      false,
+		},
+		{
+			name:           "probe is logged at debug level",
+			method:         http.MethodGet,
+			path:           "/_health",
+			logLevel:       "debug",
+			expectedStatus: http.StatusOK,
+			expectedOp:     "Unknown",
+			expectLog:      true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out := &bytes.Buffer{}
+			l, _ := logger.NewEvidentlyLocalLoggerWithOptions(out, logger.Options{Level: c.logLevel, Format: logger.FormatJSON})
+
+			mux := http.NewServeMux()
+			mux.Handle("/projects/", http.HandlerFunc(handler.NewProjectHandler(l, handler.RepositoryForTest(), handler.ProjectHandlerOptions{}).Projects))
+			mux.HandleFunc("/_health", handler.NewHealthHandler(l, nil, nil, quota.Limits{}).Health)
+
+			rec := httptest.NewRecorder()
+			handler.AccessLog(l, mux).ServeHTTP(rec, httptest.NewRequest(c.method, c.path, strings.NewReader(c.reqBody)))
+
+			asst.Equal(c.expectedStatus, rec.Code)
+			requestID := rec.Header().Get("x-amzn-RequestId")
+			asst.NotEmpty(requestID)
+
+			var access map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				entry := map[string]any{}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					continue
+				}
+				// all logs of the request have the same request ID
+				if _, ok := entry["requestId"]; ok {
+					asst.Equal(requestID, entry["requestId"])
+				}
+				if entry["msg"] == "Access" {
+					access = entry
+				}
+			}
+
+			if !c.expectLog {
+				asst.Nil(access)
+				return
+			}
+
+			if asst.NotNil(access) {
+				asst.Equal(c.expectedOp, access["operation"])
+				asst.Equal(float64(c.expectedStatus), access["status"])
+				asst.Equal(float64(rec.Body.Len()), access["bytes"])
+				asst.Contains(access, "latencyMs")
+			}
+		})
+	}
+}
+
+func Test_RequestID(t *testing.T) {
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	got := ""
+	h := handler.AccessLog(testLogger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = handler.RequestID(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	asst.NotEmpty(got)
+	asst.Equal(rec.Header().Get("x-amzn-RequestId"), got)
+	asst.Empty(handler.RequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// resourceOperations are the names of operations of resources under a project,
+// in order of list, create, get, update and delete.
+var resourceOperations = map[string][5]string{
+	"features":    {"ListFeatures", "CreateFeature", "GetFeature", "UpdateFeature", "DeleteFeature"},
+	"launches":    {"ListLaunches", "CreateLaunch", "GetLaunch", "UpdateLaunch", "DeleteLaunch"},
+	"experiments": {"ListExperiments", "CreateExperiment", "GetExperiment", "UpdateExperiment", "DeleteExperiment"},
+}
+
+// OperationName returns the name of the Evidently API operation of the request (e.g. EvaluateFeature).
+// It returns "Unknown" if the request is not an operation of Evidently.
+func OperationName(r *http.Request) string {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) >= 2 && parts[1] == "segments" {
+		return segmentOperationName(r.Method, parts)
+	}
+
+	if len(parts) < 2 || parts[1] != "projects" {
+		return "Unknown"
+	}
+
+	switch len(parts) {
+	case 2:
+		return crudOperation(r.Method, [5]string{"ListProjects", "CreateProject"}, false)
+	case 3:
+		return crudOperation(r.Method, [5]string{2: "GetProject", 3: "UpdateProject", 4: "DeleteProject"}, true)
+	case 4, 5:
+		if parts[3] == "evaluations" {
+			if r.Method != http.MethodPost {
+				return "Unknown"
+			}
+			if len(parts) == 4 {
+				return "BatchEvaluateFeature"
+			}
+			return "EvaluateFeature"
+		}
+
+		ops, ok := resourceOperations[parts[3]]
+		if !ok {
+			return "Unknown"
+		}
+		return crudOperation(r.Method, ops, len(parts) == 5)
+	default:
+		// noop
+	}
+
+	return "Unknown"
+}
+
+func segmentOperationName(method string, parts []string) string {
+	switch len(parts) {
+	case 2:
+		return crudOperation(method, [5]string{"ListSegments", "CreateSegment"}, false)
+	case 3:
+		return crudOperation(method, [5]string{2: "GetSegment", 4: "DeleteSegment"}, true)
+	case 4:
+		if parts[3] == "references" && method == http.MethodGet {
+			return "ListSegmentReferences"
+		}
+	default:
+		// noop
+	}
+
+	return "Unknown"
+}
+
+func crudOperation(method string, ops [5]string, specific bool) string {
+	op := ""
+	switch {
+	case !specific && method == http.MethodGet:
+		op = ops[0]
+	case !specific && method == http.MethodPost:
+		op = ops[1]
+	case specific && method == http.MethodGet:
+		op = ops[2]
+	case specific && method == http.MethodPatch:
+		op = ops[3]
+	case specific && method == http.MethodDelete:
+		op = ops[4]
+	default:
+		// noop
+	}
+
+	if len(op) == 0 {
+		return "Unknown"
+	}
+
+	return op
+}
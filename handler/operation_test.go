@@ -0,0 +1,50 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/fault"
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OperationName(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodPost, path: "/projects/p/evaluations/f", want: "EvaluateFeature"},
+		{method: http.MethodPost, path: "/projects/p/evaluations", want: "BatchEvaluateFeature"},
+		{method: http.MethodGet, path: "/projects/p/evaluations/f", want: "Unknown"},
+		{method: http.MethodGet, path: "/projects", want: "ListProjects"},
+		{method: http.MethodPost, path: "/projects", want: "CreateProject"},
+		{method: http.MethodGet, path: "/projects/p", want: "GetProject"},
+		{method: http.MethodPatch, path: "/projects/p", want: "UpdateProject"},
+		{method: http.MethodDelete, path: "/projects/p", want: "DeleteProject"},
+		{method: http.MethodPost, path: "/projects/p", want: "Unknown"},
+		{method: http.MethodGet, path: "/projects/p/features", want: "ListFeatures"},
+		{method: http.MethodPost, path: "/projects/p/launches", want: "CreateLaunch"},
+		{method: http.MethodPatch, path: "/projects/p/experiments/e", want: "UpdateExperiment"},
+		{method: http.MethodGet, path: "/projects/p/unknown", want: "Unknown"},
+		{method: http.MethodGet, path: "/projects/p/features/f/x", want: "Unknown"},
+		{method: http.MethodGet, path: "/segments", want: "ListSegments"},
+		{method: http.MethodDelete, path: "/segments/s", want: "DeleteSegment"},
+		{method: http.MethodGet, path: "/segments/s/references", want: "ListSegmentReferences"},
+		{method: http.MethodPost, path: "/segments/s/references", want: "Unknown"},
+		{method: http.MethodGet, path: "/_health", want: "Unknown"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(tt *testing.T) {
+			asst := assert.New(tt)
+			asst.Equal(c.want, handler.OperationName(httptest.NewRequest(c.method, c.path, nil)))
+			if c.want != "Unknown" {
+				// fault rules can match the operation
+				asst.Contains(fault.Operations, c.want)
+			}
+		})
+	}
+}
@@ -1,29 +1,56 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/components"
+	"github.com/michimani/evidentlylocal/internal"
 	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/metrics"
+	"github.com/michimani/evidentlylocal/repository"
 )
 
 type ProjectHandler struct {
-	l         logger.Logger
-	pathParts []string
+	l    logger.Logger
+	repo repository.FeatureRepository
+	m    *metrics.Metrics
+	rec  audit.Recorder
+	ix   *components.Index
 }
 
-func NewProjectHandler(l logger.Logger) *ProjectHandler {
+// ProjectHandlerOptions are optional dependencies of ProjectHandler. Zero values disable them.
+type ProjectHandlerOptions struct {
+	// Metrics counts evaluations.
+	Metrics *metrics.Metrics
+	// Recorder records evaluations, e.g. to the audit log and the coverage tracker.
+	Recorder audit.Recorder
+	// Index is compiled features to evaluate. Features are read from the repository for each evaluation if it is nil.
+	Index *components.Index
+}
+
+// NewProjectHandler returns a handler of /projects/.
+func NewProjectHandler(l logger.Logger, repo repository.FeatureRepository, opts ProjectHandlerOptions) *ProjectHandler {
 	return &ProjectHandler{
-		l: l,
+		l:    l,
+		repo: repo,
+		m:    opts.Metrics,
+		rec:  opts.Recorder,
+		ix:   opts.Index,
 	}
 }
 
 func (h *ProjectHandler) Projects(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
-	h.pathParts = parts
-	h.l.Info(fmt.Sprintf("%s %s", r.Method, path))
+
+	if err := validatePathParameters(parts); err != nil {
+		rl := requestLogger(h.l, r)
+		rl.Error("Invalid path parameter", err, "path", path)
+		writeValidationException(w, rl, err)
+		return
+	}
 
 	switch len(parts) {
 	case 2:
@@ -37,18 +64,47 @@ func (h *ProjectHandler) Projects(w http.ResponseWriter, r *http.Request) {
 		// GET | POST /projects/:project/experiments
 		// GET | POST /projects/:project/launches
 		// GET | POST /projects/:project/features
-		h.handleSomeResources(w, r)
+		h.handleSomeResources(w, r, parts)
 	case 5:
 		// POST /projects/:project/evaluations/:feature
 		// GET | PATCH | DELETE /projects/:project/experiments/:experiment
 		// GET | PATCH | DELETE /projects/:project/launches/:launch
 		// GET | PATCH | DELETE /projects/:project/features/:feature
-		h.handleSpecificResource(w, r)
+		h.handleSpecificResource(w, r, parts)
 	default:
 		http.Error(w, "Not found", http.StatusNotFound)
 	}
 }
 
+// validatePathParameters validates resource names in the request path,
+// so that they are never used to access the data directory as they are.
+func validatePathParameters(parts []string) error {
+	if len(parts) < 3 || len(parts) > 5 {
+		return nil
+	}
+
+	if err := internal.ValidateProjectName(parts[2]); err != nil {
+		return err
+	}
+
+	if len(parts) != 5 {
+		return nil
+	}
+
+	switch parts[3] {
+	case "evaluations", "features":
+		return internal.ValidateFeatureName(parts[4])
+	case "launches":
+		return internal.ValidateLaunchName(parts[4])
+	case "experiments":
+		return internal.ValidateExperimentName(parts[4])
+	default:
+		// noop
+	}
+
+	return nil
+}
+
 func (h *ProjectHandler) handleProjects(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet, http.MethodPost:
@@ -67,18 +123,18 @@ func (h *ProjectHandler) handleSpecificProject(w http.ResponseWriter, r *http.Re
 	}
 }
 
-func (h *ProjectHandler) handleSomeResources(w http.ResponseWriter, r *http.Request) {
-	if len(h.pathParts) != 4 {
-		h.l.Error("Invalid path: "+r.URL.Path, nil)
+func (h *ProjectHandler) handleSomeResources(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 4 {
+		h.l.Error("Invalid path", nil, "path", r.URL.Path)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
-	switch h.pathParts[3] {
+	switch parts[3] {
 	case "evaluations":
 		// POST /projects/:project/evaluations/
 		// https://docs.aws.amazon.com/cloudwatchevidently/latest/APIReference/API_BatchEvaluateFeature.html
-		eh := newEvaluationHandler(h.l)
+		eh := newEvaluationHandler(h.l, h.repo, h.m, h.rec, h.ix)
 		eh.batchEvaluateFeature(w, r)
 	case "experiments", "launches", "features":
 		http.Error(w, "Not implemented", http.StatusNotImplemented)
@@ -87,18 +143,18 @@ func (h *ProjectHandler) handleSomeResources(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-func (h *ProjectHandler) handleSpecificResource(w http.ResponseWriter, r *http.Request) {
-	if len(h.pathParts) != 5 {
-		h.l.Error("Invalid path: "+r.URL.Path, nil)
+func (h *ProjectHandler) handleSpecificResource(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) != 5 {
+		h.l.Error("Invalid path", nil, "path", r.URL.Path)
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
-	switch h.pathParts[3] {
+	switch parts[3] {
 	case "evaluations":
 		// POST /projects/:project/evaluations/:feature
 		// https://docs.aws.amazon.com/cloudwatchevidently/latest/APIReference/API_EvaluateFeature.html
-		eh := newEvaluationHandler(h.l)
+		eh := newEvaluationHandler(h.l, h.repo, h.m, h.rec, h.ix)
 		eh.evaluateFeature(w, r)
 	case "experiments", "launches", "features":
 		http.Error(w, "Not implemented", http.StatusNotImplemented)
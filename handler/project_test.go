@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/michimani/evidentlylocal/handler"
@@ -15,7 +17,7 @@ import (
 func Test_Project(t *testing.T) {
 	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
 	handler.PrepareForTest(testLogger)
-	ph := handler.NewProjectHandler(testLogger)
+	ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{})
 
 	cases := []struct {
 		name           string
@@ -167,7 +169,7 @@ func Test_Project(t *testing.T) {
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"1 validation error detected: Value at 'requests' failed to satisfy constraint: Member must not be null","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null","name":"requests"}]}`,
 		},
 		{
 			name:           "DELETE /projects/:project/evaluations",
@@ -382,7 +384,7 @@ func Test_Project(t *testing.T) {
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"1 validation error detected: Value at 'entityId' failed to satisfy constraint: Member must not be null or empty","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null or empty","name":"entityId"}]}`,
 		},
 		{
 			name:           "DELETE /projects/:project/evaluations/:feature",
@@ -541,6 +543,35 @@ func Test_Project(t *testing.T) {
 			expectedStatus: http.StatusNotImplemented,
 			expectedBody:   "Not implemented\n",
 		},
+		// invalid resource names
+		{
+			name:           "GET /projects/:project (invalid project name)",
+			reqPath:        "/projects/..",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'project' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"project"}]}`,
+		},
+		{
+			name:           "POST /projects/:project/evaluations/:feature (invalid feature name)",
+			reqPath:        "/projects/test-project/evaluations/..",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'feature' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"feature"}]}`,
+		},
+		{
+			name:           "GET /projects/:project/launches/:launch (invalid launch name)",
+			reqPath:        "/projects/test-project/launches/test%3Blaunch",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'launch' failed to satisfy constraint: Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","reason":"fieldValidationFailed","fieldList":[{"message":"Member must satisfy regular expression pattern: ^[-a-zA-Z0-9._]*$","name":"launch"}]}`,
+		},
+		{
+			name:           "GET /projects/:project/experiments/:experiment (invalid experiment name)",
+			reqPath:        "/projects/test-project/experiments/" + strings.Repeat("e", 128),
+			method:         http.MethodGet,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"1 validation error detected: Value at 'experiment' failed to satisfy constraint: Member must have length between 1 and 127","reason":"fieldValidationFailed","fieldList":[{"message":"Member must have length between 1 and 127","name":"experiment"}]}`,
+		},
 		// /projects/:project/invalid-resource/:feature
 		{
 			name:           "GET /projects/:project/invalid-resource/:feature",
@@ -671,7 +702,7 @@ func Test_handleSomeResources(t *testing.T) {
 			reqPath:        "/projects/test-project/evaluations",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"1 validation error detected: Value at 'requests' failed to satisfy constraint: Member must not be null","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null","name":"requests"}]}`,
 		},
 		{
 			name:           "DELETE /projects/:project/evaluations",
@@ -917,7 +948,7 @@ func Test_handleSpecificResource(t *testing.T) {
 			reqPath:        "/projects/test-project/evaluations/test-feature-1",
 			method:         http.MethodPost,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad request\n",
+			expectedBody:   `{"message":"1 validation error detected: Value at 'entityId' failed to satisfy constraint: Member must not be null or empty","reason":"fieldValidationFailed","fieldList":[{"message":"Member must not be null or empty","name":"entityId"}]}`,
 		},
 		{
 			name:           "DELETE /projects/:project/evaluations/:feature",
@@ -1137,3 +1168,39 @@ func Test_handleSpecificResource(t *testing.T) {
 		})
 	}
 }
+
+// Test_Project_Concurrent checks that one handler serves concurrent requests to different paths.
+// Run with -race to detect shared state between requests.
+func Test_Project_Concurrent(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+	ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{})
+
+	cases := []struct {
+		path           string
+		reqBody        string
+		expectedStatus int
+	}{
+		{"/projects/test-project/evaluations/test-feature-1", `{"entityId": "user-1"}`, http.StatusOK},
+		{"/projects/test-project/evaluations/test-feature-2", `{"entityId": "user-1"}`, http.StatusOK},
+		{"/projects/test-project/evaluations", `{"requests": [{"entityId": "user-1", "feature": "test-feature-1"}]}`, http.StatusOK},
+		{"/projects/test-project/features", "", http.StatusNotImplemented},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, c := range cases {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(c.reqBody))
+				w := httptest.NewRecorder()
+				ph.Projects(w, req)
+
+				assert.Equal(t, c.expectedStatus, w.Code, c.path)
+			}()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/proxy"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// ProxyHandler handles /projects/ in the record or replay mode, instead of ProjectHandler.
+// In the record mode, requests are forwarded to the upstream and recorded to the cassette.
+// In the replay mode, responses are served from the cassette.
+type ProxyHandler struct {
+	l        logger.Logger
+	mode     string
+	cassette *proxy.Cassette
+	upstream *proxy.Upstream
+}
+
+// NewProxyHandler returns a handler of the mode. upstream is only used in the record mode.
+func NewProxyHandler(l logger.Logger, mode string, cassette *proxy.Cassette, upstream *proxy.Upstream) (*ProxyHandler, error) {
+	switch mode {
+	case proxy.ModeRecord:
+		if upstream == nil {
+			return nil, fmt.Errorf("upstream is required in the %s mode", mode)
+		}
+	case proxy.ModeReplay:
+		// noop
+	default:
+		return nil, fmt.Errorf("unsupported proxy mode: %s", mode)
+	}
+
+	return &ProxyHandler{
+		l:        l,
+		mode:     mode,
+		cassette: cassette,
+		upstream: upstream,
+	}, nil
+}
+
+func (h *ProxyHandler) Projects(w http.ResponseWriter, r *http.Request) {
+	rl := requestLogger(h.l, r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rl.Error("Failed to read request body", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	key := proxyKey(r, body)
+	l := rl.With("mode", h.mode, "key", key.String())
+
+	if h.mode == proxy.ModeReplay {
+		interaction, ok := h.cassette.Find(key)
+		if !ok {
+			l.Warn("No interaction in the cassette")
+			writeErrorResponse(w, l, http.StatusNotFound, types.ErrorTypeResourceNotFoundException, types.ErrorResponse{
+				Message: "No interaction in the cassette matches " + key.String(),
+			})
+			return
+		}
+
+		l.Debug("Replayed interaction")
+		writeProxyResponse(w, interaction.Response)
+		return
+	}
+
+	res, err := h.upstream.Forward(r.Context(), key.Operation, r, body)
+	if err != nil {
+		l.Error("Failed to forward request to upstream", err)
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	if err := h.cassette.Record(proxy.Interaction{
+		Key: key,
+		Request: proxy.Request{
+			Method: r.Method,
+			Path:   r.URL.RequestURI(),
+			Body:   string(body),
+		},
+		Response:   res,
+		RecordedAt: time.Now(),
+	}); err != nil {
+		// the response is served even if it cannot be recorded
+		l.Error("Failed to record interaction", err)
+	} else {
+		l.Debug("Recorded interaction", "status", res.Status)
+	}
+
+	writeProxyResponse(w, res)
+}
+
+func writeProxyResponse(w http.ResponseWriter, res proxy.Response) {
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(res.Status)
+	_, _ = w.Write([]byte(res.Body))
+}
+
+// proxyKey returns the key to match the request with interactions in the cassette.
+func proxyKey(r *http.Request, body []byte) proxy.Key {
+	key := proxy.Key{Operation: OperationName(r)}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) >= 3 {
+		key.Project = parts[2]
+	}
+	if len(parts) == 5 {
+		key.Feature = parts[4]
+	}
+
+	switch key.Operation {
+	case "EvaluateFeature":
+		req := types.EvaluateFeatureRequest{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err == nil {
+			key.EntityID = req.EntityID
+		}
+	case "BatchEvaluateFeature":
+		req := types.BatchEvaluateFeatureRequest{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err == nil {
+			features := make([]string, len(req.Requests))
+			entityIDs := make([]string, len(req.Requests))
+			for i, er := range req.Requests {
+				features[i] = er.Feature
+				entityIDs[i] = er.EntityID
+			}
+			key.Feature = proxy.JoinKeys(features)
+			key.EntityID = proxy.JoinKeys(entityIDs)
+		}
+	}
+
+	return key
+}
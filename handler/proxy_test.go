@@ -0,0 +1,118 @@
+package handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Proxy_RecordAndReplay(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	// another evidently-local stands in for the upstream
+	standIn := httptest.NewServer(http.HandlerFunc(handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{}).Projects))
+	defer standIn.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	type request struct {
+		path string
+		body string
+	}
+	requests := []request{
+		{path: "/projects/test-project/evaluations/test-feature-1", body: `{"entityId":"force-true"}`},
+		{path: "/projects/test-project/evaluations/test-feature-1", body: `{"entityId":"user-1"}`},
+		{path: "/projects/test-project/evaluations", body: `{"requests":[{"entityId":"user-1","feature":"test-feature-1"},{"entityId":"user-1","feature":"not-exists"}]}`},
+		{path: "/projects/test-project/evaluations/not-exists", body: `{"entityId":"user-1"}`},
+	}
+
+	serve := func(h http.HandlerFunc, req request) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, req.path, strings.NewReader(req.body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	// record
+	cassette, err := proxy.LoadCassette(path, false)
+	assert.NoError(t, err)
+	upstream, err := proxy.NewUpstream(standIn.URL, "us-east-1", nil)
+	assert.NoError(t, err)
+	recorder, err := handler.NewProxyHandler(testLogger, proxy.ModeRecord, cassette, upstream)
+	assert.NoError(t, err)
+
+	recorded := []*httptest.ResponseRecorder{}
+	for _, req := range requests {
+		recorded = append(recorded, serve(recorder.Projects, req))
+	}
+
+	// replay from the file
+	cassette, err = proxy.LoadCassette(path, true)
+	assert.NoError(t, err)
+	assert.Len(t, cassette.Interactions(), len(requests))
+	replayer, err := handler.NewProxyHandler(testLogger, proxy.ModeReplay, cassette, nil)
+	assert.NoError(t, err)
+
+	for i, req := range requests {
+		t.Run(req.body, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			w := serve(replayer.Projects, req)
+			asst.Equal(recorded[i].Code, w.Code)
+			asst.Equal(recorded[i].Body.String(), w.Body.String())
+			asst.Equal(recorded[i].Header().Get("Content-Type"), w.Header().Get("Content-Type"))
+		})
+	}
+
+	t.Run("not recorded", func(tt *testing.T) {
+		asst := assert.New(tt)
+
+		w := serve(replayer.Projects, request{path: "/projects/test-project/evaluations/test-feature-1", body: `{"entityId":"user-2"}`})
+		asst.Equal(http.StatusNotFound, w.Code)
+		asst.Equal("ResourceNotFoundException", w.Header().Get("x-amzn-ErrorType"))
+		asst.Contains(w.Body.String(), "entityId=user-2")
+	})
+}
+
+func Test_NewProxyHandler(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	upstream, _ := proxy.NewUpstream("http://localhost:2306", "us-east-1", nil)
+
+	cases := []struct {
+		name     string
+		mode     string
+		upstream *proxy.Upstream
+		wantErr  bool
+	}{
+		{name: "record", mode: proxy.ModeRecord, upstream: upstream},
+		{name: "replay", mode: proxy.ModeReplay},
+		{name: "record without upstream", mode: proxy.ModeRecord, wantErr: true},
+		{name: "unsupported mode", mode: "passthrough", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			h, err := handler.NewProxyHandler(testLogger, c.mode, &proxy.Cassette{}, c.upstream)
+			if c.wantErr {
+				asst.Nil(h)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(h)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// defaultListResults is the number of resources in a page of list operations if maxResults is not given.
+const defaultListResults = 50
+
+// SegmentHandler handles /segments/.
+type SegmentHandler struct {
+	l    logger.Logger
+	repo repository.FeatureRepository
+	refs *repository.ReferenceIndex
+	arns internal.ARNs
+}
+
+// NewSegmentHandler returns a handler of /segments/. arns builds ARNs of resources in responses.
+// refs is the index of references that is rebuilt when the repository is loaded. It can be nil,
+// and references are indexed from the repository for each request until it is built.
+func NewSegmentHandler(l logger.Logger, repo repository.FeatureRepository, refs *repository.ReferenceIndex, arns internal.ARNs) *SegmentHandler {
+	return &SegmentHandler{
+		l:    l,
+		repo: repo,
+		refs: refs,
+		arns: arns,
+	}
+}
+
+func (h *SegmentHandler) Segments(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+
+	switch {
+	case len(parts) == 4 && parts[3] == "references":
+		// GET /segments/:segment/references
+		// https://docs.aws.amazon.com/cloudwatchevidently/latest/APIReference/API_ListSegmentReferences.html
+		h.listSegmentReferences(w, r, parts[2])
+	case len(parts) == 2, len(parts) == 3:
+		// GET | POST /segments
+		// GET | DELETE /segments/:segment
+		http.Error(w, "Not implemented", http.StatusNotImplemented)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// references returns the index of references if the segment exists.
+// Otherwise, it writes the error response and returns false.
+func (h *SegmentHandler) references(w http.ResponseWriter, l logger.Logger, segment string) (*repository.ReferenceIndex, bool) {
+	refs := h.refs
+	if !refs.Built() {
+		bundle, err := h.repo.Snapshot()
+		if err != nil {
+			l.Error("Failed to get snapshot", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return nil, false
+		}
+
+		refs = repository.NewReferenceIndex()
+		refs.Build(bundle)
+	}
+
+	if !refs.HasSegment(segment) {
+		l.Error("Segment not found", nil)
+		writeErrorResponse(w, l, http.StatusNotFound, types.ErrorTypeResourceNotFoundException, types.ErrorResponse{
+			Message:      "Segment not found: " + segment,
+			ResourceID:   segment,
+			ResourceType: "segment",
+		})
+		return nil, false
+	}
+
+	return refs, true
+}
+
+func (h *SegmentHandler) listSegmentReferences(w http.ResponseWriter, r *http.Request, segment string) {
+	rl := requestLogger(h.l, r)
+
+	if r.Method != http.MethodGet {
+		rl.Error("Method not allowed", nil, "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	request := &types.ListSegmentReferencesRequest{
+		Segment:   segment,
+		Type:      types.SegmentReferenceResourceType(query.Get("type")),
+		NextToken: query.Get("nextToken"),
+	}
+
+	if v := query.Get("maxResults"); len(v) > 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			rl.Error("Invalid maxResults", err, "maxResults", v)
+			writeValidationException(w, rl, &internal.ValidationError{Message: "maxResults must be a number", Reason: types.ValidationExceptionReasonCannotParse})
+			return
+		}
+		request.MaxResults = n
+	}
+
+	if err := internal.ValidateListSegmentReferencesRequest(request); err != nil {
+		rl.Error("Invalid request parameter", err)
+		writeValidationException(w, rl, err)
+		return
+	}
+
+	l := rl.With("segment", segment)
+
+	x, ok := h.references(w, l, segment)
+	if !ok {
+		return
+	}
+
+	refs := x.SegmentReferences(segment, string(request.Type))
+
+	// the next token is the offset of the next page
+	offset := 0
+	if len(request.NextToken) > 0 {
+		var err error
+		offset, err = strconv.Atoi(request.NextToken)
+		if err != nil || offset < 0 || offset > len(refs) {
+			l.Error("Invalid nextToken", err, "nextToken", request.NextToken)
+			writeValidationException(w, l, &internal.ValidationError{Message: "Invalid nextToken", Reason: types.ValidationExceptionReasonOther})
+			return
+		}
+	}
+
+	limit := request.MaxResults
+	if limit == 0 {
+		limit = defaultListResults
+	}
+
+	end := min(offset+limit, len(refs))
+	res := types.ListSegmentReferencesResponse{ReferencedBy: []types.RefResource{}}
+	for _, ref := range refs[offset:end] {
+		res.ReferencedBy = append(res.ReferencedBy, types.RefResource{
+			Arn:    h.arns.ProjectResource(ref.Project, ref.Type, ref.Name),
+			Name:   ref.Name,
+			Status: ref.Status,
+			Type:   types.SegmentReferenceResourceType(ref.Type),
+		})
+	}
+	if end < len(refs) {
+		res.NextToken = strconv.Itoa(end)
+	}
+
+	bytes, requestID, err := internal.GenerateResponseBody(res)
+	if err != nil {
+		l.Error("Failed to generate response body", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setRequestIDHeader(w, requestID)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ListSegmentReferences(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	repo, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	sh := handler.NewSegmentHandler(testLogger, repo, nil, internal.ARNs{Region: "us-east-1", AccountID: "123456789012"})
+
+	cases := []struct {
+		name              string
+		method            string
+		path              string
+		expectedStatus    int
+		expectedErrorType types.ErrorType
+		expected          *types.ListSegmentReferencesResponse
+	}{
+		{
+			name:           "launches",
+			method:         http.MethodGet,
+			path:           "/segments/test-segment/references?type=LAUNCH",
+			expectedStatus: http.StatusOK,
+			expected: &types.ListSegmentReferencesResponse{
+				ReferencedBy: []types.RefResource{{
+					Arn:    "arn:aws:evidently:us-east-1:123456789012:project/test-project/launch/test-launch",
+					Name:   "test-launch",
+					Status: "CREATED",
+					Type:   types.SegmentReferenceResourceTypeLaunch,
+				}},
+			},
+		},
+		{
+			name:           "no experiments",
+			method:         http.MethodGet,
+			path:           "/segments/test-segment/references?type=EXPERIMENT&maxResults=10",
+			expectedStatus: http.StatusOK,
+			expected:       &types.ListSegmentReferencesResponse{ReferencedBy: []types.RefResource{}},
+		},
+		{
+			name:              "segment not found",
+			method:            http.MethodGet,
+			path:              "/segments/unknown/references?type=LAUNCH",
+			expectedStatus:    http.StatusNotFound,
+			expectedErrorType: types.ErrorTypeResourceNotFoundException,
+		},
+		{
+			name:              "without type",
+			method:            http.MethodGet,
+			path:              "/segments/test-segment/references",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorType: types.ErrorTypeValidationException,
+		},
+		{
+			name:              "invalid next token",
+			method:            http.MethodGet,
+			path:              "/segments/test-segment/references?type=LAUNCH&nextToken=x",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorType: types.ErrorTypeValidationException,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			path:           "/segments/test-segment/references?type=LAUNCH",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "delete segment is not implemented",
+			method:         http.MethodDelete,
+			path:           "/segments/test-segment",
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "get segment is not implemented",
+			method:         http.MethodGet,
+			path:           "/segments/test-segment",
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			w := httptest.NewRecorder()
+			sh.Segments(w, httptest.NewRequest(c.method, c.path, nil))
+
+			asst.Equal(c.expectedStatus, w.Code)
+			asst.Equal(string(c.expectedErrorType), w.Header().Get("x-amzn-ErrorType"))
+			if c.expected == nil {
+				return
+			}
+
+			got := &types.ListSegmentReferencesResponse{}
+			asst.NoError(json.Unmarshal(w.Body.Bytes(), got))
+			asst.Equal(c.expected, got)
+		})
+	}
+}
+
+func Test_ListSegmentReferences_SharedIndex(t *testing.T) {
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	repo, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+
+	// references are read from the shared index after it is built, instead of the repository
+	refs := repository.NewReferenceIndex()
+	refs.Build(&models.Bundle{
+		Experiments: []models.Experiment{{Project: "p", Name: "e", Status: "RUNNING", Segment: "indexed-segment"}},
+		Segments:    []models.Segment{{Name: "indexed-segment"}},
+	})
+	sh := handler.NewSegmentHandler(testLogger, repo, refs, internal.ARNs{})
+
+	w := httptest.NewRecorder()
+	sh.Segments(w, httptest.NewRequest(http.MethodGet, "/segments/indexed-segment/references?type=EXPERIMENT", nil))
+	asst.Equal(http.StatusOK, w.Code)
+	asst.JSONEq(`{"referencedBy":[{"arn":"arn:aws:evidently:::project/p/experiment/e","name":"e","status":"RUNNING","type":"EXPERIMENT"}]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	sh.Segments(w, httptest.NewRequest(http.MethodGet, "/segments/test-segment/references?type=LAUNCH", nil))
+	asst.Equal(http.StatusNotFound, w.Code)
+}
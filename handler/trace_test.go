@@ -0,0 +1,108 @@
+package handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_Trace(t *testing.T) {
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	handler.PrepareForTest(testLogger)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	cases := []struct {
+		name          string
+		path          string
+		reqBody       string
+		expectedSpans []string
+		expectedAttrs map[string]string
+	}{
+		{
+			name:    "EvaluateFeature",
+			path:    "/projects/test-project/evaluations/test-feature-1",
+			reqBody: `{"entityId":"force-true"}`,
+			expectedSpans: []string{
+				"repository get feature",
+				"check override rules",
+				"evaluate feature",
+				"encode response",
+				"EvaluateFeature",
+			},
+			expectedAttrs: map[string]string{
+				"evidently.project":   "test-project",
+				"evidently.feature":   "test-feature-1",
+				"evidently.variation": "True",
+				"evidently.reason":    "OVERRIDE_RULE",
+			},
+		},
+		{
+			name:    "BatchEvaluateFeature",
+			path:    "/projects/test-project/evaluations",
+			reqBody: `{"requests":[{"entityId":"test","feature":"test-feature-1"}]}`,
+			expectedSpans: []string{
+				"repository get feature",
+				"check override rules",
+				"select default variation",
+				"evaluate feature",
+				"evaluation request",
+				"encode response",
+				"BatchEvaluateFeature",
+			},
+			expectedAttrs: map[string]string{
+				"evidently.project": "test-project",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			recorder.Reset()
+
+			ph := handler.NewProjectHandler(testLogger, handler.RepositoryForTest(), handler.ProjectHandlerOptions{})
+			req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(c.reqBody))
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+			rec := httptest.NewRecorder()
+			handler.Trace(http.HandlerFunc(ph.Projects)).ServeHTTP(rec, req)
+			asst.Equal(http.StatusOK, rec.Code)
+
+			spans := recorder.Ended()
+			names := []string{}
+			for _, s := range spans {
+				names = append(names, s.Name())
+				// all spans are in the trace of the caller
+				asst.Equal("4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String())
+			}
+			asst.Equal(c.expectedSpans, names)
+
+			root := spans[len(spans)-1]
+			asst.Equal("00f067aa0ba902b7", root.Parent().SpanID().String())
+
+			attrs := map[string]string{}
+			for _, a := range root.Attributes() {
+				if a.Value.Type() == attribute.STRING {
+					attrs[string(a.Key)] = a.Value.AsString()
+				}
+			}
+			for k, v := range c.expectedAttrs {
+				asst.Equal(v, attrs[k], k)
+			}
+		})
+	}
+}
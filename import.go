@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/importer"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/michimani/evidentlylocal/repository"
+)
+
+const (
+	importFromAWSCLI         = "aws-cli"
+	importFromCloudFormation = "cloudformation"
+	importFromTerraform      = "terraform"
+)
+
+// runImport runs import subcommand, and returns the exit code.
+//
+//	evidently-local import [-config <config file>] [-data-dir ./data] [-from aws-cli] <directory of AWS CLI outputs>
+//	evidently-local import [-config <config file>] [-data-dir ./data] -from cloudformation <template file>
+//	evidently-local import [-config <config file>] [-data-dir ./data] -from terraform <output file of terraform show -json>
+func runImport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", os.Getenv(config.EnvConfigFile), "config file (YAML or JSON) to read quotas from")
+	importDataDir := fs.String("data-dir", config.DefaultDataDir, "data directory to write imported resources")
+	from := fs.String("from", importFromAWSCLI, "source format (aws-cli, cloudformation or terraform)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: evidently-local import [-config <file>] [-data-dir <dir>] [-from <format>] <source>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	limits := quota.Limits{}
+	if len(*configFile) > 0 {
+		cfg, err := config.LoadFile(*configFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid config: %v\n", err)
+			return 2
+		}
+		limits = cfg.Quotas
+	}
+
+	var res *importer.Result
+	var err error
+	switch *from {
+	case importFromAWSCLI:
+		res, err = importer.FromAWSCLIOutputs(fs.Arg(0))
+	case importFromCloudFormation:
+		res, err = importer.FromCloudFormationTemplate(fs.Arg(0))
+	case importFromTerraform:
+		res, err = importer.FromTerraformShowOutput(fs.Arg(0))
+	default:
+		fmt.Fprintf(stderr, "unsupported source format: %s\n", *from)
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to import: %v\n", err)
+		return 1
+	}
+
+	return writeImportResult(res, *importDataDir, limits, stdout, stderr)
+}
+
+// writeImportResult reports the import result, and writes imported resources into the data directory
+// if there is no conflict and no resource exceeds the limits.
+func writeImportResult(res *importer.Result, dir string, limits quota.Limits, stdout, stderr io.Writer) int {
+	for _, w := range res.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+
+	for _, d := range res.Duplicates {
+		fmt.Fprintf(stdout, "duplicate: %s\n", d)
+	}
+
+	if res.HasConflicts() {
+		for _, c := range res.Conflicts {
+			fmt.Fprintf(stderr, "conflict: %s\n", c)
+		}
+		fmt.Fprintln(stderr, "nothing is written because of conflicts")
+		return 1
+	}
+
+	// resources from AWS are within the limits of AWS, so violations mean that the outputs are edited by hand
+	// or the limits are lowered in the config file
+	if vs := quota.Check(res.Bundle, limits); len(vs) > 0 {
+		for _, v := range vs {
+			fmt.Fprintf(stderr, "quota exceeded: %s\n", v)
+		}
+		fmt.Fprintln(stderr, "nothing is written because of quota violations")
+		return 1
+	}
+
+	if err := repository.WriteBundleToDataDir(dir, res.Bundle); err != nil {
+		fmt.Fprintf(stderr, "failed to write resources: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "imported %d projects, %d features, %d launches, %d experiments and %d segments into %s\n",
+		len(res.Bundle.Projects), len(res.Bundle.Features), len(res.Bundle.Launches), len(res.Bundle.Experiments), len(res.Bundle.Segments), dir)
+
+	return 0
+}
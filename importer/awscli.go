@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/michimani/evidentlylocal/models"
+)
+
+type featureSummary struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+}
+
+// FromAWSCLIOutputs imports resources from a directory that has JSON outputs of the following AWS CLI commands.
+// Files are searched recursively, and files that are not outputs of these commands are reported as warnings.
+//
+//   - aws evidently get-project
+//   - aws evidently list-features
+//   - aws evidently get-feature
+//   - aws evidently get-launch
+//   - aws evidently get-experiment
+//   - aws evidently get-segment
+//
+// list-features does not output variations of features, so it is only used to report features
+// whose get-feature output is missing.
+func FromAWSCLIOutputs(dir string) (*Result, error) {
+	if len(dir) == 0 {
+		return nil, errors.New("dir is empty")
+	}
+
+	c := newCollector()
+	listed := newResourceSet[featureSummary]()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		output := map[string]json.RawMessage{}
+		if err := json.Unmarshal(b, &output); err != nil {
+			c.warn("%s is skipped because it is not a JSON object: %v", path, err)
+			return nil
+		}
+
+		if err := c.addAWSCLIOutput(path, output, listed); err != nil {
+			c.warn("%s is skipped because it cannot be parsed: %v", path, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range listed.list() {
+		key := f.Project + "/" + f.Name
+		if !c.features.has(key) {
+			c.warn("feature %s is listed in %s, but its get-feature output is not found", key, listed.sources[key])
+		}
+	}
+
+	return c.finish(), nil
+}
+
+// addAWSCLIOutput adds resources in the output of AWS CLI. The command is detected by the top-level key.
+func (c *collector) addAWSCLIOutput(path string, output map[string]json.RawMessage, listed *resourceSet[featureSummary]) error {
+	switch {
+	case output["variations"] != nil && output["valueType"] != nil:
+		// output of get-feature with --query 'feature'
+		b, err := json.Marshal(output)
+		if err != nil {
+			return err
+		}
+		return c.addFeatureOutput(path, b)
+	case output["project"] != nil:
+		p := models.Project{}
+		if err := json.Unmarshal(output["project"], &p); err != nil {
+			return err
+		}
+		c.addProject(p, path)
+	case output["feature"] != nil:
+		return c.addFeatureOutput(path, output["feature"])
+	case output["features"] != nil:
+		summaries := []featureSummary{}
+		if err := json.Unmarshal(output["features"], &summaries); err != nil {
+			return err
+		}
+		for _, s := range summaries {
+			s.Project = nameFromARN(s.Project)
+			key := s.Project + "/" + s.Name
+			if !listed.has(key) {
+				listed.keys = append(listed.keys, key)
+				listed.items[key] = s
+				listed.sources[key] = path
+			}
+		}
+	case output["launch"] != nil:
+		l := models.Launch{}
+		if err := json.Unmarshal(output["launch"], &l); err != nil {
+			return err
+		}
+		l.Project = nameFromARN(l.Project)
+		if l.ScheduledSplitsDefinition != nil {
+			for i, step := range l.ScheduledSplitsDefinition.Steps {
+				for j, o := range step.SegmentOverrides {
+					l.ScheduledSplitsDefinition.Steps[i].SegmentOverrides[j].Segment = nameFromARN(o.Segment)
+				}
+			}
+		}
+		c.addLaunch(l, path)
+	case output["experiment"] != nil:
+		e := models.Experiment{}
+		if err := json.Unmarshal(output["experiment"], &e); err != nil {
+			return err
+		}
+		e.Project = nameFromARN(e.Project)
+		e.Segment = nameFromARN(e.Segment)
+		c.addExperiment(e, path)
+	case output["segment"] != nil:
+		s := models.Segment{}
+		if err := json.Unmarshal(output["segment"], &s); err != nil {
+			return err
+		}
+		c.addSegment(s, path)
+	default:
+		return errors.New("unknown output format")
+	}
+
+	return nil
+}
+
+func (c *collector) addFeatureOutput(path string, raw json.RawMessage) error {
+	f := models.Feature{}
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	f.Project = nameFromARN(f.Project)
+	c.addFeature(f, path)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package importer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/importer"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromAWSCLIOutputs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name            string
+		dir             string
+		wantErr         bool
+		expectConflicts int
+		expectBundle    *models.Bundle
+		expectWarnings  []string
+	}{
+		{
+			name:    "dir is empty",
+			dir:     "",
+			wantErr: true,
+		},
+		{
+			name:    "dir not found",
+			dir:     "../testdata/import/not-exists",
+			wantErr: true,
+		},
+		{
+			name:            "conflict",
+			dir:             "../testdata/import/aws-cli-conflict",
+			wantErr:         false,
+			expectConflicts: 1,
+		},
+		{
+			name:            "success",
+			dir:             "../testdata/import/aws-cli",
+			wantErr:         false,
+			expectConflicts: 0,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{
+						Description: "project for test",
+						Name:        "test-project",
+						Status:      "AVAILABLE",
+						Tags:        map[string]string{"env": "test"},
+					},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "False",
+						EntityOverrides:  models.EntityOverride{"force-true": "True"},
+						Name:             "test-feature-1",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeBoolean,
+						Variations: []models.Variation{
+							{Name: "True", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+							{Name: "False", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+						},
+					},
+					{
+						DefaultVariation: "String1",
+						EntityOverrides:  models.EntityOverride{"force-2": "String2"},
+						Name:             "test-feature-2",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeString,
+						Variations: []models.Variation{
+							{Name: "String1", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "string-1"}},
+							{Name: "String2", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "string-2"}},
+						},
+					},
+				},
+				Launches: []models.Launch{
+					{
+						Groups: []models.LaunchGroup{
+							{FeatureVariations: map[string]string{"test-feature-1": "False"}, Name: "control"},
+							{FeatureVariations: map[string]string{"test-feature-1": "True"}, Name: "treatment"},
+						},
+						Name:              "test-launch",
+						Project:           "test-project",
+						RandomizationSalt: "test-launch",
+						ScheduledSplitsDefinition: &models.ScheduledSplitsDefinition{
+							Steps: []models.ScheduledSplit{
+								{
+									GroupWeights: map[string]int64{"control": 50000, "treatment": 50000},
+									SegmentOverrides: []models.SegmentOverride{
+										{EvaluationOrder: 1, Segment: "test-segment", Weights: map[string]int64{"control": 0, "treatment": 100000}},
+									},
+									StartTime: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+								},
+							},
+						},
+						Status: "RUNNING",
+						Type:   "AWS.Evidently.SPLITS",
+					},
+				},
+				Experiments: []models.Experiment{
+					{
+						Name: "test-experiment",
+						OnlineAbDefinition: &models.OnlineAbDefinition{
+							ControlTreatmentName: "control",
+							TreatmentWeights:     map[string]int64{"control": 50000, "treatment": 50000},
+						},
+						Project:           "test-project",
+						RandomizationSalt: "test-experiment",
+						SamplingRate:      100000,
+						Segment:           "test-segment",
+						Status:            "CREATED",
+						Treatments: []models.Treatment{
+							{FeatureVariations: map[string]string{"test-feature-2": "String1"}, Name: "control"},
+							{FeatureVariations: map[string]string{"test-feature-2": "String2"}, Name: "treatment"},
+						},
+						Type: "aws.evidently.onlineab",
+					},
+				},
+				Segments: []models.Segment{
+					{Name: "test-segment", Pattern: `{"Price":[{"numeric":[">",10]}]}`},
+				},
+			},
+			expectWarnings: []string{
+				"../testdata/import/aws-cli/unknown.json is skipped because it cannot be parsed: unknown output format",
+				"feature test-project/test-feature-3 is listed in ../testdata/import/aws-cli/list-features-2.json, but its get-feature output is not found",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := importer.FromAWSCLIOutputs(c.dir)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Len(got.Conflicts, c.expectConflicts, got.Conflicts)
+			if c.expectBundle == nil {
+				return
+			}
+
+			// time.Time values are compared as instants
+			for i, l := range got.Bundle.Launches {
+				for j, s := range l.ScheduledSplitsDefinition.Steps {
+					got.Bundle.Launches[i].ScheduledSplitsDefinition.Steps[j].StartTime = s.StartTime.UTC()
+				}
+			}
+
+			asst.Equal(c.expectBundle, got.Bundle)
+			asst.Equal(c.expectWarnings, got.Warnings)
+			asst.Len(got.Duplicates, 1, got.Duplicates)
+		})
+	}
+}
@@ -0,0 +1,653 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	cfnTypeProject    = "AWS::Evidently::Project"
+	cfnTypeFeature    = "AWS::Evidently::Feature"
+	cfnTypeLaunch     = "AWS::Evidently::Launch"
+	cfnTypeExperiment = "AWS::Evidently::Experiment"
+	cfnTypeSegment    = "AWS::Evidently::Segment"
+)
+
+var cfnSubVariablePattern = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+type cfnTemplate struct {
+	Parameters map[string]cfnParameter `json:"Parameters"`
+	Resources  map[string]cfnResource  `json:"Resources"`
+}
+
+type cfnParameter struct {
+	Default any `json:"Default"`
+}
+
+type cfnResource struct {
+	Type       string         `json:"Type"`
+	Properties map[string]any `json:"Properties"`
+}
+
+type cfnTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+type cfnProject struct {
+	Description string   `json:"Description"`
+	Name        string   `json:"Name"`
+	Tags        []cfnTag `json:"Tags"`
+}
+
+type cfnFeature struct {
+	DefaultVariation string `json:"DefaultVariation"`
+	Description      string `json:"Description"`
+	EntityOverrides  []struct {
+		EntityID  string `json:"EntityId"`
+		Variation string `json:"Variation"`
+	} `json:"EntityOverrides"`
+	Name       string   `json:"Name"`
+	Project    string   `json:"Project"`
+	Tags       []cfnTag `json:"Tags"`
+	Variations []struct {
+		BooleanValue  *bool    `json:"BooleanValue"`
+		DoubleValue   *float64 `json:"DoubleValue"`
+		LongValue     *int64   `json:"LongValue"`
+		StringValue   *string  `json:"StringValue"`
+		VariationName string   `json:"VariationName"`
+	} `json:"Variations"`
+}
+
+type cfnGroupWeight struct {
+	GroupName   string `json:"GroupName"`
+	SplitWeight int64  `json:"SplitWeight"`
+}
+
+type cfnLaunch struct {
+	Description     string `json:"Description"`
+	ExecutionStatus *struct {
+		Status string `json:"Status"`
+	} `json:"ExecutionStatus"`
+	Groups []struct {
+		Description string `json:"Description"`
+		Feature     string `json:"Feature"`
+		GroupName   string `json:"GroupName"`
+		Variation   string `json:"Variation"`
+	} `json:"Groups"`
+	Name                  string `json:"Name"`
+	Project               string `json:"Project"`
+	RandomizationSalt     string `json:"RandomizationSalt"`
+	ScheduledSplitsConfig []struct {
+		GroupWeights     []cfnGroupWeight `json:"GroupWeights"`
+		SegmentOverrides []struct {
+			EvaluationOrder int64            `json:"EvaluationOrder"`
+			Segment         string           `json:"Segment"`
+			Weights         []cfnGroupWeight `json:"Weights"`
+		} `json:"SegmentOverrides"`
+		StartTime time.Time `json:"StartTime"`
+	} `json:"ScheduledSplitsConfig"`
+	Tags []cfnTag `json:"Tags"`
+}
+
+type cfnExperiment struct {
+	Description    string `json:"Description"`
+	Name           string `json:"Name"`
+	OnlineAbConfig struct {
+		ControlTreatmentName string `json:"ControlTreatmentName"`
+		TreatmentWeights     []struct {
+			SplitWeight int64  `json:"SplitWeight"`
+			Treatment   string `json:"Treatment"`
+		} `json:"TreatmentWeights"`
+	} `json:"OnlineAbConfig"`
+	Project           string `json:"Project"`
+	RandomizationSalt string `json:"RandomizationSalt"`
+	RunningStatus     *struct {
+		Status string `json:"Status"`
+	} `json:"RunningStatus"`
+	SamplingRate int64    `json:"SamplingRate"`
+	Segment      string   `json:"Segment"`
+	Tags         []cfnTag `json:"Tags"`
+	Treatments   []struct {
+		Description   string `json:"Description"`
+		Feature       string `json:"Feature"`
+		TreatmentName string `json:"TreatmentName"`
+		Variation     string `json:"Variation"`
+	} `json:"Treatments"`
+}
+
+type cfnSegment struct {
+	Description string   `json:"Description"`
+	Name        string   `json:"Name"`
+	Pattern     any      `json:"Pattern"`
+	Tags        []cfnTag `json:"Tags"`
+}
+
+// FromCloudFormationTemplate imports AWS::Evidently::* resources from a CloudFormation template in YAML or JSON.
+//
+// Intrinsic functions are resolved only when their values are static.
+// Ref and Fn::GetAtt (Arn) of Evidently resources are resolved to the name of the resource,
+// and Ref of parameters are resolved to their default values.
+// Fn::Sub, Fn::Join and Fn::Select are resolved if all of their arguments are static.
+// Resources that have properties that cannot be resolved are reported as warnings.
+func FromCloudFormationTemplate(templateFile string) (*Result, error) {
+	if len(templateFile) == 0 {
+		return nil, errors.New("templateFile is empty")
+	}
+
+	b, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if filepath.Ext(templateFile) == ".json" {
+		err = json.Unmarshal(b, &doc)
+	} else {
+		doc, err = decodeCFNYAML(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", templateFile, err)
+	}
+
+	t := &cfnTemplate{}
+	if err := remarshal(doc, t); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", templateFile, err)
+	}
+
+	c := newCollector()
+
+	logicalIDs := make([]string, 0, len(t.Resources))
+	for id := range t.Resources {
+		logicalIDs = append(logicalIDs, id)
+	}
+	sort.Strings(logicalIDs)
+
+	for _, id := range logicalIDs {
+		res := t.Resources[id]
+		if !strings.HasPrefix(res.Type, "AWS::Evidently::") {
+			continue
+		}
+
+		source := templateFile + "#" + id
+		props, err := t.resolve(res.Properties, map[string]bool{})
+		if err != nil {
+			c.warn("%s is skipped: %v", source, err)
+			continue
+		}
+
+		if err := c.addCFNResource(res.Type, props, source); err != nil {
+			c.warn("%s is skipped: %v", source, err)
+		}
+	}
+
+	return c.finish(), nil
+}
+
+func (c *collector) addCFNResource(resourceType string, props any, source string) error {
+	switch resourceType {
+	case cfnTypeProject:
+		p := cfnProject{}
+		if err := remarshal(props, &p); err != nil {
+			return err
+		}
+		c.addProject(models.Project{
+			Description: p.Description,
+			Name:        p.Name,
+			Status:      "AVAILABLE",
+			Tags:        cfnTagsToMap(p.Tags),
+		}, source)
+	case cfnTypeFeature:
+		f := cfnFeature{}
+		if err := remarshal(props, &f); err != nil {
+			return err
+		}
+		feature := models.Feature{
+			DefaultVariation: f.DefaultVariation,
+			Description:      f.Description,
+			EntityOverrides:  models.EntityOverride{},
+			Name:             f.Name,
+			Project:          nameFromARN(f.Project),
+			Status:           "AVAILABLE",
+			Tags:             cfnTagsToMap(f.Tags),
+			Variations:       []models.Variation{},
+		}
+		for _, o := range f.EntityOverrides {
+			feature.EntityOverrides[o.EntityID] = o.Variation
+		}
+		for _, v := range f.Variations {
+			var valueType types.FeatureValueType
+			var value any
+			switch {
+			case v.BooleanValue != nil:
+				valueType, value = types.FeatureValueTypeBoolean, *v.BooleanValue
+			case v.StringValue != nil:
+				valueType, value = types.FeatureValueTypeString, *v.StringValue
+			case v.LongValue != nil:
+				valueType, value = types.FeatureValueTypeLong, *v.LongValue
+			case v.DoubleValue != nil:
+				valueType, value = types.FeatureValueTypeDouble, *v.DoubleValue
+			default:
+				return fmt.Errorf("variation %s has no value", v.VariationName)
+			}
+
+			if err := setValueType(&feature, v.VariationName, valueType); err != nil {
+				return err
+			}
+			feature.Variations = append(feature.Variations, models.Variation{
+				Name:  v.VariationName,
+				Value: map[types.VariableValueType]any{feature.VariableValueType(): value},
+			})
+		}
+		setDefaultVariation(&feature)
+		c.addFeature(feature, source)
+	case cfnTypeLaunch:
+		l := cfnLaunch{}
+		if err := remarshal(props, &l); err != nil {
+			return err
+		}
+		launch := models.Launch{
+			Description:       l.Description,
+			Groups:            []models.LaunchGroup{},
+			Name:              l.Name,
+			Project:           nameFromARN(l.Project),
+			RandomizationSalt: l.RandomizationSalt,
+			ScheduledSplitsDefinition: &models.ScheduledSplitsDefinition{
+				Steps: []models.ScheduledSplit{},
+			},
+			Status: "CREATED",
+			Tags:   cfnTagsToMap(l.Tags),
+			Type:   "AWS.Evidently.SPLITS",
+		}
+		if l.ExecutionStatus != nil && l.ExecutionStatus.Status == "START" {
+			launch.Status = "RUNNING"
+		}
+		for _, g := range l.Groups {
+			launch.Groups = append(launch.Groups, models.LaunchGroup{
+				Description:       g.Description,
+				FeatureVariations: map[string]string{g.Feature: g.Variation},
+				Name:              g.GroupName,
+			})
+		}
+		for _, s := range l.ScheduledSplitsConfig {
+			step := models.ScheduledSplit{
+				GroupWeights: cfnGroupWeightsToMap(s.GroupWeights),
+				StartTime:    s.StartTime,
+			}
+			for _, o := range s.SegmentOverrides {
+				step.SegmentOverrides = append(step.SegmentOverrides, models.SegmentOverride{
+					EvaluationOrder: o.EvaluationOrder,
+					Segment:         nameFromARN(o.Segment),
+					Weights:         cfnGroupWeightsToMap(o.Weights),
+				})
+			}
+			launch.ScheduledSplitsDefinition.Steps = append(launch.ScheduledSplitsDefinition.Steps, step)
+		}
+		c.addLaunch(launch, source)
+	case cfnTypeExperiment:
+		e := cfnExperiment{}
+		if err := remarshal(props, &e); err != nil {
+			return err
+		}
+		experiment := models.Experiment{
+			Description: e.Description,
+			Name:        e.Name,
+			OnlineAbDefinition: &models.OnlineAbDefinition{
+				ControlTreatmentName: e.OnlineAbConfig.ControlTreatmentName,
+				TreatmentWeights:     map[string]int64{},
+			},
+			Project:           nameFromARN(e.Project),
+			RandomizationSalt: e.RandomizationSalt,
+			SamplingRate:      e.SamplingRate,
+			Segment:           nameFromARN(e.Segment),
+			Status:            "CREATED",
+			Tags:              cfnTagsToMap(e.Tags),
+			Treatments:        []models.Treatment{},
+			Type:              "aws.evidently.onlineab",
+		}
+		if e.RunningStatus != nil && e.RunningStatus.Status == "START" {
+			experiment.Status = "RUNNING"
+		}
+		for _, w := range e.OnlineAbConfig.TreatmentWeights {
+			experiment.OnlineAbDefinition.TreatmentWeights[w.Treatment] = w.SplitWeight
+		}
+		for _, t := range e.Treatments {
+			experiment.Treatments = append(experiment.Treatments, models.Treatment{
+				Description:       t.Description,
+				FeatureVariations: map[string]string{t.Feature: t.Variation},
+				Name:              t.TreatmentName,
+			})
+		}
+		c.addExperiment(experiment, source)
+	case cfnTypeSegment:
+		s := cfnSegment{}
+		if err := remarshal(props, &s); err != nil {
+			return err
+		}
+		pattern, ok := s.Pattern.(string)
+		if !ok && s.Pattern != nil {
+			b, err := json.Marshal(s.Pattern)
+			if err != nil {
+				return err
+			}
+			pattern = string(b)
+		}
+		c.addSegment(models.Segment{
+			Description: s.Description,
+			Name:        s.Name,
+			Pattern:     pattern,
+			Tags:        cfnTagsToMap(s.Tags),
+		}, source)
+	default:
+		return fmt.Errorf("unsupported resource type %s", resourceType)
+	}
+
+	return nil
+}
+
+// resolve resolves intrinsic functions in v. visiting is used to detect circular references.
+func (t *cfnTemplate) resolve(v any, visiting map[string]bool) (any, error) {
+	switch tv := v.(type) {
+	case []any:
+		res := make([]any, len(tv))
+		for i, item := range tv {
+			r, err := t.resolve(item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = r
+		}
+		return res, nil
+	case map[string]any:
+		if len(tv) == 1 {
+			for fn, arg := range tv {
+				if fn == "Ref" || strings.HasPrefix(fn, "Fn::") || fn == "Condition" {
+					return t.resolveFunction(fn, arg, visiting)
+				}
+			}
+		}
+
+		res := make(map[string]any, len(tv))
+		for k, item := range tv {
+			r, err := t.resolve(item, visiting)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = r
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+func (t *cfnTemplate) resolveFunction(fn string, arg any, visiting map[string]bool) (any, error) {
+	switch fn {
+	case "Ref":
+		name, ok := arg.(string)
+		if !ok {
+			return nil, errors.New("Ref must have a string argument")
+		}
+		return t.resolveRef(name, visiting)
+	case "Fn::GetAtt":
+		var parts []any
+		switch a := arg.(type) {
+		case string:
+			for _, p := range strings.SplitN(a, ".", 2) {
+				parts = append(parts, p)
+			}
+		case []any:
+			parts = a
+		}
+		if len(parts) != 2 {
+			return nil, errors.New("Fn::GetAtt must have a logical ID and an attribute name")
+		}
+		id, _ := parts[0].(string)
+		attr, _ := parts[1].(string)
+		return t.resolveGetAtt(id, attr, visiting)
+	case "Fn::Sub":
+		return t.resolveSub(arg, visiting)
+	case "Fn::Join":
+		a, err := t.resolve(arg, visiting)
+		if err != nil {
+			return nil, err
+		}
+		args, ok := a.([]any)
+		if !ok || len(args) != 2 {
+			return nil, errors.New("Fn::Join must have a delimiter and a list of values")
+		}
+		delimiter, ok := args[0].(string)
+		values, ok2 := args[1].([]any)
+		if !ok || !ok2 {
+			return nil, errors.New("Fn::Join must have a delimiter and a list of values")
+		}
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = fmt.Sprint(v)
+		}
+		return strings.Join(strs, delimiter), nil
+	case "Fn::Select":
+		a, err := t.resolve(arg, visiting)
+		if err != nil {
+			return nil, err
+		}
+		args, ok := a.([]any)
+		if !ok || len(args) != 2 {
+			return nil, errors.New("Fn::Select must have an index and a list of values")
+		}
+		values, ok := args[1].([]any)
+		var index int
+		if _, err := fmt.Sscan(fmt.Sprint(args[0]), &index); err != nil || !ok || index < 0 || index >= len(values) {
+			return nil, errors.New("Fn::Select has an invalid index or list")
+		}
+		return values[index], nil
+	default:
+		return nil, fmt.Errorf("%s cannot be resolved statically", fn)
+	}
+}
+
+func (t *cfnTemplate) resolveRef(name string, visiting map[string]bool) (any, error) {
+	if p, ok := t.Parameters[name]; ok {
+		if p.Default == nil {
+			return nil, fmt.Errorf("parameter %s has no default value", name)
+		}
+		return t.resolve(p.Default, visiting)
+	}
+
+	if _, ok := t.Resources[name]; ok {
+		return t.resolveResourceName(name, visiting)
+	}
+
+	return nil, fmt.Errorf("Ref %s cannot be resolved statically", name)
+}
+
+func (t *cfnTemplate) resolveGetAtt(id, attr string, visiting map[string]bool) (any, error) {
+	if attr != "Arn" {
+		return nil, fmt.Errorf("Fn::GetAtt %s.%s cannot be resolved statically", id, attr)
+	}
+
+	return t.resolveResourceName(id, visiting)
+}
+
+// resolveResourceName resolves Ref and the ARN of an Evidently resource to its name,
+// because resources are referred by their names in the data directory.
+func (t *cfnTemplate) resolveResourceName(id string, visiting map[string]bool) (any, error) {
+	res, ok := t.Resources[id]
+	if !ok || !strings.HasPrefix(res.Type, "AWS::Evidently::") {
+		return nil, fmt.Errorf("reference to %s cannot be resolved statically", id)
+	}
+
+	if visiting[id] {
+		return nil, fmt.Errorf("circular reference to %s", id)
+	}
+	visiting[id] = true
+	defer delete(visiting, id)
+
+	return t.resolve(res.Properties["Name"], visiting)
+}
+
+func (t *cfnTemplate) resolveSub(arg any, visiting map[string]bool) (any, error) {
+	var format string
+	vars := map[string]any{}
+
+	switch a := arg.(type) {
+	case string:
+		format = a
+	case []any:
+		if len(a) != 2 {
+			return nil, errors.New("Fn::Sub must have a string and a map of variables")
+		}
+		format, _ = a[0].(string)
+		m, _ := a[1].(map[string]any)
+		for k, v := range m {
+			r, err := t.resolve(v, visiting)
+			if err != nil {
+				return nil, err
+			}
+			vars[k] = r
+		}
+	default:
+		return nil, errors.New("Fn::Sub must have a string argument")
+	}
+
+	var resolveErr error
+	res := cfnSubVariablePattern.ReplaceAllStringFunc(format, func(m string) string {
+		name := m[2 : len(m)-1]
+		var v any
+		var err error
+		if vv, ok := vars[name]; ok {
+			v = vv
+		} else if id, attr, found := strings.Cut(name, "."); found {
+			v, err = t.resolveGetAtt(id, attr, visiting)
+		} else {
+			v, err = t.resolveRef(name, visiting)
+		}
+		if err != nil {
+			resolveErr = err
+			return m
+		}
+		return fmt.Sprint(v)
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	// ${!Literal} is written as ${Literal}
+	return strings.ReplaceAll(res, "${!", "${"), nil
+}
+
+// decodeCFNYAML decodes a CloudFormation template in YAML.
+// Short form intrinsic functions (e.g. !Ref) are converted to the full form (e.g. {"Ref": ...}).
+func decodeCFNYAML(b []byte) (any, error) {
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+
+	return cfnNodeToValue(&node)
+}
+
+func cfnNodeToValue(node *yaml.Node) (any, error) {
+	var v any
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return cfnNodeToValue(node.Content[0])
+	case yaml.AliasNode:
+		return cfnNodeToValue(node.Alias)
+	case yaml.MappingNode:
+		m := map[string]any{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := cfnNodeToValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[node.Content[i].Value] = value
+		}
+		v = m
+	case yaml.SequenceNode:
+		l := []any{}
+		for _, n := range node.Content {
+			value, err := cfnNodeToValue(n)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, value)
+		}
+		v = l
+	default:
+		if isCFNShortFormTag(node.Tag) {
+			v = node.Value
+		} else if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+
+	if !isCFNShortFormTag(node.Tag) {
+		return v, nil
+	}
+
+	fn := strings.TrimPrefix(node.Tag, "!")
+	switch fn {
+	case "Ref", "Condition":
+		return map[string]any{fn: v}, nil
+	case "GetAtt":
+		if s, ok := v.(string); ok {
+			id, attr, _ := strings.Cut(s, ".")
+			v = []any{id, attr}
+		}
+		return map[string]any{"Fn::GetAtt": v}, nil
+	default:
+		return map[string]any{"Fn::" + fn: v}, nil
+	}
+}
+
+// isCFNShortFormTag reports whether the tag is a short form intrinsic function (e.g. !Ref).
+// Standard YAML tags start with "!!" (e.g. !!str).
+func isCFNShortFormTag(tag string) bool {
+	return strings.HasPrefix(tag, "!") && !strings.HasPrefix(tag, "!!")
+}
+
+func cfnTagsToMap(tags []cfnTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+func cfnGroupWeightsToMap(weights []cfnGroupWeight) map[string]int64 {
+	m := make(map[string]int64, len(weights))
+	for _, w := range weights {
+		m[w.GroupName] = w.SplitWeight
+	}
+	return m
+}
+
+// remarshal converts v into out via JSON.
+func remarshal(v any, out any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,204 @@
+package importer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/importer"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromCloudFormationTemplate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		templateFile   string
+		wantErr        bool
+		expectBundle   *models.Bundle
+		expectWarnings []string
+	}{
+		{
+			name:         "templateFile is empty",
+			templateFile: "",
+			wantErr:      true,
+		},
+		{
+			name:         "templateFile not found",
+			templateFile: "../testdata/import/cloudformation/not-exists.yaml",
+			wantErr:      true,
+		},
+		{
+			name:         "not a template",
+			templateFile: "../testdata/bundles/unparsable-bundle.yaml",
+			wantErr:      true,
+		},
+		{
+			name:         "success: YAML with short form intrinsic functions",
+			templateFile: "../testdata/import/cloudformation/template.yaml",
+			wantErr:      false,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{
+						Description: "project for test",
+						Name:        "test-project",
+						Status:      "AVAILABLE",
+						Tags:        map[string]string{"env": "test"},
+					},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "False",
+						Description:      "feature for test",
+						EntityOverrides:  models.EntityOverride{"force-true": "True"},
+						Name:             "bool-feature",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						Tags:             map[string]string{"owner": "test-team"},
+						ValueType:        types.FeatureValueTypeBoolean,
+						Variations: []models.Variation{
+							{Name: "True", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+							{Name: "False", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+						},
+					},
+					{
+						DefaultVariation: "Long1",
+						EntityOverrides:  models.EntityOverride{},
+						Name:             "long-feature",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeLong,
+						Variations: []models.Variation{
+							{Name: "Long1", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(1)}},
+							{Name: "Long2", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(2)}},
+						},
+					},
+				},
+				Launches: []models.Launch{
+					{
+						Groups: []models.LaunchGroup{
+							{FeatureVariations: map[string]string{"bool-feature": "False"}, Name: "control"},
+							{FeatureVariations: map[string]string{"bool-feature": "True"}, Name: "treatment"},
+						},
+						Name:    "test-launch",
+						Project: "test-project",
+						ScheduledSplitsDefinition: &models.ScheduledSplitsDefinition{
+							Steps: []models.ScheduledSplit{
+								{
+									GroupWeights: map[string]int64{"control": 50000, "treatment": 50000},
+									SegmentOverrides: []models.SegmentOverride{
+										{EvaluationOrder: 1, Segment: "test-segment", Weights: map[string]int64{"treatment": 100000}},
+									},
+									StartTime: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+								},
+							},
+						},
+						Status: "RUNNING",
+						Type:   "AWS.Evidently.SPLITS",
+					},
+				},
+				Experiments: []models.Experiment{
+					{
+						Name: "test-experiment",
+						OnlineAbDefinition: &models.OnlineAbDefinition{
+							ControlTreatmentName: "control",
+							TreatmentWeights:     map[string]int64{"control": 50000, "treatment": 50000},
+						},
+						Project:      "test-project",
+						SamplingRate: 100000,
+						Status:       "CREATED",
+						Treatments: []models.Treatment{
+							{FeatureVariations: map[string]string{"long-feature": "Long1"}, Name: "control"},
+							{FeatureVariations: map[string]string{"long-feature": "Long2"}, Name: "treatment"},
+						},
+						Type: "aws.evidently.onlineab",
+					},
+				},
+				Segments: []models.Segment{
+					{Name: "test-segment", Pattern: `{"Price":[{"numeric":[">",10]}]}`},
+				},
+			},
+			expectWarnings: []string{
+				"../testdata/import/cloudformation/template.yaml#NoDefaultFeature is skipped: parameter NoDefault has no default value",
+				"../testdata/import/cloudformation/template.yaml#RegionalFeature is skipped: Ref AWS::Region cannot be resolved statically",
+			},
+		},
+		{
+			name:         "success: JSON",
+			templateFile: "../testdata/import/cloudformation/template.json",
+			wantErr:      false,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{Name: "test-project", Status: "AVAILABLE"},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "String1",
+						EntityOverrides:  models.EntityOverride{},
+						Name:             "string-feature",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeString,
+						Variations: []models.Variation{
+							{Name: "String1", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "string-1"}},
+						},
+					},
+				},
+				Launches:    []models.Launch{},
+				Experiments: []models.Experiment{},
+				Segments:    []models.Segment{},
+			},
+			expectWarnings: []string{},
+		},
+		{
+			name:         "success: default variation is omitted",
+			templateFile: "../testdata/import/cloudformation/no-default-variation.json",
+			wantErr:      false,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{Name: "test-project"},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "Long1",
+						EntityOverrides:  models.EntityOverride{},
+						Name:             "long-feature",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeLong,
+						Variations: []models.Variation{
+							{Name: "Long1", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(1)}},
+							{Name: "Long2", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(2)}},
+						},
+					},
+				},
+				Launches:    []models.Launch{},
+				Experiments: []models.Experiment{},
+				Segments:    []models.Segment{},
+			},
+			expectWarnings: []string{
+				"../testdata/import/cloudformation/no-default-variation.json#MixedFeature is skipped: variation Long1 has a LONG value, but the feature has STRING values",
+				"project test-project is not defined, so it is imported with its name only",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := importer.FromCloudFormationTemplate(c.templateFile)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Empty(got.Conflicts)
+			asst.Equal(c.expectBundle, got.Bundle)
+			asst.Equal(c.expectWarnings, got.Warnings)
+		})
+	}
+}
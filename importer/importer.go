@@ -0,0 +1,187 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// Result is the result of importing resources.
+type Result struct {
+	Bundle *models.Bundle
+
+	// Duplicates are resources that are found more than once with the same definition.
+	// Only one of them is imported.
+	Duplicates []string
+
+	// Conflicts are resources that are found more than once with different definitions.
+	Conflicts []string
+
+	// Warnings are problems that do not prevent importing, e.g. unrecognized files.
+	Warnings []string
+}
+
+func (r *Result) HasConflicts() bool {
+	return len(r.Conflicts) > 0
+}
+
+// resourceSet is a set of resources of the same kind that keeps the order they are added.
+type resourceSet[T any] struct {
+	keys    []string
+	items   map[string]T
+	encoded map[string][]byte
+	sources map[string]string
+}
+
+func newResourceSet[T any]() *resourceSet[T] {
+	return &resourceSet[T]{
+		keys:    []string{},
+		items:   map[string]T{},
+		encoded: map[string][]byte{},
+		sources: map[string]string{},
+	}
+}
+
+func (s *resourceSet[T]) has(key string) bool {
+	_, ok := s.items[key]
+	return ok
+}
+
+func (s *resourceSet[T]) list() []T {
+	res := make([]T, 0, len(s.keys))
+	for _, k := range s.keys {
+		res = append(res, s.items[k])
+	}
+	return res
+}
+
+// collector collects resources from multiple sources, and detects duplicates and conflicts.
+type collector struct {
+	result      *Result
+	projects    *resourceSet[models.Project]
+	features    *resourceSet[models.Feature]
+	launches    *resourceSet[models.Launch]
+	experiments *resourceSet[models.Experiment]
+	segments    *resourceSet[models.Segment]
+}
+
+func newCollector() *collector {
+	return &collector{
+		result:      &Result{Duplicates: []string{}, Conflicts: []string{}, Warnings: []string{}},
+		projects:    newResourceSet[models.Project](),
+		features:    newResourceSet[models.Feature](),
+		launches:    newResourceSet[models.Launch](),
+		experiments: newResourceSet[models.Experiment](),
+		segments:    newResourceSet[models.Segment](),
+	}
+}
+
+func (c *collector) warn(format string, a ...any) {
+	c.result.Warnings = append(c.result.Warnings, fmt.Sprintf(format, a...))
+}
+
+func (c *collector) addProject(p models.Project, source string) {
+	add(c, c.projects, "project "+p.Name, p.Name, p, source)
+}
+
+func (c *collector) addFeature(f models.Feature, source string) {
+	add(c, c.features, "feature "+f.Project+"/"+f.Name, f.Project+"/"+f.Name, f, source)
+}
+
+func (c *collector) addLaunch(l models.Launch, source string) {
+	add(c, c.launches, "launch "+l.Project+"/"+l.Name, l.Project+"/"+l.Name, l, source)
+}
+
+func (c *collector) addExperiment(e models.Experiment, source string) {
+	add(c, c.experiments, "experiment "+e.Project+"/"+e.Name, e.Project+"/"+e.Name, e, source)
+}
+
+func (c *collector) addSegment(s models.Segment, source string) {
+	add(c, c.segments, "segment "+s.Name, s.Name, s, source)
+}
+
+func add[T any](c *collector, s *resourceSet[T], label, key string, item T, source string) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		c.warn("%s in %s cannot be encoded: %v", label, source, err)
+		return
+	}
+
+	if !s.has(key) {
+		s.keys = append(s.keys, key)
+		s.items[key] = item
+		s.encoded[key] = encoded
+		s.sources[key] = source
+		return
+	}
+
+	if string(s.encoded[key]) == string(encoded) {
+		c.result.Duplicates = append(c.result.Duplicates, fmt.Sprintf("%s in %s is the same as in %s", label, source, s.sources[key]))
+		return
+	}
+
+	c.result.Conflicts = append(c.result.Conflicts, fmt.Sprintf("%s in %s conflicts with %s", label, source, s.sources[key]))
+}
+
+// finish returns the result that has all collected resources.
+// Projects that are referred by other resources but not defined are added with their name only.
+func (c *collector) finish() *Result {
+	referred := []string{}
+	for _, f := range c.features.list() {
+		referred = append(referred, f.Project)
+	}
+	for _, l := range c.launches.list() {
+		referred = append(referred, l.Project)
+	}
+	for _, e := range c.experiments.list() {
+		referred = append(referred, e.Project)
+	}
+
+	for _, p := range referred {
+		if !c.projects.has(p) {
+			c.warn("project %s is not defined, so it is imported with its name only", p)
+			c.addProject(models.Project{Name: p}, "(generated)")
+		}
+	}
+
+	c.result.Bundle = &models.Bundle{
+		Projects:    c.projects.list(),
+		Features:    c.features.list(),
+		Launches:    c.launches.list(),
+		Experiments: c.experiments.list(),
+		Segments:    c.segments.list(),
+	}
+
+	return c.result
+}
+
+// setValueType sets the value type of the feature from the value of a variation.
+// All variations of a feature must have values of the same type.
+func setValueType(f *models.Feature, variation string, valueType types.FeatureValueType) error {
+	if len(f.ValueType) > 0 && f.ValueType != valueType {
+		return fmt.Errorf("variation %s has a %s value, but the feature has %s values", variation, valueType, f.ValueType)
+	}
+
+	f.ValueType = valueType
+	return nil
+}
+
+// setDefaultVariation sets the first variation as the default variation if it is omitted, as AWS does.
+func setDefaultVariation(f *models.Feature) {
+	if len(f.DefaultVariation) == 0 && len(f.Variations) > 0 {
+		f.DefaultVariation = f.Variations[0].Name
+	}
+}
+
+// nameFromARN returns the resource name from the ARN (e.g. arn:aws:evidently:ap-northeast-1:123456789012:project/test-project).
+// If the value is not an ARN, it is returned as it is.
+func nameFromARN(v string) string {
+	if !strings.HasPrefix(v, "arn:") {
+		return v
+	}
+
+	return v[strings.LastIndex(v, "/")+1:]
+}
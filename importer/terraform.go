@@ -0,0 +1,290 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+const (
+	tfTypeProject = "aws_evidently_project"
+	tfTypeFeature = "aws_evidently_feature"
+	tfTypeLaunch  = "aws_evidently_launch"
+	tfTypeSegment = "aws_evidently_segment"
+)
+
+type tfShowOutput struct {
+	Values        *tfValues `json:"values"`
+	PlannedValues *tfValues `json:"planned_values"`
+}
+
+type tfValues struct {
+	RootModule tfModule `json:"root_module"`
+}
+
+type tfModule struct {
+	Resources    []tfResource `json:"resources"`
+	ChildModules []tfModule   `json:"child_modules"`
+}
+
+type tfResource struct {
+	Address string          `json:"address"`
+	Mode    string          `json:"mode"`
+	Type    string          `json:"type"`
+	Values  json.RawMessage `json:"values"`
+}
+
+type tfProject struct {
+	Description string            `json:"description"`
+	Name        string            `json:"name"`
+	Status      string            `json:"status"`
+	Tags        map[string]string `json:"tags"`
+}
+
+type tfFeature struct {
+	DefaultVariation string            `json:"default_variation"`
+	Description      string            `json:"description"`
+	EntityOverrides  map[string]string `json:"entity_overrides"`
+	Name             string            `json:"name"`
+	Project          string            `json:"project"`
+	Status           string            `json:"status"`
+	Tags             map[string]string `json:"tags"`
+	ValueType        string            `json:"value_type"`
+	Variations       []struct {
+		Name  string `json:"name"`
+		Value []struct {
+			BoolValue   string `json:"bool_value"`
+			DoubleValue string `json:"double_value"`
+			LongValue   string `json:"long_value"`
+			StringValue string `json:"string_value"`
+		} `json:"value"`
+	} `json:"variations"`
+}
+
+type tfLaunch struct {
+	Description string `json:"description"`
+	Groups      []struct {
+		Description string `json:"description"`
+		Feature     string `json:"feature"`
+		Name        string `json:"name"`
+		Variation   string `json:"variation"`
+	} `json:"groups"`
+	Name                  string `json:"name"`
+	Project               string `json:"project"`
+	RandomizationSalt     string `json:"randomization_salt"`
+	ScheduledSplitsConfig []struct {
+		Steps []struct {
+			GroupWeights     map[string]int64 `json:"group_weights"`
+			SegmentOverrides []struct {
+				EvaluationOrder int64            `json:"evaluation_order"`
+				Segment         string           `json:"segment"`
+				Weights         map[string]int64 `json:"weights"`
+			} `json:"segment_overrides"`
+			StartTime time.Time `json:"start_time"`
+		} `json:"steps"`
+	} `json:"scheduled_splits_config"`
+	Status string            `json:"status"`
+	Tags   map[string]string `json:"tags"`
+	Type   string            `json:"type"`
+}
+
+type tfSegment struct {
+	Description string            `json:"description"`
+	Name        string            `json:"name"`
+	Pattern     string            `json:"pattern"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// FromTerraformShowOutput imports aws_evidently_* resources from the output of `terraform show -json`.
+// Both of a state (values) and a plan (planned_values) are supported, and resources in child modules are also imported.
+func FromTerraformShowOutput(showFile string) (*Result, error) {
+	if len(showFile) == 0 {
+		return nil, errors.New("showFile is empty")
+	}
+
+	b, err := os.ReadFile(showFile)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &tfShowOutput{}
+	if err := json.Unmarshal(b, output); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", showFile, err)
+	}
+
+	values := output.Values
+	if values == nil {
+		values = output.PlannedValues
+	}
+	if values == nil {
+		return nil, fmt.Errorf("%s has neither values nor planned_values", showFile)
+	}
+
+	c := newCollector()
+	c.addTFModule(values.RootModule)
+
+	return c.finish(), nil
+}
+
+func (c *collector) addTFModule(m tfModule) {
+	for _, r := range m.Resources {
+		if r.Mode != "managed" || !strings.HasPrefix(r.Type, "aws_evidently_") {
+			continue
+		}
+
+		if err := c.addTFResource(r); err != nil {
+			c.warn("%s is skipped: %v", r.Address, err)
+		}
+	}
+
+	for _, child := range m.ChildModules {
+		c.addTFModule(child)
+	}
+}
+
+func (c *collector) addTFResource(r tfResource) error {
+	switch r.Type {
+	case tfTypeProject:
+		p := tfProject{}
+		if err := json.Unmarshal(r.Values, &p); err != nil {
+			return err
+		}
+		c.addProject(models.Project{
+			Description: p.Description,
+			Name:        p.Name,
+			Status:      p.Status,
+			Tags:        emptyToNil(p.Tags),
+		}, r.Address)
+	case tfTypeFeature:
+		f := tfFeature{}
+		if err := json.Unmarshal(r.Values, &f); err != nil {
+			return err
+		}
+		feature := models.Feature{
+			DefaultVariation: f.DefaultVariation,
+			Description:      f.Description,
+			EntityOverrides:  models.EntityOverride{},
+			Name:             f.Name,
+			Project:          nameFromARN(f.Project),
+			Status:           f.Status,
+			Tags:             emptyToNil(f.Tags),
+			ValueType:        types.FeatureValueType(f.ValueType),
+			Variations:       []models.Variation{},
+		}
+		for k, v := range f.EntityOverrides {
+			feature.EntityOverrides[k] = v
+		}
+		for _, v := range f.Variations {
+			if len(v.Value) != 1 {
+				return fmt.Errorf("variation %s must have one value", v.Name)
+			}
+			tv := v.Value[0]
+
+			// value_type is computed, so it is inferred from the value when it is not known yet (e.g. in a plan)
+			valueType := types.FeatureValueTypeString
+			switch {
+			case len(tv.BoolValue) > 0:
+				valueType = types.FeatureValueTypeBoolean
+			case len(tv.LongValue) > 0:
+				valueType = types.FeatureValueTypeLong
+			case len(tv.DoubleValue) > 0:
+				valueType = types.FeatureValueTypeDouble
+			}
+			if err := setValueType(&feature, v.Name, valueType); err != nil {
+				return err
+			}
+
+			var value any
+			var err error
+			switch feature.ValueType {
+			case types.FeatureValueTypeBoolean:
+				value, err = strconv.ParseBool(tv.BoolValue)
+			case types.FeatureValueTypeLong:
+				value, err = strconv.ParseInt(tv.LongValue, 10, 64)
+			case types.FeatureValueTypeDouble:
+				value, err = strconv.ParseFloat(tv.DoubleValue, 64)
+			default:
+				value = tv.StringValue
+			}
+			if err != nil {
+				return fmt.Errorf("variation %s has an invalid value: %w", v.Name, err)
+			}
+
+			feature.Variations = append(feature.Variations, models.Variation{
+				Name:  v.Name,
+				Value: map[types.VariableValueType]any{feature.VariableValueType(): value},
+			})
+		}
+		setDefaultVariation(&feature)
+		c.addFeature(feature, r.Address)
+	case tfTypeLaunch:
+		l := tfLaunch{}
+		if err := json.Unmarshal(r.Values, &l); err != nil {
+			return err
+		}
+		launch := models.Launch{
+			Description:       l.Description,
+			Groups:            []models.LaunchGroup{},
+			Name:              l.Name,
+			Project:           nameFromARN(l.Project),
+			RandomizationSalt: l.RandomizationSalt,
+			Status:            l.Status,
+			Tags:              emptyToNil(l.Tags),
+			Type:              l.Type,
+		}
+		for _, g := range l.Groups {
+			launch.Groups = append(launch.Groups, models.LaunchGroup{
+				Description:       g.Description,
+				FeatureVariations: map[string]string{g.Feature: g.Variation},
+				Name:              g.Name,
+			})
+		}
+		for _, config := range l.ScheduledSplitsConfig {
+			launch.ScheduledSplitsDefinition = &models.ScheduledSplitsDefinition{Steps: []models.ScheduledSplit{}}
+			for _, s := range config.Steps {
+				step := models.ScheduledSplit{
+					GroupWeights: s.GroupWeights,
+					StartTime:    s.StartTime,
+				}
+				for _, o := range s.SegmentOverrides {
+					step.SegmentOverrides = append(step.SegmentOverrides, models.SegmentOverride{
+						EvaluationOrder: o.EvaluationOrder,
+						Segment:         nameFromARN(o.Segment),
+						Weights:         o.Weights,
+					})
+				}
+				launch.ScheduledSplitsDefinition.Steps = append(launch.ScheduledSplitsDefinition.Steps, step)
+			}
+		}
+		c.addLaunch(launch, r.Address)
+	case tfTypeSegment:
+		s := tfSegment{}
+		if err := json.Unmarshal(r.Values, &s); err != nil {
+			return err
+		}
+		c.addSegment(models.Segment{
+			Description: s.Description,
+			Name:        s.Name,
+			Pattern:     s.Pattern,
+			Tags:        emptyToNil(s.Tags),
+		}, r.Address)
+	default:
+		return fmt.Errorf("unsupported resource type %s", r.Type)
+	}
+
+	return nil
+}
+
+func emptyToNil(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,146 @@
+package importer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/importer"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromTerraformShowOutput(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		showFile       string
+		wantErr        bool
+		expectBundle   *models.Bundle
+		expectWarnings []string
+	}{
+		{
+			name:     "showFile is empty",
+			showFile: "",
+			wantErr:  true,
+		},
+		{
+			name:     "showFile not found",
+			showFile: "../testdata/import/terraform/not-exists.json",
+			wantErr:  true,
+		},
+		{
+			name:     "no values",
+			showFile: "../testdata/import/terraform/empty.json",
+			wantErr:  true,
+		},
+		{
+			name:     "success",
+			showFile: "../testdata/import/terraform/show.json",
+			wantErr:  false,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{
+						Description: "project for test",
+						Name:        "test-project",
+						Status:      "AVAILABLE",
+						Tags:        map[string]string{"env": "test"},
+					},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "False",
+						EntityOverrides:  models.EntityOverride{"force-true": "True"},
+						Name:             "bool-feature",
+						Project:          "test-project",
+						Status:           "AVAILABLE",
+						ValueType:        types.FeatureValueTypeBoolean,
+						Variations: []models.Variation{
+							{Name: "True", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+							{Name: "False", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+						},
+					},
+				},
+				Launches: []models.Launch{
+					{
+						Groups: []models.LaunchGroup{
+							{FeatureVariations: map[string]string{"bool-feature": "False"}, Name: "control"},
+							{FeatureVariations: map[string]string{"bool-feature": "True"}, Name: "treatment"},
+						},
+						Name:              "test-launch",
+						Project:           "test-project",
+						RandomizationSalt: "test-launch",
+						ScheduledSplitsDefinition: &models.ScheduledSplitsDefinition{
+							Steps: []models.ScheduledSplit{
+								{
+									GroupWeights: map[string]int64{"control": 50000, "treatment": 50000},
+									SegmentOverrides: []models.SegmentOverride{
+										{EvaluationOrder: 1, Segment: "test-segment", Weights: map[string]int64{"treatment": 100000}},
+									},
+									StartTime: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+								},
+							},
+						},
+						Status: "CREATED",
+						Type:   "aws.evidently.splits",
+					},
+				},
+				Experiments: []models.Experiment{},
+				Segments: []models.Segment{
+					{Name: "test-segment", Pattern: `{"Price":[{"numeric":[">",10]}]}`},
+				},
+			},
+			expectWarnings: []string{
+				"module.launch.aws_evidently_unknown.test is skipped: unsupported resource type aws_evidently_unknown",
+			},
+		},
+		{
+			name:     "success: default variation is omitted in a plan",
+			showFile: "../testdata/import/terraform/no-default-variation.json",
+			wantErr:  false,
+			expectBundle: &models.Bundle{
+				Projects: []models.Project{
+					{Name: "test-project"},
+				},
+				Features: []models.Feature{
+					{
+						DefaultVariation: "Long1",
+						EntityOverrides:  models.EntityOverride{},
+						Name:             "long-feature",
+						Project:          "test-project",
+						ValueType:        types.FeatureValueTypeLong,
+						Variations: []models.Variation{
+							{Name: "Long1", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(1)}},
+							{Name: "Long2", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: int64(2)}},
+						},
+					},
+				},
+				Launches:    []models.Launch{},
+				Experiments: []models.Experiment{},
+				Segments:    []models.Segment{},
+			},
+			expectWarnings: []string{
+				"aws_evidently_feature.mixed is skipped: variation Long1 has a LONG value, but the feature has STRING values",
+				"project test-project is not defined, so it is imported with its name only",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := importer.FromTerraformShowOutput(c.showFile)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Empty(got.Conflicts)
+			asst.Equal(c.expectBundle, got.Bundle)
+			asst.Equal(c.expectWarnings, got.Warnings)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ARNs builds ARNs of Evidently resources in the account and the region of the server.
+type ARNs struct {
+	Region    string
+	AccountID string
+}
+
+// Project returns the ARN of the project, e.g. arn:aws:evidently:us-east-1:123456789012:project/test-project.
+func (a ARNs) Project(project string) string {
+	return a.arn("project/" + project)
+}
+
+// ProjectResource returns the ARN of a resource in the project, e.g. a launch or an experiment.
+// resourceType is the lower case name of the type, e.g. launch.
+func (a ARNs) ProjectResource(project, resourceType, name string) string {
+	return a.arn(fmt.Sprintf("project/%s/%s/%s", project, strings.ToLower(resourceType), name))
+}
+
+// Segment returns the ARN of the segment.
+func (a ARNs) Segment(segment string) string {
+	return a.arn("segment/" + segment)
+}
+
+func (a ARNs) arn(resource string) string {
+	return fmt.Sprintf("arn:aws:evidently:%s:%s:%s", a.Region, a.AccountID, resource)
+}
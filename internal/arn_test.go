@@ -0,0 +1,46 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ARNs(t *testing.T) {
+	arns := internal.ARNs{Region: "ap-northeast-1", AccountID: "123456789012"}
+
+	cases := []struct {
+		name   string
+		arn    string
+		expect string
+	}{
+		{
+			name:   "project",
+			arn:    arns.Project("test-project"),
+			expect: "arn:aws:evidently:ap-northeast-1:123456789012:project/test-project",
+		},
+		{
+			name:   "launch",
+			arn:    arns.ProjectResource("test-project", "LAUNCH", "test-launch"),
+			expect: "arn:aws:evidently:ap-northeast-1:123456789012:project/test-project/launch/test-launch",
+		},
+		{
+			name:   "experiment",
+			arn:    arns.ProjectResource("test-project", "EXPERIMENT", "test-experiment"),
+			expect: "arn:aws:evidently:ap-northeast-1:123456789012:project/test-project/experiment/test-experiment",
+		},
+		{
+			name:   "segment",
+			arn:    arns.Segment("test-segment"),
+			expect: "arn:aws:evidently:ap-northeast-1:123456789012:segment/test-segment",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			asst.Equal(c.expect, c.arn)
+		})
+	}
+}
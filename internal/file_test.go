@@ -0,0 +1,37 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteFileAtomic(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "file.json")
+
+	asst.NoError(internal.WriteFileAtomic(path, []byte("first")))
+	asst.NoError(internal.WriteFileAtomic(path, []byte("second")))
+
+	b, err := os.ReadFile(path)
+	asst.NoError(err)
+	asst.Equal("second", string(b))
+
+	info, err := os.Stat(path)
+	asst.NoError(err)
+	asst.Equal(os.FileMode(0o644), info.Mode().Perm())
+
+	// no temporary files are left
+	files, err := os.ReadDir(filepath.Dir(path))
+	asst.NoError(err)
+	asst.Len(files, 1)
+
+	// the directory of the file cannot be created
+	asst.Error(internal.WriteFileAtomic(filepath.Join(path, "file.json"), []byte("third")))
+}
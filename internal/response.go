@@ -3,6 +3,7 @@ package internal
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
 
 	"github.com/gofrs/uuid"
 )
@@ -26,11 +27,51 @@ func GenerateResponseBody(data any) ([]byte, string, error) {
 		return nil, "", err
 	}
 
-	requestID := dummyRequestID
-	uuid, err := uuid.NewV4()
-	if err == nil {
-		requestID = uuid.String()
+	return bytes, NewRequestID(), nil
+}
+
+// NewRequestID generates a request ID in the same form as AWS.
+func NewRequestID() string {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return dummyRequestID
+	}
+
+	return id.String()
+}
+
+// StatusWriter is a http.ResponseWriter that records the status code and the size of the response body.
+type StatusWriter struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+
+	wroteHeader bool
+}
+
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{
+		ResponseWriter: w,
+		Status:         http.StatusOK,
+	}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.Status = status
+		w.wroteHeader = true
 	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += n
+	return n, err
+}
 
-	return bytes, requestID, nil
+// Unwrap returns the original ResponseWriter for http.ResponseController.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/michimani/evidentlylocal/types"
+)
+
+const (
+	maxResourceNameLength = 127
+	maxSegmentNameLength  = 64
+	maxEntityIDLength     = 512
+	minBatchRequests      = 1
+	maxBatchRequests      = 20
+	maxProjectEvents      = 50
+	minListResults        = 1
+	maxListResults        = 100
+)
+
+var resourceNamePattern = regexp.MustCompile(`^[-a-zA-Z0-9._]*$`)
+
+// ValidationError is returned when input from a client does not satisfy
+// the constraints of Evidently. It is sent to clients as ValidationException.
+type ValidationError struct {
+	Message string
+	Reason  types.ValidationExceptionReason
+	Fields  []types.ValidationExceptionField
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// newFieldValidationError generates a ValidationError that has the same message format as AWS.
+func newFieldValidationError(fields []types.ValidationExceptionField) *ValidationError {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = fmt.Sprintf("Value at '%s' failed to satisfy constraint: %s", f.Name, f.Message)
+	}
+
+	format := "%d validation errors detected: %s"
+	if len(fields) == 1 {
+		format = "%d validation error detected: %s"
+	}
+
+	return &ValidationError{
+		Message: fmt.Sprintf(format, len(fields), strings.Join(messages, "; ")),
+		Reason:  types.ValidationExceptionReasonFieldValidationFailed,
+		Fields:  fields,
+	}
+}
+
+// DecodeRequestBody decodes the request body into v strictly.
+// Unknown fields and trailing data are rejected. An empty body is decoded as an empty object,
+// so that missing required fields are reported by the validation of each request.
+func DecodeRequestBody(body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return &ValidationError{
+			Message: "Unable to parse request body: " + err.Error(),
+			Reason:  types.ValidationExceptionReasonCannotParse,
+		}
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &ValidationError{
+			Message: "Unable to parse request body: unexpected data after the top-level value",
+			Reason:  types.ValidationExceptionReasonCannotParse,
+		}
+	}
+
+	return nil
+}
+
+func ValidateProjectName(name string) error {
+	return validateFields(checkResourceName("project", name, maxResourceNameLength))
+}
+
+func ValidateFeatureName(name string) error {
+	return validateFields(checkResourceName("feature", name, maxResourceNameLength))
+}
+
+func ValidateLaunchName(name string) error {
+	return validateFields(checkResourceName("launch", name, maxResourceNameLength))
+}
+
+func ValidateExperimentName(name string) error {
+	return validateFields(checkResourceName("experiment", name, maxResourceNameLength))
+}
+
+func ValidateSegmentName(name string) error {
+	return validateFields(checkResourceName("segment", name, maxSegmentNameLength))
+}
+
+// ValidateEvaluateFeatureRequest validates the body of EvaluateFeature request.
+func ValidateEvaluateFeatureRequest(req *types.EvaluateFeatureRequest) error {
+	return validateFields(
+		checkRequiredLength("entityId", req.EntityID, maxEntityIDLength),
+		checkJSON("evaluationContext", req.EvaluationContext),
+	)
+}
+
+// ValidateBatchEvaluateFeatureRequest validates the body of BatchEvaluateFeature request.
+func ValidateBatchEvaluateFeatureRequest(req *types.BatchEvaluateFeatureRequest) error {
+	if req.Requests == nil {
+		return validateFields(&types.ValidationExceptionField{
+			Name:    "requests",
+			Message: "Member must not be null",
+		})
+	}
+
+	if len(req.Requests) < minBatchRequests || len(req.Requests) > maxBatchRequests {
+		return validateFields(&types.ValidationExceptionField{
+			Name:    "requests",
+			Message: fmt.Sprintf("Member must have length between %d and %d", minBatchRequests, maxBatchRequests),
+		})
+	}
+
+	checks := []*types.ValidationExceptionField{}
+	for i, r := range req.Requests {
+		prefix := fmt.Sprintf("requests.%d.member.", i+1)
+		checks = append(checks,
+			checkRequiredLength(prefix+"entityId", r.EntityID, maxEntityIDLength),
+			checkJSON(prefix+"evaluationContext", r.EvaluationContext),
+			checkResourceName(prefix+"feature", r.Feature, maxResourceNameLength),
+		)
+	}
+
+	return validateFields(checks...)
+}
+
+// ValidatePutProjectEventsRequest validates the body of PutProjectEvents request.
+func ValidatePutProjectEventsRequest(req *types.PutProjectEventsRequest) error {
+	if req.Events == nil {
+		return validateFields(&types.ValidationExceptionField{
+			Name:    "events",
+			Message: "Member must not be null",
+		})
+	}
+
+	if len(req.Events) > maxProjectEvents {
+		return validateFields(&types.ValidationExceptionField{
+			Name:    "events",
+			Message: fmt.Sprintf("Member must have length less than or equal to %d", maxProjectEvents),
+		})
+	}
+
+	checks := []*types.ValidationExceptionField{}
+	for i, e := range req.Events {
+		prefix := fmt.Sprintf("events.%d.member.", i+1)
+		checks = append(checks,
+			checkRequiredJSON(prefix+"data", e.Data),
+			checkTimestamp(prefix+"timestamp", e.Timestamp),
+			checkEventType(prefix+"type", e.Type),
+		)
+	}
+
+	return validateFields(checks...)
+}
+
+// ValidateListSegmentReferencesRequest validates the parameters of ListSegmentReferences request.
+// MaxResults is zero if it is not given.
+func ValidateListSegmentReferencesRequest(req *types.ListSegmentReferencesRequest) error {
+	checks := []*types.ValidationExceptionField{
+		checkResourceName("segment", req.Segment, maxSegmentNameLength),
+		checkSegmentReferenceResourceType("type", req.Type),
+	}
+
+	if req.MaxResults != 0 && (req.MaxResults < minListResults || req.MaxResults > maxListResults) {
+		checks = append(checks, &types.ValidationExceptionField{
+			Name:    "maxResults",
+			Message: fmt.Sprintf("Member must have value between %d and %d", minListResults, maxListResults),
+		})
+	}
+
+	return validateFields(checks...)
+}
+
+// validateFields returns ValidationError that has all given field errors.
+// nil values mean that the field is valid.
+func validateFields(checks ...*types.ValidationExceptionField) error {
+	fields := []types.ValidationExceptionField{}
+	for _, c := range checks {
+		if c != nil {
+			fields = append(fields, *c)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return newFieldValidationError(fields)
+}
+
+// checkResourceName checks the name against the AWS name pattern and length.
+// "." and ".." match the AWS pattern, but they are rejected because names are
+// used as path elements in the data directory.
+func checkResourceName(field, name string, maxLength int) *types.ValidationExceptionField {
+	if f := checkRequiredLength(field, name, maxLength); f != nil {
+		return f
+	}
+
+	if !resourceNamePattern.MatchString(name) || name == "." || name == ".." {
+		return &types.ValidationExceptionField{
+			Name:    field,
+			Message: "Member must satisfy regular expression pattern: " + resourceNamePattern.String(),
+		}
+	}
+
+	return nil
+}
+
+func checkRequiredLength(field, value string, maxLength int) *types.ValidationExceptionField {
+	if len(value) == 0 {
+		return &types.ValidationExceptionField{
+			Name:    field,
+			Message: "Member must not be null or empty",
+		}
+	}
+
+	if utf8.RuneCountInString(value) > maxLength {
+		return &types.ValidationExceptionField{
+			Name:    field,
+			Message: fmt.Sprintf("Member must have length between 1 and %d", maxLength),
+		}
+	}
+
+	return nil
+}
+
+// checkJSON checks that the optional value is a valid JSON.
+func checkJSON(field, value string) *types.ValidationExceptionField {
+	if len(value) == 0 || json.Valid([]byte(value)) {
+		return nil
+	}
+
+	return &types.ValidationExceptionField{
+		Name:    field,
+		Message: "Member must be a valid JSON",
+	}
+}
+
+func checkRequiredJSON(field, value string) *types.ValidationExceptionField {
+	if len(value) == 0 {
+		return &types.ValidationExceptionField{
+			Name:    field,
+			Message: "Member must not be null or empty",
+		}
+	}
+
+	return checkJSON(field, value)
+}
+
+func checkTimestamp(field string, value float64) *types.ValidationExceptionField {
+	if value > 0 {
+		return nil
+	}
+
+	return &types.ValidationExceptionField{
+		Name:    field,
+		Message: "Member must not be null",
+	}
+}
+
+func checkEventType(field string, value types.EventType) *types.ValidationExceptionField {
+	if value == types.EventTypeEvaluation || value == types.EventTypeCustom {
+		return nil
+	}
+
+	return &types.ValidationExceptionField{
+		Name:    field,
+		Message: fmt.Sprintf("Member must satisfy enum value set: [%s, %s]", types.EventTypeEvaluation, types.EventTypeCustom),
+	}
+}
+
+func checkSegmentReferenceResourceType(field string, value types.SegmentReferenceResourceType) *types.ValidationExceptionField {
+	if value == types.SegmentReferenceResourceTypeLaunch || value == types.SegmentReferenceResourceTypeExperiment {
+		return nil
+	}
+
+	return &types.ValidationExceptionField{
+		Name:    field,
+		Message: fmt.Sprintf("Member must satisfy enum value set: [%s, %s]", types.SegmentReferenceResourceTypeExperiment, types.SegmentReferenceResourceTypeLaunch),
+	}
+}
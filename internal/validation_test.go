@@ -0,0 +1,204 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateResourceNames(t *testing.T) {
+	t.Parallel()
+
+	validators := map[string]func(string) error{
+		"project":    internal.ValidateProjectName,
+		"feature":    internal.ValidateFeatureName,
+		"launch":     internal.ValidateLaunchName,
+		"experiment": internal.ValidateExperimentName,
+		"segment":    internal.ValidateSegmentName,
+	}
+
+	cases := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "valid name", input: "test-Feature_1.v2", wantErr: false},
+		{name: "empty", input: "", wantErr: true},
+		{name: "max length", input: strings.Repeat("a", 127), wantErr: false},
+		{name: "too long", input: strings.Repeat("a", 128), wantErr: true},
+		{name: "dot", input: ".", wantErr: true},
+		{name: "dot dot", input: "..", wantErr: true},
+		{name: "contains slash", input: "../features", wantErr: true},
+		{name: "contains backslash", input: `..\features`, wantErr: true},
+		{name: "contains space", input: "test feature", wantErr: true},
+		{name: "contains null", input: "test\x00", wantErr: true},
+	}
+
+	for _, c := range cases {
+		for field, validate := range validators {
+			c, field, validate := c, field, validate
+			t.Run(field+": "+c.name, func(tt *testing.T) {
+				asst := assert.New(tt)
+				err := validate(c.input)
+
+				wantErr := c.wantErr
+				if field == "segment" && len(c.input) > 64 {
+					wantErr = true
+				}
+
+				if wantErr {
+					asst.Error(err)
+					asst.IsType(&internal.ValidationError{}, err)
+					asst.Contains(err.Error(), field)
+					return
+				}
+
+				asst.NoError(err)
+			})
+		}
+	}
+}
+
+func Test_DecodeRequestBody(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		body    string
+		wantErr bool
+		expect  types.EvaluateFeatureRequest
+	}{
+		{
+			name:    "success",
+			body:    `{"entityId":"test-entity-id","evaluationContext":"{}"}`,
+			wantErr: false,
+			expect:  types.EvaluateFeatureRequest{EntityID: "test-entity-id", EvaluationContext: "{}"},
+		},
+		{
+			name:    "empty body",
+			body:    "",
+			wantErr: false,
+			expect:  types.EvaluateFeatureRequest{},
+		},
+		{
+			name:    "unknown field",
+			body:    `{"entityId":"test-entity-id","evaluateContext":"{}"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			body:    `{"entityId":`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			body:    `{"entityId":"test-entity-id"} {"entityId":"test-entity-id"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got := types.EvaluateFeatureRequest{}
+			err := internal.DecodeRequestBody(strings.NewReader(c.body), &got)
+			if c.wantErr {
+				ve := &internal.ValidationError{}
+				asst.ErrorAs(err, &ve)
+				asst.Equal(types.ValidationExceptionReasonCannotParse, ve.Reason)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(c.expect, got)
+		})
+	}
+}
+
+func Test_ValidatePutProjectEventsRequest(t *testing.T) {
+	t.Parallel()
+
+	validEvent := types.Event{Data: `{"details":{}}`, Timestamp: 1700000000, Type: types.EventTypeCustom}
+
+	cases := []struct {
+		name       string
+		req        *types.PutProjectEventsRequest
+		wantFields []string
+	}{
+		{name: "ok", req: &types.PutProjectEventsRequest{Events: []types.Event{validEvent}}},
+		{name: "empty events", req: &types.PutProjectEventsRequest{Events: []types.Event{}}},
+		{name: "null events", req: &types.PutProjectEventsRequest{}, wantFields: []string{"events"}},
+		{name: "too many events", req: &types.PutProjectEventsRequest{Events: make([]types.Event, 51)}, wantFields: []string{"events"}},
+		{
+			name:       "invalid event",
+			req:        &types.PutProjectEventsRequest{Events: []types.Event{validEvent, {Data: "not json", Type: "unknown"}}},
+			wantFields: []string{"events.2.member.data", "events.2.member.timestamp", "events.2.member.type"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			err := internal.ValidatePutProjectEventsRequest(c.req)
+			if len(c.wantFields) == 0 {
+				asst.NoError(err)
+				return
+			}
+
+			ve := &internal.ValidationError{}
+			if asst.ErrorAs(err, &ve) {
+				fields := []string{}
+				for _, f := range ve.Fields {
+					fields = append(fields, f.Name)
+				}
+				asst.Equal(c.wantFields, fields)
+			}
+		})
+	}
+}
+
+func Test_ValidateListSegmentReferencesRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		req        *types.ListSegmentReferencesRequest
+		wantFields []string
+	}{
+		{name: "ok", req: &types.ListSegmentReferencesRequest{Segment: "s", Type: types.SegmentReferenceResourceTypeLaunch}},
+		{name: "ok with max results", req: &types.ListSegmentReferencesRequest{Segment: "s", Type: types.SegmentReferenceResourceTypeExperiment, MaxResults: 100}},
+		{name: "without type", req: &types.ListSegmentReferencesRequest{Segment: "s"}, wantFields: []string{"type"}},
+		{
+			name:       "invalid segment and max results",
+			req:        &types.ListSegmentReferencesRequest{Segment: "..", Type: types.SegmentReferenceResourceTypeLaunch, MaxResults: 101},
+			wantFields: []string{"segment", "maxResults"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			err := internal.ValidateListSegmentReferencesRequest(c.req)
+			if len(c.wantFields) == 0 {
+				asst.NoError(err)
+				return
+			}
+
+			ve := &internal.ValidationError{}
+			if asst.ErrorAs(err, &ve) {
+				fields := []string{}
+				for _, f := range ve.Fields {
+					fields = append(fields, f.Name)
+				}
+				asst.Equal(c.wantFields, fields)
+			}
+		})
+	}
+}
@@ -2,14 +2,20 @@ package logger
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 )
 
+// Logger is a structured logger. args are key/value pairs of fields in the same form as log/slog,
+// e.g. l.Info("evaluated", "project", project, "feature", feature).
 type Logger interface {
-	Info(msg string)
-	Error(msg string, err error)
-	Warn(msg string)
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, err error, args ...any)
+	// With returns a child logger that adds the fields to all logs, e.g. a request ID.
+	With(args ...any) Logger
 }
 
 type ELLogger struct {
@@ -20,32 +26,71 @@ var _ Logger = (*ELLogger)(nil)
 
 const serviceName = "evidently-local"
 
+// Formats of log output.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Options is options of the logger. Level is one of debug, info, warn and error.
+type Options struct {
+	Level  string
+	Format string
+}
+
 func NewEvidentlyLocalLogger(out io.Writer) (*ELLogger, error) {
+	return NewEvidentlyLocalLoggerWithOptions(out, Options{Level: "info", Format: FormatJSON})
+}
+
+func NewEvidentlyLocalLoggerWithOptions(out io.Writer, opts Options) (*ELLogger, error) {
 	if out == nil {
 		return nil, errors.New("out is nil")
 	}
 
-	handler := slog.NewJSONHandler(out, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level: %s", opts.Level)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level: level,
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case FormatText:
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", opts.Format)
+	}
 
 	logger := slog.New(handler)
 
 	return &ELLogger{logger: logger}, nil
 }
 
-func (l *ELLogger) Info(msg string) {
-	l.logger.Info(msg)
+func (l *ELLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
 }
 
-func (l *ELLogger) Error(msg string, err error) {
+func (l *ELLogger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
+}
+
+func (l *ELLogger) Error(msg string, err error, args ...any) {
 	if err == nil {
-		l.logger.Error(msg)
+		l.logger.Error(msg, args...)
 		return
 	}
-	l.logger.Error(msg, slog.String("error", err.Error()))
+	l.logger.Error(msg, append([]any{slog.String("error", err.Error())}, args...)...)
+}
+
+func (l *ELLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
 }
 
-func (l *ELLogger) Warn(msg string) {
-	l.logger.Warn(msg)
+func (l *ELLogger) With(args ...any) Logger {
+	return &ELLogger{logger: l.logger.With(args...)}
 }
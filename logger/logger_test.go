@@ -172,3 +172,150 @@ func Test_ELLogger_Error(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewEvidentlyLocalLoggerWithOptions(t *testing.T) {
+	cases := []struct {
+		name         string
+		opts         logger.Options
+		wantError    bool
+		expectInfo   bool
+		expectOutput string
+	}{
+		{
+			name:         "json",
+			opts:         logger.Options{Level: "info", Format: logger.FormatJSON},
+			expectInfo:   true,
+			expectOutput: `"msg":"test"`,
+		},
+		{
+			name:         "text",
+			opts:         logger.Options{Level: "debug", Format: logger.FormatText},
+			expectInfo:   true,
+			expectOutput: `msg=test`,
+		},
+		{
+			name:       "info is filtered by warn level",
+			opts:       logger.Options{Level: "warn", Format: logger.FormatJSON},
+			expectInfo: false,
+		},
+		{
+			name:      "invalid level",
+			opts:      logger.Options{Level: "verbose", Format: logger.FormatJSON},
+			wantError: true,
+		},
+		{
+			name:      "invalid format",
+			opts:      logger.Options{Level: "info", Format: "xml"},
+			wantError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			out := bytes.Buffer{}
+			l, err := logger.NewEvidentlyLocalLoggerWithOptions(&out, c.opts)
+
+			if c.wantError {
+				asst.Nil(l)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			l.Info("test")
+			if !c.expectInfo {
+				asst.Empty(out.String())
+				return
+			}
+			asst.Contains(out.String(), c.expectOutput, out.String())
+		})
+	}
+}
+
+func Test_ELLogger_Debug(t *testing.T) {
+	cases := []struct {
+		name        string
+		level       string
+		expectEmpty bool
+	}{
+		{
+			name:  "debug level",
+			level: "debug",
+		},
+		{
+			name:        "filtered by info level",
+			level:       "info",
+			expectEmpty: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out := bytes.Buffer{}
+			l, err := logger.NewEvidentlyLocalLoggerWithOptions(&out, logger.Options{Level: c.level, Format: logger.FormatJSON})
+			asst.NoError(err)
+
+			l.Debug("test")
+
+			if c.expectEmpty {
+				asst.Empty(out.String())
+				return
+			}
+			asst.Contains(out.String(), `"level":"DEBUG"`, out.String())
+			asst.Contains(out.String(), `"msg":"test"`, out.String())
+		})
+	}
+}
+
+func Test_ELLogger_Fields(t *testing.T) {
+	cases := []struct {
+		name    string
+		log     func(l logger.Logger)
+		format  string
+		expects []string
+	}{
+		{
+			name:    "json fields",
+			log:     func(l logger.Logger) { l.Info("evaluated", "project", "p", "variation", "On") },
+			format:  logger.FormatJSON,
+			expects: []string{`"msg":"evaluated"`, `"project":"p"`, `"variation":"On"`},
+		},
+		{
+			name:    "text fields",
+			log:     func(l logger.Logger) { l.Warn("evaluated", "project", "p") },
+			format:  logger.FormatText,
+			expects: []string{`msg=evaluated`, `project=p`},
+		},
+		{
+			name:    "error with fields",
+			log:     func(l logger.Logger) { l.Error("failed", errors.New("test error"), "feature", "f") },
+			format:  logger.FormatJSON,
+			expects: []string{`"error":"test error"`, `"feature":"f"`},
+		},
+		{
+			name:    "child logger",
+			log:     func(l logger.Logger) { l.With("requestId", "r-1").With("project", "p").Info("test", "feature", "f") },
+			format:  logger.FormatJSON,
+			expects: []string{`"requestId":"r-1"`, `"project":"p"`, `"feature":"f"`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			out := bytes.Buffer{}
+			l, err := logger.NewEvidentlyLocalLoggerWithOptions(&out, logger.Options{Level: "info", Format: c.format})
+			asst.NoError(err)
+
+			c.log(l)
+
+			for _, expect := range c.expects {
+				asst.Contains(out.String(), expect, out.String())
+			}
+		})
+	}
+}
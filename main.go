@@ -1,34 +1,120 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/handler"
 	"github.com/michimani/evidentlylocal/logger"
 	"github.com/michimani/evidentlylocal/repository"
 	"github.com/michimani/evidentlylocal/server"
+	"github.com/michimani/evidentlylocal/tracing"
 )
 
 const (
-	portEnvKey  = "EVIDENTLY_LOCAL_PORT"
-	defaultPort = "2306"
-	dataDir     = "./data"
+	commandName = "evidently-local"
 )
 
 func main() {
-	port := os.Getenv(portEnvKey)
-	if len(port) == 0 {
-		port = defaultPort
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			os.Exit(runImport(os.Args[2:], os.Stdout, os.Stderr))
+		case "export":
+			os.Exit(runExport(os.Args[2:], os.Stdout, os.Stderr))
+		case "config":
+			os.Exit(runConfig(os.Args[2:], os.Stdout, os.Stderr))
+		default:
+			// noop
+		}
 	}
 
-	l, err := logger.NewEvidentlyLocalLogger(os.Stdout)
+	cfg, err := config.Load(commandName, os.Args[1:], os.Getenv, os.Stderr)
+	if err != nil {
+		os.Exit(configErrorExitCode(err, os.Stderr))
+	}
+
+	l, err := logger.NewEvidentlyLocalLoggerWithOptions(os.Stdout, logger.Options{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	fRepo, err := repository.NewFeatureRepositoryWithJSONFile(dataDir, l)
+	l.Info("effective configuration", "config", cfg.String())
+
+	fRepo, err := newFeatureRepository(cfg, l)
 	if err != nil {
 		panic(err)
 	}
 
-	server.Start(port, l, fRepo)
+	srv, err := server.New(cfg, l, fRepo)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(cfg.Tracing.OTLPEndpoint) > 0 {
+		shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.OTLPEndpoint, handler.ServerVersion())
+		if err != nil {
+			panic(err)
+		}
+		// spans are flushed after in-flight requests are drained
+		srv.AddShutdownHook(shutdownTracing)
+	}
+
+	// in-flight requests are drained on SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if err := srv.Run(ctx); err != nil {
+		l.Error("server stopped with error", err)
+		os.Exit(1)
+	}
+}
+
+// runConfig runs config subcommand that prints the effective configuration in YAML, and returns the exit code.
+//
+//	evidently-local config [flags of the server]
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.Load(commandName+" config", args, os.Getenv, stderr)
+	if err != nil {
+		return configErrorExitCode(err, stderr)
+	}
+
+	fmt.Fprint(stdout, cfg.YAML())
+
+	return 0
+}
+
+func configErrorExitCode(err error, stderr io.Writer) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "invalid configuration: %v\n", err)
+	return 2
+}
+
+// newFeatureRepository returns a repository for the storage backend in the configuration.
+// Multiple data directories are loaded as layers.
+func newFeatureRepository(cfg *config.Config, l logger.Logger) (repository.FeatureRepository, error) {
+	switch cfg.Storage {
+	case config.StorageBundle:
+		return repository.NewFeatureRepositoryWithBundleFile(cfg.BundleFile, l)
+	case config.StorageDataDir:
+		if len(cfg.DataDirs) > 1 {
+			return repository.NewFeatureRepositoryWithLayers(cfg.DataDirs, l)
+		}
+		return repository.NewFeatureRepositoryWithJSONFile(cfg.DataDirs[0], l)
+	default:
+		return nil, fmt.Errorf("unsupported storage: %s", cfg.Storage)
+	}
 }
@@ -0,0 +1,140 @@
+// Package metrics exposes metrics of the server in the Prometheus format.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "evidently_local"
+
+// Metrics is a set of metrics of a server. Each server has its own registry,
+// so that multiple servers can run in one process.
+// All methods of a nil Metrics do nothing, so that handlers can be used without metrics.
+type Metrics struct {
+	registry             *prometheus.Registry
+	evaluations          *prometheus.CounterVec
+	apiCalls             *prometheus.CounterVec
+	apiCallDuration      *prometheus.HistogramVec
+	repositoryLoads      *prometheus.CounterVec
+	repositoryLoadErrors prometheus.Gauge
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		evaluations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "evaluations_total",
+			Help:      "Number of feature evaluations.",
+		}, []string{"project", "feature", "variation", "reason"}),
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_calls_total",
+			Help:      "Number of API calls.",
+		}, []string{"operation", "status"}),
+		apiCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_call_duration_seconds",
+			Help:      "Latency of API calls.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		repositoryLoads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_loads_total",
+			Help:      "Number of loads of the repository.",
+		}, []string{"result"}),
+		repositoryLoadErrors: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "repository_load_errors",
+			Help:      "Number of files that could not be loaded in the last load of the repository.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.evaluations,
+		m.apiCalls,
+		m.apiCallDuration,
+		m.repositoryLoads,
+		m.repositoryLoadErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns the handler of /metrics.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the registry of the metrics, e.g. to register collectors of an application that embeds the server.
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+
+	return m.registry
+}
+
+// ObserveEvaluation counts an evaluation of a feature.
+func (m *Metrics) ObserveEvaluation(project, feature, variation, reason string) {
+	if m == nil {
+		return
+	}
+
+	m.evaluations.WithLabelValues(project, feature, variation, reason).Inc()
+}
+
+// ObserveAPICall counts an API call, and observes its latency.
+func (m *Metrics) ObserveAPICall(operation string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.apiCalls.WithLabelValues(operation, strconv.Itoa(status)).Inc()
+	m.apiCallDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// ObserveRepositoryLoad counts a load of the repository, and sets the number of files that could not be loaded.
+func (m *Metrics) ObserveRepositoryLoad(report *repository.LoadReport, err error) {
+	if m == nil {
+		return
+	}
+
+	if err != nil {
+		m.repositoryLoads.WithLabelValues("failure").Inc()
+		return
+	}
+
+	m.repositoryLoads.WithLabelValues("success").Inc()
+	m.repositoryLoadErrors.Set(float64(len(report.Errors)))
+}
+
+// Middleware observes API calls handled by next. operation returns the name of the operation of a request.
+func (m *Metrics) Middleware(operation func(r *http.Request) string, next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := internal.NewStatusWriter(w)
+
+		next.ServeHTTP(sw, r)
+
+		m.ObserveAPICall(operation(r), sw.Status, time.Since(start))
+	})
+}
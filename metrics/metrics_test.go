@@ -0,0 +1,94 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/metrics"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Metrics(t *testing.T) {
+	asst := assert.New(t)
+
+	m := metrics.New()
+	m.ObserveEvaluation("p", "f", "On", "DEFAULT")
+	m.ObserveEvaluation("p", "f", "On", "DEFAULT")
+	m.ObserveAPICall("EvaluateFeature", http.StatusOK, 10*time.Millisecond)
+	m.ObserveRepositoryLoad(&repository.LoadReport{Errors: []repository.LoadError{{File: "a.json", Error: "invalid"}}}, nil)
+	m.ObserveRepositoryLoad(nil, errors.New("failed"))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	asst.Equal(http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`evidently_local_evaluations_total{feature="f",project="p",reason="DEFAULT",variation="On"} 2`,
+		`evidently_local_api_calls_total{operation="EvaluateFeature",status="200"} 1`,
+		`evidently_local_api_call_duration_seconds_count{operation="EvaluateFeature"} 1`,
+		`evidently_local_repository_loads_total{result="success"} 1`,
+		`evidently_local_repository_loads_total{result="failure"} 1`,
+		`evidently_local_repository_load_errors 1`,
+		`go_goroutines`,
+	} {
+		asst.Contains(body, want)
+	}
+}
+
+func Test_Metrics_Middleware(t *testing.T) {
+	cases := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus string
+	}{
+		{
+			name:       "implicit 200",
+			handler:    func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) },
+			wantStatus: "200",
+		},
+		{
+			name:       "error",
+			handler:    func(w http.ResponseWriter, r *http.Request) { http.Error(w, "Not found", http.StatusNotFound) },
+			wantStatus: "404",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			m := metrics.New()
+			h := m.Middleware(func(r *http.Request) string { return "Test" }, c.handler)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			rec := httptest.NewRecorder()
+			m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			asst.Contains(rec.Body.String(), `evidently_local_api_calls_total{operation="Test",status="`+c.wantStatus+`"} 1`)
+		})
+	}
+}
+
+func Test_Metrics_Nil(t *testing.T) {
+	asst := assert.New(t)
+
+	var m *metrics.Metrics
+	asst.NotPanics(func() {
+		m.ObserveEvaluation("p", "f", "On", "DEFAULT")
+		m.ObserveAPICall("EvaluateFeature", http.StatusOK, time.Millisecond)
+		m.ObserveRepositoryLoad(nil, nil)
+	})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	asst.NotNil(m.Middleware(func(r *http.Request) string { return "" }, next))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	asst.Equal(http.StatusNotFound, rec.Code)
+	asst.True(strings.HasPrefix(rec.Body.String(), "404"))
+}
@@ -0,0 +1,11 @@
+package models
+
+// Bundle is a set of all resources of an environment.
+// Features, launches and experiments belong to the project specified by their project field.
+type Bundle struct {
+	Projects    []Project    `json:"projects"`
+	Features    []Feature    `json:"features"`
+	Launches    []Launch     `json:"launches"`
+	Experiments []Experiment `json:"experiments"`
+	Segments    []Segment    `json:"segments"`
+}
@@ -0,0 +1,26 @@
+package models
+
+type Experiment struct {
+	Description        string              `json:"description,omitempty"`
+	Name               string              `json:"name"`
+	OnlineAbDefinition *OnlineAbDefinition `json:"onlineAbDefinition,omitempty"`
+	Project            string              `json:"project"`
+	RandomizationSalt  string              `json:"randomizationSalt,omitempty"`
+	SamplingRate       int64               `json:"samplingRate,omitempty"`
+	Segment            string              `json:"segment,omitempty"`
+	Status             string              `json:"status,omitempty"`
+	Tags               map[string]string   `json:"tags,omitempty"`
+	Treatments         []Treatment         `json:"treatments"`
+	Type               string              `json:"type,omitempty"`
+}
+
+type OnlineAbDefinition struct {
+	ControlTreatmentName string           `json:"controlTreatmentName"`
+	TreatmentWeights     map[string]int64 `json:"treatmentWeights"`
+}
+
+type Treatment struct {
+	Description       string            `json:"description,omitempty"`
+	FeatureVariations map[string]string `json:"featureVariations"`
+	Name              string            `json:"name"`
+}
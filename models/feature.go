@@ -1,15 +1,22 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
 	"github.com/michimani/evidentlylocal/types"
 )
 
 type Feature struct {
 	DefaultVariation string                 `json:"defaultVariation"`
+	Description      string                 `json:"description,omitempty"`
 	EntityOverrides  EntityOverride         `json:"entityOverrides"`
 	Name             string                 `json:"name"`
 	Project          string                 `json:"project"`
 	Status           string                 `json:"status"`
+	Tags             map[string]string      `json:"tags,omitempty"`
 	ValueType        types.FeatureValueType `json:"valueType"`
 	Variations       []Variation            `json:"variations"`
 }
@@ -51,3 +58,67 @@ func (f *Feature) VariableValueType() types.VariableValueType {
 
 	return ""
 }
+
+// Clone returns a deep copy of the feature, that shares no maps or slices with it.
+// Values of variations are not copied, because they are scalars.
+func (f *Feature) Clone() *Feature {
+	c := *f
+	c.EntityOverrides = maps.Clone(f.EntityOverrides)
+	c.Tags = maps.Clone(f.Tags)
+
+	if f.Variations != nil {
+		c.Variations = make([]Variation, len(f.Variations))
+		for i, v := range f.Variations {
+			c.Variations[i] = Variation{Name: v.Name, Value: maps.Clone(v.Value)}
+		}
+	}
+
+	return &c
+}
+
+// HasVariation reports whether the variation is defined in the feature.
+func (f *Feature) HasVariation(variation string) bool {
+	return slices.ContainsFunc(f.Variations, func(v Variation) bool { return v.Name == variation })
+}
+
+// Validate checks references in the feature, so that evaluations never return a null value:
+// the value type is known, each variation has a value of the type, and the default variation
+// and variations of entity overrides are defined.
+func (f *Feature) Validate() error {
+	errs := []error{}
+
+	valueType := f.VariableValueType()
+	if len(valueType) == 0 {
+		errs = append(errs, fmt.Errorf("valueType %q is unknown", f.ValueType))
+	}
+
+	seen := map[string]bool{}
+	for _, v := range f.Variations {
+		if seen[v.Name] {
+			errs = append(errs, fmt.Errorf("variation %s is defined more than once", v.Name))
+		}
+		seen[v.Name] = true
+
+		if _, ok := v.Value[valueType]; len(valueType) > 0 && !ok {
+			errs = append(errs, fmt.Errorf("variation %s has no %s", v.Name, valueType))
+		}
+	}
+
+	if !seen[f.DefaultVariation] {
+		errs = append(errs, fmt.Errorf("defaultVariation %q is not a variation of the feature", f.DefaultVariation))
+	}
+
+	entityIDs := make([]string, 0, len(f.EntityOverrides))
+	for entityID := range f.EntityOverrides {
+		entityIDs = append(entityIDs, entityID)
+	}
+	slices.Sort(entityIDs)
+
+	for _, entityID := range entityIDs {
+		if v := f.EntityOverrides[entityID]; !seen[v] {
+			errs = append(errs, fmt.Errorf("variation %q of the override for %s is not a variation of the feature", v, entityID))
+		}
+	}
+
+	return errors.Join(errs...)
+}
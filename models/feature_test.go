@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/michimani/evidentlylocal/models"
@@ -264,3 +265,89 @@ func Test_Feature_GetDefaultValue(t *testing.T) {
 		})
 	}
 }
+
+func Test_Feature_Validate(t *testing.T) {
+	t.Parallel()
+
+	variations := []models.Variation{
+		{Name: "on", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+		{Name: "off", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+	}
+
+	cases := []struct {
+		name    string
+		feature *models.Feature
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			feature: &models.Feature{
+				ValueType:        types.FeatureValueTypeBoolean,
+				DefaultVariation: "off",
+				EntityOverrides:  map[string]string{"user-1": "on"},
+				Variations:       variations,
+			},
+		},
+		{
+			name: "unknown value type",
+			feature: &models.Feature{
+				ValueType:        "NUMBER",
+				DefaultVariation: "off",
+				Variations:       variations,
+			},
+			wantErr: true,
+		},
+		{
+			name: "variation without value of the type",
+			feature: &models.Feature{
+				ValueType:        types.FeatureValueTypeString,
+				DefaultVariation: "off",
+				Variations:       variations,
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated variation",
+			feature: &models.Feature{
+				ValueType:        types.FeatureValueTypeBoolean,
+				DefaultVariation: "off",
+				Variations:       append(slices.Clone(variations), variations[0]),
+			},
+			wantErr: true,
+		},
+		{
+			name: "default variation is not defined",
+			feature: &models.Feature{
+				ValueType:        types.FeatureValueTypeBoolean,
+				DefaultVariation: "unknown",
+				Variations:       variations,
+			},
+			wantErr: true,
+		},
+		{
+			name: "variation of override is not defined",
+			feature: &models.Feature{
+				ValueType:        types.FeatureValueTypeBoolean,
+				DefaultVariation: "off",
+				EntityOverrides:  map[string]string{"user-1": "unknown"},
+				Variations:       variations,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			tt.Parallel()
+			asst := assert.New(tt)
+
+			err := c.feature.Validate()
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+			asst.NoError(err)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type Launch struct {
+	Description               string                     `json:"description,omitempty"`
+	Groups                    []LaunchGroup              `json:"groups"`
+	Name                      string                     `json:"name"`
+	Project                   string                     `json:"project"`
+	RandomizationSalt         string                     `json:"randomizationSalt,omitempty"`
+	ScheduledSplitsDefinition *ScheduledSplitsDefinition `json:"scheduledSplitsDefinition,omitempty"`
+	Status                    string                     `json:"status,omitempty"`
+	Tags                      map[string]string          `json:"tags,omitempty"`
+	Type                      string                     `json:"type,omitempty"`
+}
+
+type LaunchGroup struct {
+	Description       string            `json:"description,omitempty"`
+	FeatureVariations map[string]string `json:"featureVariations"`
+	Name              string            `json:"name"`
+}
+
+type ScheduledSplitsDefinition struct {
+	Steps []ScheduledSplit `json:"steps"`
+}
+
+type ScheduledSplit struct {
+	GroupWeights     map[string]int64  `json:"groupWeights"`
+	SegmentOverrides []SegmentOverride `json:"segmentOverrides,omitempty"`
+	StartTime        time.Time         `json:"startTime"`
+}
+
+type SegmentOverride struct {
+	EvaluationOrder int64            `json:"evaluationOrder"`
+	Segment         string           `json:"segment"`
+	Weights         map[string]int64 `json:"weights"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type Project struct {
+	Description string            `json:"description,omitempty"`
+	Name        string            `json:"name"`
+	Status      string            `json:"status,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
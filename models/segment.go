@@ -0,0 +1,8 @@
+package models
+
+type Segment struct {
+	Description string            `json:"description,omitempty"`
+	Name        string            `json:"name"`
+	Pattern     string            `json:"pattern"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
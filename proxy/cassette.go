@@ -0,0 +1,156 @@
+// Package proxy forwards requests to an upstream Evidently endpoint and records them to a cassette,
+// so that the recorded responses can be replayed offline.
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michimani/evidentlylocal/internal"
+)
+
+// Modes of the proxy.
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// Key is the matching rule of interactions. For BatchEvaluateFeature, Feature and EntityID are
+// features and entity IDs of all requests joined by commas, so that the same batch is matched.
+type Key struct {
+	Operation string `json:"operation"`
+	Project   string `json:"project,omitempty"`
+	Feature   string `json:"feature,omitempty"`
+	EntityID  string `json:"entityId,omitempty"`
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("operation=%s project=%s feature=%s entityId=%s", k.Operation, k.Project, k.Feature, k.EntityID)
+}
+
+// Interaction is a pair of a request and a response.
+type Interaction struct {
+	Key
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Cassette is a file of interactions. An interaction is replaced when the same key is recorded again.
+type Cassette struct {
+	path string
+
+	mu           sync.RWMutex
+	interactions []Interaction
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette loads the cassette file. If mustExist is false, a missing file is an empty cassette.
+func LoadCassette(path string, mustExist bool) (*Cassette, error) {
+	c := &Cassette{
+		path:         path,
+		interactions: []Interaction{},
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !mustExist {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f := cassetteFile{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to load cassette %s: %w", path, err)
+	}
+
+	if f.Interactions != nil {
+		c.interactions = f.Interactions
+	}
+
+	return c, nil
+}
+
+// Find returns the interaction that matches the key.
+func (c *Cassette) Find(key Key) (Interaction, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, i := range c.interactions {
+		if i.Key == key {
+			return i, true
+		}
+	}
+
+	return Interaction{}, false
+}
+
+// Interactions returns all interactions in the cassette.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	res := make([]Interaction, len(c.interactions))
+	copy(res, c.interactions)
+
+	return res
+}
+
+// Record adds the interaction and saves the cassette, so that recorded interactions are kept
+// even if the server is killed.
+func (c *Cassette) Record(i Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	replaced := false
+	for n := range c.interactions {
+		if c.interactions[n].Key == i.Key {
+			c.interactions[n] = i
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		c.interactions = append(c.interactions, i)
+	}
+
+	return c.save()
+}
+
+// save writes the cassette atomically, so that the file is not broken by a crash.
+func (c *Cassette) save() error {
+	b, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return internal.WriteFileAtomic(c.path, append(b, '\n'))
+}
+
+// JoinKeys joins features or entity IDs of a batch for Key.
+func JoinKeys(values []string) string {
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,98 @@
+package proxy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func interaction(entityID, body string) proxy.Interaction {
+	return proxy.Interaction{
+		Key:      proxy.Key{Operation: "EvaluateFeature", Project: "p", Feature: "f", EntityID: entityID},
+		Request:  proxy.Request{Method: "POST", Path: "/projects/p/evaluations/f", Body: `{"entityId":"` + entityID + `"}`},
+		Response: proxy.Response{Status: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: body},
+	}
+}
+
+func Test_Cassette(t *testing.T) {
+	asst := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "cassettes", "evidently.json")
+
+	c, err := proxy.LoadCassette(path, false)
+	asst.NoError(err)
+	asst.Empty(c.Interactions())
+
+	asst.NoError(c.Record(interaction("user-1", `{"variation":"Off"}`)))
+	asst.NoError(c.Record(interaction("user-2", `{"variation":"On"}`)))
+	// the same key is replaced
+	asst.NoError(c.Record(interaction("user-1", `{"variation":"On"}`)))
+	asst.Len(c.Interactions(), 2)
+
+	loaded, err := proxy.LoadCassette(path, true)
+	asst.NoError(err)
+	asst.Equal(c.Interactions(), loaded.Interactions())
+
+	i, ok := loaded.Find(proxy.Key{Operation: "EvaluateFeature", Project: "p", Feature: "f", EntityID: "user-1"})
+	asst.True(ok)
+	asst.Equal(`{"variation":"On"}`, i.Response.Body)
+
+	_, ok = loaded.Find(proxy.Key{Operation: "EvaluateFeature", Project: "p", Feature: "f", EntityID: "user-3"})
+	asst.False(ok)
+
+	// no temporary files are left
+	entries, err := os.ReadDir(filepath.Dir(path))
+	asst.NoError(err)
+	asst.Len(entries, 1)
+}
+
+func Test_LoadCassette(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		path      string
+		mustExist bool
+		wantErr   bool
+	}{
+		{
+			name:      "not exists",
+			path:      filepath.Join(dir, "not-exists.json"),
+			mustExist: false,
+		},
+		{
+			name:      "must exist",
+			path:      filepath.Join(dir, "not-exists.json"),
+			mustExist: true,
+			wantErr:   true,
+		},
+		{
+			name:    "invalid",
+			path:    invalid,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			got, err := proxy.LoadCassette(c.path, c.mustExist)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Empty(got.Interactions())
+		})
+	}
+}
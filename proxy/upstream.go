@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const signingName = "evidently"
+
+// dataplaneOperations are operations that AWS serves on the "dataplane." host.
+var dataplaneOperations = []string{"EvaluateFeature", "BatchEvaluateFeature"}
+
+// headers are response headers that are recorded.
+var headers = []string{"Content-Type", "x-amzn-ErrorType"}
+
+// Upstream is a client of the upstream Evidently endpoint.
+type Upstream struct {
+	url         *url.URL
+	region      string
+	credentials aws.CredentialsProvider
+	client      *http.Client
+	signer      *v4.Signer
+}
+
+// NewUpstream returns a client of the upstream endpoint. If credentials is not nil, requests are signed
+// with SigV4 again, because the signature of the client is for the host of Evidently-Local.
+// Dataplane operations (e.g. EvaluateFeature) are sent to the "dataplane." host of AWS endpoints in the same way as AWS SDKs.
+func NewUpstream(endpoint, region string, credentials aws.CredentialsProvider) (*Upstream, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("upstream must be an http or https URL: %s", endpoint)
+	}
+
+	return &Upstream{
+		url:         u,
+		region:      region,
+		credentials: credentials,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		signer:      v4.NewSigner(),
+	}, nil
+}
+
+// EnvCredentials returns static credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN,
+// or nil if they are not set.
+func EnvCredentials(getenv func(string) string) aws.CredentialsProvider {
+	creds := aws.Credentials{
+		AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		Source:          "EnvironmentVariables",
+	}
+
+	if len(creds.AccessKeyID) == 0 || len(creds.SecretAccessKey) == 0 {
+		return nil
+	}
+
+	return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return creds, nil
+	})
+}
+
+// Forward sends the request to the upstream, and returns the response.
+func (u *Upstream) Forward(ctx context.Context, operation string, r *http.Request, body []byte) (Response, error) {
+	target := *u.url
+	target.Path = strings.TrimSuffix(u.url.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+	if isDataplane(operation) && strings.HasSuffix(target.Hostname(), ".amazonaws.com") {
+		target.Host = "dataplane." + target.Host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+
+	if ct := r.Header.Get("Content-Type"); len(ct) > 0 {
+		req.Header.Set("Content-Type", ct)
+	}
+
+	if u.credentials != nil {
+		creds, err := u.credentials.Retrieve(ctx)
+		if err != nil {
+			return Response{}, err
+		}
+
+		hash := sha256.Sum256(body)
+		if err := u.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), signingName, u.region, time.Now()); err != nil {
+			return Response{}, err
+		}
+	}
+
+	res, err := u.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	recorded := Response{
+		Status:  res.StatusCode,
+		Headers: map[string]string{},
+		Body:    string(b),
+	}
+	for _, h := range headers {
+		if v := res.Header.Get(h); len(v) > 0 {
+			recorded.Headers[h] = v
+		}
+	}
+
+	return recorded, nil
+}
+
+func isDataplane(operation string) bool {
+	return slices.Contains(dataplaneOperations, operation)
+}
@@ -0,0 +1,113 @@
+package proxy_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Upstream_Forward(t *testing.T) {
+	env := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	}
+
+	cases := []struct {
+		name       string
+		getenv     func(string) string
+		wantSigned bool
+	}{
+		{
+			name:       "signed with credentials in the environment",
+			getenv:     func(key string) string { return env[key] },
+			wantSigned: true,
+		},
+		{
+			name:   "not signed without credentials",
+			getenv: func(key string) string { return "" },
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			var got *http.Request
+			var gotBody string
+			standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("x-amzn-ErrorType", "ResourceNotFoundException")
+				w.Header().Set("X-Other", "not recorded")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"not found"}`))
+			}))
+			defer standIn.Close()
+
+			u, err := proxy.NewUpstream(standIn.URL, "us-east-1", proxy.EnvCredentials(c.getenv))
+			asst.NoError(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/projects/p/evaluations/f?x=1", nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "signature for evidently-local")
+
+			res, err := u.Forward(context.Background(), "EvaluateFeature", req, []byte(`{"entityId":"user-1"}`))
+			if !asst.NoError(err) {
+				return
+			}
+
+			asst.Equal(proxy.Response{
+				Status:  http.StatusNotFound,
+				Headers: map[string]string{"Content-Type": "application/json", "x-amzn-ErrorType": "ResourceNotFoundException"},
+				Body:    `{"message":"not found"}`,
+			}, res)
+
+			asst.Equal("/projects/p/evaluations/f", got.URL.Path)
+			asst.Equal("x=1", got.URL.RawQuery)
+			asst.Equal(`{"entityId":"user-1"}`, gotBody)
+			if c.wantSigned {
+				asst.True(strings.HasPrefix(got.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIAEXAMPLE/"))
+				asst.Contains(got.Header.Get("Authorization"), "/us-east-1/evidently/aws4_request")
+			} else {
+				asst.Empty(got.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func Test_NewUpstream(t *testing.T) {
+	cases := []struct {
+		name     string
+		endpoint string
+		wantErr  bool
+	}{
+		{name: "https", endpoint: "https://evidently.us-east-1.amazonaws.com"},
+		{name: "http", endpoint: "http://localhost:2306"},
+		{name: "no scheme", endpoint: "localhost:2306", wantErr: true},
+		{name: "unsupported scheme", endpoint: "ftp://localhost", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			u, err := proxy.NewUpstream(c.endpoint, "us-east-1", nil)
+			if c.wantErr {
+				asst.Nil(u)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(u)
+		})
+	}
+}
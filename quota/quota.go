@@ -0,0 +1,154 @@
+// Package quota checks resources against limits of Evidently, so that definitions that cannot be deployed to AWS
+// are found locally.
+package quota
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/types"
+)
+
+// Limits are limits of resources. Nil values mean the limits of AWS in Default, and 0 allows no resources.
+// Limits of a resource (e.g. variations per feature) are reported as ValidationException as the API does,
+// and limits of the number of resources in a project are reported as ServiceQuotaExceededException.
+type Limits struct {
+	VariationsPerFeature      *int `json:"variationsPerFeature,omitempty" yaml:"variationsPerFeature,omitempty"`
+	EntityOverridesPerFeature *int `json:"entityOverridesPerFeature,omitempty" yaml:"entityOverridesPerFeature,omitempty"`
+	FeaturesPerProject        *int `json:"featuresPerProject,omitempty" yaml:"featuresPerProject,omitempty"`
+	LaunchesPerProject        *int `json:"launchesPerProject,omitempty" yaml:"launchesPerProject,omitempty"`
+	ExperimentsPerProject     *int `json:"experimentsPerProject,omitempty" yaml:"experimentsPerProject,omitempty"`
+	GroupsPerLaunch           *int `json:"groupsPerLaunch,omitempty" yaml:"groupsPerLaunch,omitempty"`
+	TreatmentsPerExperiment   *int `json:"treatmentsPerExperiment,omitempty" yaml:"treatmentsPerExperiment,omitempty"`
+	// VariationValueLength is the maximum number of characters of string values of variations.
+	VariationValueLength *int `json:"variationValueLength,omitempty" yaml:"variationValueLength,omitempty"`
+}
+
+// Limit returns n as a value of Limits.
+func Limit(n int) *int {
+	return &n
+}
+
+// Default returns the limits of AWS.
+func Default() Limits {
+	return Limits{
+		VariationsPerFeature:      Limit(5),
+		EntityOverridesPerFeature: Limit(2500),
+		FeaturesPerProject:        Limit(100),
+		LaunchesPerProject:        Limit(100),
+		ExperimentsPerProject:     Limit(100),
+		GroupsPerLaunch:           Limit(5),
+		TreatmentsPerExperiment:   Limit(5),
+		VariationValueLength:      Limit(512),
+	}
+}
+
+// WithDefaults returns the limits whose nil values are replaced by the defaults.
+func (l Limits) WithDefaults() Limits {
+	d := Default()
+	fill := func(v **int, def *int) {
+		if *v == nil {
+			*v = def
+		}
+	}
+
+	fill(&l.VariationsPerFeature, d.VariationsPerFeature)
+	fill(&l.EntityOverridesPerFeature, d.EntityOverridesPerFeature)
+	fill(&l.FeaturesPerProject, d.FeaturesPerProject)
+	fill(&l.LaunchesPerProject, d.LaunchesPerProject)
+	fill(&l.ExperimentsPerProject, d.ExperimentsPerProject)
+	fill(&l.GroupsPerLaunch, d.GroupsPerLaunch)
+	fill(&l.TreatmentsPerExperiment, d.TreatmentsPerExperiment)
+	fill(&l.VariationValueLength, d.VariationValueLength)
+
+	return l
+}
+
+// Validate validates the limits.
+func (l Limits) Validate() error {
+	for _, v := range []*int{
+		l.VariationsPerFeature, l.EntityOverridesPerFeature, l.FeaturesPerProject, l.LaunchesPerProject,
+		l.ExperimentsPerProject, l.GroupsPerLaunch, l.TreatmentsPerExperiment, l.VariationValueLength,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("quota limits must not be negative: %d", *v)
+		}
+	}
+
+	return nil
+}
+
+// Violation is a resource that exceeds a limit.
+type Violation struct {
+	// Resource is the path of the resource, e.g. projects/p/features/f
+	Resource  string          `json:"resource"`
+	Quota     string          `json:"quota"`
+	Limit     int             `json:"limit"`
+	Actual    int             `json:"actual"`
+	ErrorType types.ErrorType `json:"errorType"`
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s: %s is %d, but the limit is %d", v.ErrorType, v.Resource, v.Quota, v.Actual, v.Limit)
+}
+
+// Check returns all violations of resources in the bundle, sorted by resources.
+func Check(bundle *models.Bundle, limits Limits) []Violation {
+	limits = limits.WithDefaults()
+	vs := []Violation{}
+
+	check := func(resource, quota string, actual int, limit *int, errorType types.ErrorType) {
+		if actual > *limit {
+			vs = append(vs, Violation{Resource: resource, Quota: quota, Limit: *limit, Actual: actual, ErrorType: errorType})
+		}
+	}
+
+	features := map[string]int{}
+	for _, f := range bundle.Features {
+		features[f.Project]++
+
+		resource := "projects/" + f.Project + "/features/" + f.Name
+		check(resource, "variationsPerFeature", len(f.Variations), limits.VariationsPerFeature, types.ErrorTypeValidationException)
+		check(resource, "entityOverridesPerFeature", len(f.EntityOverrides), limits.EntityOverridesPerFeature, types.ErrorTypeValidationException)
+
+		for _, v := range f.Variations {
+			if s, ok := v.Value[types.VariableValueTypeString].(string); ok {
+				check(resource+"/variations/"+v.Name, "variationValueLength", utf8.RuneCountInString(s), limits.VariationValueLength, types.ErrorTypeValidationException)
+			}
+		}
+	}
+
+	launches := map[string]int{}
+	for _, l := range bundle.Launches {
+		launches[l.Project]++
+		check("projects/"+l.Project+"/launches/"+l.Name, "groupsPerLaunch", len(l.Groups), limits.GroupsPerLaunch, types.ErrorTypeValidationException)
+	}
+
+	experiments := map[string]int{}
+	for _, e := range bundle.Experiments {
+		experiments[e.Project]++
+		check("projects/"+e.Project+"/experiments/"+e.Name, "treatmentsPerExperiment", len(e.Treatments), limits.TreatmentsPerExperiment, types.ErrorTypeValidationException)
+	}
+
+	for project, n := range features {
+		check("projects/"+project, "featuresPerProject", n, limits.FeaturesPerProject, types.ErrorTypeServiceQuotaExceededException)
+	}
+	for project, n := range launches {
+		check("projects/"+project, "launchesPerProject", n, limits.LaunchesPerProject, types.ErrorTypeServiceQuotaExceededException)
+	}
+	for project, n := range experiments {
+		check("projects/"+project, "experimentsPerProject", n, limits.ExperimentsPerProject, types.ErrorTypeServiceQuotaExceededException)
+	}
+
+	slices.SortStableFunc(vs, func(a, b Violation) int {
+		if c := strings.Compare(a.Resource, b.Resource); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Quota, b.Quota)
+	})
+
+	return vs
+}
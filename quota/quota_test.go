@@ -0,0 +1,130 @@
+package quota_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Check(t *testing.T) {
+	feature := func(project, name string, variations int) models.Feature {
+		f := models.Feature{Project: project, Name: name, EntityOverrides: models.EntityOverride{"user-1": "v0"}}
+		for i := 0; i < variations; i++ {
+			f.Variations = append(f.Variations, models.Variation{Name: "v" + string(rune('0'+i)), Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}})
+		}
+		return f
+	}
+
+	cases := []struct {
+		name   string
+		bundle *models.Bundle
+		limits quota.Limits
+		expect []quota.Violation
+	}{
+		{
+			name: "within default limits",
+			bundle: &models.Bundle{
+				Features:    []models.Feature{feature("p", "f1", 5), feature("p", "f2", 2)},
+				Launches:    []models.Launch{{Project: "p", Name: "l", Groups: make([]models.LaunchGroup, 5)}},
+				Experiments: []models.Experiment{{Project: "p", Name: "e", Treatments: make([]models.Treatment, 2)}},
+			},
+			expect: []quota.Violation{},
+		},
+		{
+			name: "too many variations by default",
+			bundle: &models.Bundle{
+				Features: []models.Feature{feature("p", "f", 6)},
+			},
+			expect: []quota.Violation{
+				{Resource: "projects/p/features/f", Quota: "variationsPerFeature", Limit: 5, Actual: 6, ErrorType: types.ErrorTypeValidationException},
+			},
+		},
+		{
+			name: "lowered limits",
+			bundle: &models.Bundle{
+				Features:    []models.Feature{feature("p", "f1", 2), feature("p", "f2", 1), feature("q", "f", 1)},
+				Launches:    []models.Launch{{Project: "p", Name: "l", Groups: make([]models.LaunchGroup, 3)}},
+				Experiments: []models.Experiment{{Project: "p", Name: "e", Treatments: make([]models.Treatment, 3)}},
+			},
+			limits: quota.Limits{
+				VariationsPerFeature:      quota.Limit(1),
+				EntityOverridesPerFeature: quota.Limit(1),
+				FeaturesPerProject:        quota.Limit(1),
+				GroupsPerLaunch:           quota.Limit(2),
+				TreatmentsPerExperiment:   quota.Limit(2),
+			},
+			expect: []quota.Violation{
+				{Resource: "projects/p", Quota: "featuresPerProject", Limit: 1, Actual: 2, ErrorType: types.ErrorTypeServiceQuotaExceededException},
+				{Resource: "projects/p/experiments/e", Quota: "treatmentsPerExperiment", Limit: 2, Actual: 3, ErrorType: types.ErrorTypeValidationException},
+				{Resource: "projects/p/features/f1", Quota: "variationsPerFeature", Limit: 1, Actual: 2, ErrorType: types.ErrorTypeValidationException},
+				{Resource: "projects/p/launches/l", Quota: "groupsPerLaunch", Limit: 2, Actual: 3, ErrorType: types.ErrorTypeValidationException},
+			},
+		},
+		{
+			name: "long string value",
+			bundle: &models.Bundle{
+				Features: []models.Feature{{
+					Project: "p",
+					Name:    "f",
+					Variations: []models.Variation{
+						{Name: "short", Value: map[types.VariableValueType]any{types.VariableValueTypeString: strings.Repeat("あ", 4)}},
+						{Name: "long", Value: map[types.VariableValueType]any{types.VariableValueTypeString: strings.Repeat("a", 5)}},
+					},
+				}},
+			},
+			limits: quota.Limits{VariationValueLength: quota.Limit(4)},
+			expect: []quota.Violation{
+				{Resource: "projects/p/features/f/variations/long", Quota: "variationValueLength", Limit: 4, Actual: 5, ErrorType: types.ErrorTypeValidationException},
+			},
+		},
+		{
+			name: "zero limits allow no resources",
+			bundle: &models.Bundle{
+				Features: []models.Feature{feature("p", "f", 1)},
+			},
+			limits: quota.Limits{FeaturesPerProject: quota.Limit(0), EntityOverridesPerFeature: quota.Limit(0)},
+			expect: []quota.Violation{
+				{Resource: "projects/p", Quota: "featuresPerProject", Limit: 0, Actual: 1, ErrorType: types.ErrorTypeServiceQuotaExceededException},
+				{Resource: "projects/p/features/f", Quota: "entityOverridesPerFeature", Limit: 0, Actual: 1, ErrorType: types.ErrorTypeValidationException},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			asst.Equal(c.expect, quota.Check(c.bundle, c.limits))
+		})
+	}
+}
+
+func Test_Limits_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		limits  quota.Limits
+		wantErr bool
+	}{
+		{name: "nil values are defaults", limits: quota.Limits{}},
+		{name: "lowered", limits: quota.Limits{FeaturesPerProject: quota.Limit(1)}},
+		{name: "zero", limits: quota.Limits{FeaturesPerProject: quota.Limit(0)}},
+		{name: "negative", limits: quota.Limits{GroupsPerLaunch: quota.Limit(-1)}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			err := c.limits.Validate()
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+			asst.NoError(err)
+		})
+	}
+}
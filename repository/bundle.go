@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+)
+
+var _ FeatureRepository = (*FeatureRepositoryWithBundleFile)(nil)
+
+// Formats of bundle files.
+const (
+	BundleFormatJSON = "json"
+	BundleFormatYAML = "yaml"
+)
+
+// FeatureRepositoryWithBundleFile is a FeatureRepository that loads all resources of an environment
+// from a single JSON or YAML bundle file. The bundle file is loaded only once when it is created.
+type FeatureRepositoryWithBundleFile struct {
+	bundleFile string
+	bundle     *models.Bundle
+	l          logger.Logger
+}
+
+func NewFeatureRepositoryWithBundleFile(bundleFile string, l logger.Logger) (*FeatureRepositoryWithBundleFile, error) {
+	if len(bundleFile) == 0 {
+		return nil, errors.New("bundleFile is empty")
+	}
+
+	if l == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	bundle, err := loadBundleFile(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateBundle(bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle file %s: %w", bundleFile, err)
+	}
+
+	return &FeatureRepositoryWithBundleFile{
+		bundleFile: bundleFile,
+		bundle:     bundle,
+		l:          l,
+	}, nil
+}
+
+// NewFeatureRepositoryWithBundle returns a repository that has the bundle in memory, e.g. a bundle built in Go code.
+// The bundle is copied, so changes to it after this call do not affect the repository.
+func NewFeatureRepositoryWithBundle(bundle *models.Bundle, l logger.Logger) (*FeatureRepositoryWithBundleFile, error) {
+	if bundle == nil {
+		return nil, errors.New("bundle is nil")
+	}
+
+	if l == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	if err := validateBundle(bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	return &FeatureRepositoryWithBundleFile{
+		bundle: &models.Bundle{
+			Projects:    slices.Clone(bundle.Projects),
+			Features:    cloneFeatures(bundle.Features),
+			Launches:    slices.Clone(bundle.Launches),
+			Experiments: slices.Clone(bundle.Experiments),
+			Segments:    slices.Clone(bundle.Segments),
+		},
+		l: l,
+	}, nil
+}
+
+func (r *FeatureRepositoryWithBundleFile) Get(project, featureName string) (*models.Feature, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithBundleFile is nil")
+	}
+
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	if err := internal.ValidateFeatureName(featureName); err != nil {
+		return nil, err
+	}
+
+	if !r.hasProject(project) {
+		r.l.Error("project not found in bundle file", nil)
+		return nil, newNotFoundError("Project not found: %s", project)
+	}
+
+	for _, f := range r.bundle.Features {
+		if f.Project == project && f.Name == featureName {
+			return f.Clone(), nil
+		}
+	}
+
+	r.l.Error("feature not found in bundle file", nil)
+	return nil, newNotFoundError("Feature not found: %s", featureName)
+}
+
+func (r *FeatureRepositoryWithBundleFile) List(project string) ([]*models.Feature, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithBundleFile is nil")
+	}
+
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	if !r.hasProject(project) {
+		r.l.Error("project not found in bundle file", nil)
+		return nil, fmt.Errorf("Project not found: %s", project)
+	}
+
+	res := []*models.Feature{}
+	for _, f := range r.bundle.Features {
+		if f.Project == project {
+			res = append(res, f.Clone())
+		}
+	}
+
+	return res, nil
+}
+
+// Snapshot returns a copy of the loaded bundle.
+func (r *FeatureRepositoryWithBundleFile) Snapshot() (*models.Bundle, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithBundleFile is nil")
+	}
+
+	return &models.Bundle{
+		Projects:    slices.Clone(r.bundle.Projects),
+		Features:    cloneFeatures(r.bundle.Features),
+		Launches:    slices.Clone(r.bundle.Launches),
+		Experiments: slices.Clone(r.bundle.Experiments),
+		Segments:    slices.Clone(r.bundle.Segments),
+	}, nil
+}
+
+// cloneFeatures returns deep copies of the features, so that callers cannot change features in the bundle.
+func cloneFeatures(features []models.Feature) []models.Feature {
+	if features == nil {
+		return nil
+	}
+
+	res := make([]models.Feature, len(features))
+	for i := range features {
+		res[i] = *features[i].Clone()
+	}
+
+	return res
+}
+
+func (r *FeatureRepositoryWithBundleFile) hasProject(project string) bool {
+	for _, p := range r.bundle.Projects {
+		if p.Name == project {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadBundleFile loads a bundle file. The format is decided by the file extension.
+func loadBundleFile(bundleFile string) (*models.Bundle, error) {
+	b, err := os.ReadFile(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	unmarshal := json.Unmarshal
+	switch filepath.Ext(bundleFile) {
+	case ".json":
+		// noop
+	case ".yaml", ".yml":
+		unmarshal = unmarshalYAML
+	default:
+		return nil, fmt.Errorf("unsupported bundle file format: %s", bundleFile)
+	}
+
+	bundle := &models.Bundle{}
+	if err := unmarshal(b, bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle file %s: %w", bundleFile, err)
+	}
+
+	return bundle, nil
+}
+
+// MarshalBundle marshals the bundle in the format that can be loaded as a bundle file.
+func MarshalBundle(bundle *models.Bundle, format string) ([]byte, error) {
+	if bundle == nil {
+		return nil, errors.New("bundle is nil")
+	}
+
+	switch format {
+	case BundleFormatJSON:
+		b, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case BundleFormatYAML:
+		return marshalYAML(bundle)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+}
+
+// validateBundle validates names of all resources in the bundle,
+// and that each resource belongs to a project defined in the bundle.
+func validateBundle(bundle *models.Bundle) error {
+	errs := []error{}
+
+	projects := map[string]bool{}
+	for _, p := range bundle.Projects {
+		if err := internal.ValidateProjectName(p.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if projects[p.Name] {
+			errs = append(errs, fmt.Errorf("project %s is defined more than once", p.Name))
+		}
+		projects[p.Name] = true
+	}
+
+	checkProjectResource := func(kind, project, name string, validate func(string) error, seen map[string]bool) {
+		if err := validate(name); err != nil {
+			errs = append(errs, err)
+			return
+		}
+
+		if !projects[project] {
+			errs = append(errs, fmt.Errorf("%s %s belongs to project %s that is not defined", kind, name, project))
+			return
+		}
+
+		if seen[project+"/"+name] {
+			errs = append(errs, fmt.Errorf("%s %s is defined more than once in project %s", kind, name, project))
+		}
+		seen[project+"/"+name] = true
+	}
+
+	features := map[string]bool{}
+	for _, f := range bundle.Features {
+		checkProjectResource("feature", f.Project, f.Name, internal.ValidateFeatureName, features)
+	}
+
+	launches := map[string]bool{}
+	for _, l := range bundle.Launches {
+		checkProjectResource("launch", l.Project, l.Name, internal.ValidateLaunchName, launches)
+	}
+
+	experiments := map[string]bool{}
+	for _, e := range bundle.Experiments {
+		checkProjectResource("experiment", e.Project, e.Name, internal.ValidateExperimentName, experiments)
+	}
+
+	segments := map[string]bool{}
+	for _, s := range bundle.Segments {
+		if err := internal.ValidateSegmentName(s.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if segments[s.Name] {
+			errs = append(errs, fmt.Errorf("segment %s is defined more than once", s.Name))
+		}
+		segments[s.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
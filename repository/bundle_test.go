@@ -0,0 +1,480 @@
+package repository_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewFeatureRepositoryWithBundleFile(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(os.Stdout)
+
+	cases := []struct {
+		name       string
+		bundleFile string
+		l          logger.Logger
+		wantErr    bool
+	}{
+		{
+			name:       "bundleFile is empty",
+			bundleFile: "",
+			l:          testLogger,
+			wantErr:    true,
+		},
+		{
+			name:       "logger is nil",
+			bundleFile: "../testdata/bundles/test-bundle.yaml",
+			l:          nil,
+			wantErr:    true,
+		},
+		{
+			name:       "bundle file not found",
+			bundleFile: "../testdata/bundles/not-exists-bundle.yaml",
+			l:          testLogger,
+			wantErr:    true,
+		},
+		{
+			name:       "unsupported format",
+			bundleFile: "../testdata/projects/has-no-feature-project/features/.gitkeep",
+			l:          testLogger,
+			wantErr:    true,
+		},
+		{
+			name:       "unparsable bundle file",
+			bundleFile: "../testdata/bundles/unparsable-bundle.yaml",
+			l:          testLogger,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid bundle file",
+			bundleFile: "../testdata/bundles/invalid-bundle.yaml",
+			l:          testLogger,
+			wantErr:    true,
+		},
+		{
+			name:       "success: yaml",
+			bundleFile: "../testdata/bundles/test-bundle.yaml",
+			l:          testLogger,
+			wantErr:    false,
+		},
+		{
+			name:       "success: json",
+			bundleFile: "../testdata/bundles/test-bundle.json",
+			l:          testLogger,
+			wantErr:    false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := repository.NewFeatureRepositoryWithBundleFile(c.bundleFile, c.l)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(got)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithBundleFile_Get(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(os.Stdout)
+	testRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		repo        *repository.FeatureRepositoryWithBundleFile
+		project     string
+		featureName string
+		wantErr     bool
+		// wantNotFound is true if the error matches ErrFeatureNotFound
+		wantNotFound bool
+		expect       *models.Feature
+	}{
+		{
+			name:        "repo is nil",
+			repo:        nil,
+			project:     "test-project",
+			featureName: "test-feature-1",
+			wantErr:     true,
+			expect:      nil,
+		},
+		{
+			name:        "invalid project name",
+			repo:        testRepo,
+			project:     "..",
+			featureName: "test-feature-1",
+			wantErr:     true,
+			expect:      nil,
+		},
+		{
+			name:         "project not found",
+			repo:         testRepo,
+			project:      "not-exists-project",
+			featureName:  "test-feature-1",
+			wantErr:      true,
+			wantNotFound: true,
+			expect:       nil,
+		},
+		{
+			name:         "feature not found",
+			repo:         testRepo,
+			project:      "test-project",
+			featureName:  "not-exists-feature",
+			wantErr:      true,
+			wantNotFound: true,
+			expect:       nil,
+		},
+		{
+			name:        "success",
+			repo:        testRepo,
+			project:     "test-project",
+			featureName: "test-feature-1",
+			wantErr:     false,
+			expect: &models.Feature{
+				Name:             "test-feature-1",
+				DefaultVariation: "False",
+				EntityOverrides: models.EntityOverride{
+					"force-true": "True",
+				},
+				Project:   "test-project",
+				Status:    "AVAILABLE",
+				ValueType: "BOOLEAN",
+				Variations: []models.Variation{
+					{
+						Name: "True", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeBool: true,
+						},
+					},
+					{
+						Name: "False", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeBool: false,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.Get(c.project, c.featureName)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				asst.Equal(c.wantNotFound, errors.Is(err, repository.ErrFeatureNotFound))
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *got)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithBundleFile_List(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(os.Stdout)
+	testRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		repo        *repository.FeatureRepositoryWithBundleFile
+		project     string
+		wantErr     bool
+		expectNames []string
+	}{
+		{
+			name:    "repo is nil",
+			repo:    nil,
+			project: "test-project",
+			wantErr: true,
+		},
+		{
+			name:    "project not found",
+			repo:    testRepo,
+			project: "not-exists-project",
+			wantErr: true,
+		},
+		{
+			name:        "success",
+			repo:        testRepo,
+			project:     "test-project",
+			wantErr:     false,
+			expectNames: []string{"test-feature-1", "test-feature-2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.List(c.project)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			names := []string{}
+			for _, f := range got {
+				names = append(names, f.Name)
+			}
+			asst.Equal(c.expectNames, names)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithBundleFile_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		repo    *repository.FeatureRepositoryWithBundleFile
+		wantErr bool
+	}{
+		{
+			name:    "repo is nil",
+			repo:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "success",
+			repo:    testRepo,
+			wantErr: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.Snapshot()
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.Len(got.Projects, 1)
+			asst.Len(got.Features, 2)
+			asst.Len(got.Launches, 1)
+			asst.Len(got.Experiments, 1)
+			asst.Len(got.Segments, 1)
+		})
+	}
+}
+
+// Test_FeatureRepositoryWithBundleFile_Copy checks that changes of returned features do not change features in the repository.
+func Test_FeatureRepositoryWithBundleFile_Copy(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+
+	expected, err := testRepo.Get("test-project", "test-feature-1")
+	assert.NoError(t, err)
+
+	change := func(f *models.Feature) {
+		f.EntityOverrides["force-true"] = "False"
+		f.Tags = map[string]string{"changed": "true"}
+		f.Variations[0].Name = "changed"
+		f.Variations[0].Value[types.VariableValueTypeBool] = false
+	}
+
+	cases := []struct {
+		name string
+		get  func() (*models.Feature, error)
+	}{
+		{
+			name: "Get",
+			get: func() (*models.Feature, error) {
+				return testRepo.Get("test-project", "test-feature-1")
+			},
+		},
+		{
+			name: "List",
+			get: func() (*models.Feature, error) {
+				features, err := testRepo.List("test-project")
+				if err != nil {
+					return nil, err
+				}
+				return features[0], nil
+			},
+		},
+		{
+			name: "Snapshot",
+			get: func() (*models.Feature, error) {
+				bundle, err := testRepo.Snapshot()
+				if err != nil {
+					return nil, err
+				}
+				return &bundle.Features[0], nil
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+
+			f, err := c.get()
+			asst.NoError(err)
+			change(f)
+
+			got, err := testRepo.Get("test-project", "test-feature-1")
+			asst.NoError(err)
+			asst.Equal(expected, got)
+		})
+	}
+}
+
+func Test_MarshalBundle(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+	testBundle, err := testRepo.Snapshot()
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		bundle  *models.Bundle
+		format  string
+		wantErr bool
+	}{
+		{
+			name:    "bundle is nil",
+			bundle:  nil,
+			format:  repository.BundleFormatJSON,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			bundle:  testBundle,
+			format:  "toml",
+			wantErr: true,
+		},
+		{
+			name:    "success: json",
+			bundle:  testBundle,
+			format:  repository.BundleFormatJSON,
+			wantErr: false,
+		},
+		{
+			name:    "success: yaml",
+			bundle:  testBundle,
+			format:  repository.BundleFormatYAML,
+			wantErr: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := repository.MarshalBundle(c.bundle, c.format)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+
+			// the marshaled bundle can be loaded again
+			bundleFile := filepath.Join(tt.TempDir(), "bundle."+c.format)
+			asst.NoError(os.WriteFile(bundleFile, got, 0o644))
+			repo, err := repository.NewFeatureRepositoryWithBundleFile(bundleFile, testLogger)
+			asst.NoError(err)
+			loaded, err := repo.Snapshot()
+			asst.NoError(err)
+			asst.Equal(c.bundle, loaded)
+		})
+	}
+}
+
+func Test_NewFeatureRepositoryWithBundle(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	testBundle := &models.Bundle{
+		Projects: []models.Project{{Name: "test-project"}},
+		Features: []models.Feature{{Name: "test-feature", Project: "test-project"}},
+	}
+
+	cases := []struct {
+		name    string
+		bundle  *models.Bundle
+		l       logger.Logger
+		wantErr bool
+	}{
+		{
+			name:    "bundle is nil",
+			bundle:  nil,
+			l:       testLogger,
+			wantErr: true,
+		},
+		{
+			name:    "logger is nil",
+			bundle:  testBundle,
+			l:       nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid bundle",
+			bundle:  &models.Bundle{Features: []models.Feature{{Name: "test-feature", Project: "test-project"}}},
+			l:       testLogger,
+			wantErr: true,
+		},
+		{
+			name:    "success",
+			bundle:  testBundle,
+			l:       testLogger,
+			wantErr: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := repository.NewFeatureRepositoryWithBundle(c.bundle, c.l)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			f, err := got.Get("test-project", "test-feature")
+			asst.NoError(err)
+			asst.Equal("test-feature", f.Name)
+		})
+	}
+}
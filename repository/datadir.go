@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+)
+
+// Layout of the data directory.
+//
+//	<dataDir>
+//	├── projects
+//	│   └── <project>
+//	│       ├── project.json
+//	│       ├── features/<feature>.json
+//	│       ├── launches/<launch>.json
+//	│       └── experiments/<experiment>.json
+//	└── segments/<segment>.json
+const (
+	projectsDirName    = "projects"
+	featuresDirName    = "features"
+	launchesDirName    = "launches"
+	experimentsDirName = "experiments"
+	segmentsDirName    = "segments"
+	projectFileName    = "project.json"
+)
+
+// WriteBundleToDataDir writes all resources in the bundle into the data directory as JSON files.
+// Files of the same resources are overwritten atomically, and the other files are left as they are.
+// It fails with ErrModifiedExternally if a file is changed by others while it is written.
+func WriteBundleToDataDir(dataDir string, bundle *models.Bundle) error {
+	if len(dataDir) == 0 {
+		return errors.New("dataDir is empty")
+	}
+
+	if bundle == nil {
+		return errors.New("bundle is nil")
+	}
+
+	if err := validateBundle(bundle); err != nil {
+		return fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	// a feature defined in multiple formats cannot be loaded, so check it before writing any files
+	for _, f := range bundle.Features {
+		featuresDir := filepath.Join(dataDir, projectsDirName, f.Project, featuresDirName)
+		for _, ext := range featureFileExtensions {
+			if ext == ".json" {
+				continue
+			}
+
+			if _, err := os.Stat(filepath.Join(featuresDir, f.Name+ext)); err == nil {
+				return newDuplicatedFeatureError(f.Name, []string{filepath.Join(featuresDir, f.Name+ext)})
+			}
+		}
+	}
+
+	store, err := OpenFileStore(dataDir)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range bundle.Projects {
+		if err := replaceFile(store, filepath.Join(projectsDirName, p.Name, projectFileName), p); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range bundle.Features {
+		if err := replaceFile(store, filepath.Join(projectsDirName, f.Project, featuresDirName, f.Name+".json"), f); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range bundle.Launches {
+		if err := replaceFile(store, filepath.Join(projectsDirName, l.Project, launchesDirName, l.Name+".json"), l); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range bundle.Experiments {
+		if err := replaceFile(store, filepath.Join(projectsDirName, e.Project, experimentsDirName, e.Name+".json"), e); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range bundle.Segments {
+		if err := replaceFile(store, filepath.Join(segmentsDirName, s.Name+".json"), s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceFile replaces the file at path with v. It fails with ErrModifiedExternally
+// instead of overwriting changes made by others after the current file is read.
+func replaceFile[T any](store *FileStore, path string, v T) error {
+	var current T
+	return store.Modify(path, &current, func(bool) error {
+		current = v
+		return nil
+	})
+}
+
+func readJSONFile(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// readJSONFiles reads all JSON files in the directory. Files that cannot be read are skipped with an error log.
+func readJSONFiles[T models.Launch | models.Experiment | models.Segment](dir string, l logger.Logger) []T {
+	res := []T{}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return res
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		var v T
+		if err := readJSONFile(filepath.Join(dir, file.Name()), &v); err != nil {
+			l.Error("failed to read file", err, "file", filepath.Join(dir, file.Name()))
+			continue
+		}
+
+		res = append(res, v)
+	}
+
+	return res
+}
@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteBundleToDataDir(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	testFeature := models.Feature{
+		DefaultVariation: "False",
+		EntityOverrides:  models.EntityOverride{"force-true": "True"},
+		Name:             "test-feature-1",
+		Project:          "test-project",
+		Status:           "AVAILABLE",
+		ValueType:        types.FeatureValueTypeBoolean,
+		Variations: []models.Variation{
+			{Name: "True", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+			{Name: "False", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+		},
+	}
+
+	testBundle := &models.Bundle{
+		Projects:    []models.Project{{Name: "test-project"}},
+		Features:    []models.Feature{testFeature},
+		Launches:    []models.Launch{{Name: "test-launch", Project: "test-project"}},
+		Experiments: []models.Experiment{{Name: "test-experiment", Project: "test-project"}},
+		Segments:    []models.Segment{{Name: "test-segment", Pattern: "{}"}},
+	}
+
+	cases := []struct {
+		name        string
+		prepare     func(dataDir string)
+		bundle      *models.Bundle
+		wantErr     bool
+		expectFiles []string
+	}{
+		{
+			name:    "bundle is nil",
+			bundle:  nil,
+			wantErr: true,
+		},
+		{
+			name: "invalid bundle",
+			bundle: &models.Bundle{
+				Features: []models.Feature{testFeature},
+			},
+			wantErr: true,
+		},
+		{
+			name: "feature is already defined in yaml",
+			prepare: func(dataDir string) {
+				featuresDir := filepath.Join(dataDir, "projects", "test-project", "features")
+				_ = os.MkdirAll(featuresDir, 0o755)
+				_ = os.WriteFile(filepath.Join(featuresDir, "test-feature-1.yaml"), []byte("name: test-feature-1"), 0o644)
+			},
+			bundle:  testBundle,
+			wantErr: true,
+		},
+		{
+			name: "existing file is not JSON",
+			prepare: func(dataDir string) {
+				segmentsDir := filepath.Join(dataDir, "segments")
+				_ = os.MkdirAll(segmentsDir, 0o755)
+				_ = os.WriteFile(filepath.Join(segmentsDir, "test-segment.json"), []byte("{"), 0o644)
+			},
+			bundle:  testBundle,
+			wantErr: true,
+		},
+		{
+			name: "overwrite existing files",
+			prepare: func(dataDir string) {
+				featuresDir := filepath.Join(dataDir, "projects", "test-project", "features")
+				_ = os.MkdirAll(featuresDir, 0o755)
+				_ = os.WriteFile(filepath.Join(featuresDir, "test-feature-1.json"), []byte(`{"name":"test-feature-1","defaultVariation":"True"}`), 0o644)
+			},
+			bundle:  testBundle,
+			wantErr: false,
+			expectFiles: []string{
+				"projects/test-project/features/test-feature-1.json",
+			},
+		},
+		{
+			name:    "success",
+			bundle:  testBundle,
+			wantErr: false,
+			expectFiles: []string{
+				"projects/test-project/project.json",
+				"projects/test-project/features/test-feature-1.json",
+				"projects/test-project/launches/test-launch.json",
+				"projects/test-project/experiments/test-experiment.json",
+				"segments/test-segment.json",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			dataDir := tt.TempDir()
+			if c.prepare != nil {
+				c.prepare(dataDir)
+			}
+
+			err := repository.WriteBundleToDataDir(dataDir, c.bundle)
+			if c.wantErr {
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			for _, f := range c.expectFiles {
+				asst.FileExists(filepath.Join(dataDir, f))
+			}
+
+			repo, _ := repository.NewFeatureRepositoryWithJSONFile(dataDir, testLogger)
+			got, err := repo.Get("test-project", "test-feature-1")
+			asst.NoError(err)
+			asst.Equal(testFeature, *got)
+		})
+	}
+}
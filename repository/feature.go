@@ -4,16 +4,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/michimani/evidentlylocal/internal"
 	"github.com/michimani/evidentlylocal/logger"
 	"github.com/michimani/evidentlylocal/models"
 )
 
 var featureRepositoryInstance FeatureRepository
 
+// featureFileExtensions is the list of supported feature file extensions.
+// YAML files have the same schema as JSON files.
+var featureFileExtensions = []string{".json", ".yaml", ".yml"}
+
 func SetFeatureRepositoryInstance(r FeatureRepository) {
 	featureRepositoryInstance = r
 }
@@ -23,8 +30,11 @@ func FeatureRepositoryInstance() FeatureRepository {
 }
 
 type FeatureRepository interface {
+	// Get returns the feature. The error matches ErrFeatureNotFound if the project or the feature is not defined.
 	Get(project, feature string) (*models.Feature, error)
 	List(project string) ([]*models.Feature, error)
+	// Snapshot returns all resources in the repository as a bundle that can be loaded again.
+	Snapshot() (*models.Bundle, error)
 }
 
 var _ FeatureRepository = (*FeatureRepositoryWithJSONFile)(nil)
@@ -54,16 +64,23 @@ func (r *FeatureRepositoryWithJSONFile) Get(project, featureName string) (*model
 		return nil, errors.New("FeatureRepositoryWithJSONFile is nil")
 	}
 
-	projectDir := filepath.Join(r.dataDir, "projects", project)
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	if err := internal.ValidateFeatureName(featureName); err != nil {
+		return nil, err
+	}
+
+	projectDir := filepath.Join(r.dataDir, projectsDirName, project)
 	if _, err := os.Stat(projectDir); err != nil {
 		r.l.Error("project directory not found", err)
-		return nil, fmt.Errorf("Project not found: %s", project)
+		return nil, newNotFoundError("Project not found: %s", project)
 	}
 
-	featureFile := path.Join(projectDir, "features", featureName+".json")
-	if _, err := os.Stat(featureFile); err != nil {
-		r.l.Error("feature file not found", err)
-		return nil, fmt.Errorf("Feature not found: %s", featureName)
+	featureFile, err := r.findFeatureFile(filepath.Join(projectDir, featuresDirName), featureName)
+	if err != nil {
+		return nil, err
 	}
 
 	feature, err := r.getFeatureByFilePath(featureFile)
@@ -79,33 +96,131 @@ func (r *FeatureRepositoryWithJSONFile) List(project string) ([]*models.Feature,
 		return nil, errors.New("FeatureRepositoryWithJSONFile is nil")
 	}
 
-	projectDir := filepath.Join(r.dataDir, "projects", project)
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	projectDir := filepath.Join(r.dataDir, projectsDirName, project)
 	if _, err := os.Stat(projectDir); err != nil {
 		r.l.Error("project directory not found", err)
 		return nil, fmt.Errorf("Project not found: %s", project)
 	}
 
-	files, err := os.ReadDir(filepath.Join(projectDir, "features"))
+	_, features := r.listFeatures(projectDir)
+	return features, nil
+}
+
+// listFeatures returns features in the project directory, and names of their files without the extension.
+// Files that cannot be loaded are skipped.
+func (r *FeatureRepositoryWithJSONFile) listFeatures(projectDir string) ([]string, []*models.Feature) {
+	names, featureFiles, err := listFeatureFiles(filepath.Join(projectDir, featuresDirName))
 	if err != nil {
 		r.l.Warn("failed to read features directory")
-		return []*models.Feature{}, nil
+		return []string{}, []*models.Feature{}
 	}
 
+	loaded := []string{}
 	res := []*models.Feature{}
-	for _, file := range files {
-		if !file.IsDir() && file.Name()[len(file.Name())-5:] == ".json" {
-			featureFilePath := filepath.Join(projectDir, "features", file.Name())
-			feature, err := r.getFeatureByFilePath(featureFilePath)
-			if err != nil {
-				r.l.Error("failed to get feature", err)
-				continue
-			}
-
-			res = append(res, feature)
+	for _, name := range names {
+		if len(featureFiles[name]) > 1 {
+			r.l.Error("failed to get feature", newDuplicatedFeatureError(name, featureFiles[name]))
+			continue
 		}
+
+		feature, err := r.getFeatureByFilePath(featureFiles[name][0])
+		if err != nil {
+			r.l.Error("failed to get feature", err)
+			continue
+		}
+
+		loaded = append(loaded, name)
+		res = append(res, feature)
 	}
 
-	return res, nil
+	return loaded, res
+}
+
+// Snapshot returns all projects, features, launches, experiments and segments in the data directory.
+// Files that cannot be loaded are skipped in the same way as List.
+func (r *FeatureRepositoryWithJSONFile) Snapshot() (*models.Bundle, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithJSONFile is nil")
+	}
+
+	bundle := &models.Bundle{
+		Projects:    []models.Project{},
+		Features:    []models.Feature{},
+		Launches:    []models.Launch{},
+		Experiments: []models.Experiment{},
+		Segments:    []models.Segment{},
+	}
+
+	projectDirs, err := os.ReadDir(filepath.Join(r.dataDir, projectsDirName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, d := range projectDirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		name := d.Name()
+		if err := internal.ValidateProjectName(name); err != nil {
+			r.l.Warn("project directory is skipped", "project", name, "error", err.Error())
+			continue
+		}
+
+		projectDir := filepath.Join(r.dataDir, projectsDirName, name)
+
+		project := models.Project{}
+		if err := readJSONFile(filepath.Join(projectDir, projectFileName), &project); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			r.l.Error("failed to read project file", err)
+		}
+		// the directory name is the name of the project
+		project.Name = name
+		bundle.Projects = append(bundle.Projects, project)
+
+		// features are looked up by the directory and the file name, as Get does
+		featureNames, features := r.listFeatures(projectDir)
+		for i, f := range features {
+			f.Project = name
+			f.Name = featureNames[i]
+			bundle.Features = append(bundle.Features, *f)
+		}
+
+		for _, l := range readJSONFiles[models.Launch](filepath.Join(projectDir, launchesDirName), r.l) {
+			l.Project = name
+			bundle.Launches = append(bundle.Launches, l)
+		}
+
+		for _, e := range readJSONFiles[models.Experiment](filepath.Join(projectDir, experimentsDirName), r.l) {
+			e.Project = name
+			bundle.Experiments = append(bundle.Experiments, e)
+		}
+	}
+
+	bundle.Segments = append(bundle.Segments, readJSONFiles[models.Segment](filepath.Join(r.dataDir, segmentsDirName), r.l)...)
+
+	return bundle, nil
+}
+
+// findFeatureFile returns the path of the file that defines the feature.
+// It returns an error if the feature is not defined or defined in multiple formats.
+func (r *FeatureRepositoryWithJSONFile) findFeatureFile(featuresDir, featureName string) (string, error) {
+	found := findFeatureFiles(featuresDir, featureName)
+
+	switch len(found) {
+	case 0:
+		r.l.Error("feature file not found", nil)
+		return "", newNotFoundError("Feature not found: %s", featureName)
+	case 1:
+		return found[0], nil
+	default:
+		err := newDuplicatedFeatureError(featureName, found)
+		r.l.Error("feature is defined in multiple files", err)
+		return "", err
+	}
 }
 
 func (r *FeatureRepositoryWithJSONFile) getFeatureByFilePath(path string) (*models.Feature, error) {
@@ -116,11 +231,85 @@ func (r *FeatureRepositoryWithJSONFile) getFeatureByFilePath(path string) (*mode
 	}
 
 	feature := &models.Feature{}
-
-	if err = json.Unmarshal([]byte(f), feature); err != nil {
+	if err = unmarshalFeatureFile(path, f, feature); err != nil {
 		r.l.Error("failed to unmarshal feature file", err)
 		return nil, err
 	}
 
 	return feature, nil
 }
+
+// listFeatureFiles returns names of features in the features directory, and files of each feature.
+// Files are grouped by feature name to detect features defined in multiple formats.
+func listFeatureFiles(featuresDir string) ([]string, map[string][]string, error) {
+	files, err := os.ReadDir(featuresDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := []string{}
+	featureFiles := map[string][]string{}
+	for _, file := range files {
+		if file.IsDir() || !isFeatureFile(file.Name()) {
+			continue
+		}
+
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		if _, ok := featureFiles[name]; !ok {
+			names = append(names, name)
+		}
+		featureFiles[name] = append(featureFiles[name], filepath.Join(featuresDir, file.Name()))
+	}
+
+	return names, featureFiles, nil
+}
+
+// findFeatureFiles returns paths of all files that define the feature in the features directory.
+func findFeatureFiles(featuresDir, featureName string) []string {
+	found := []string{}
+	for _, ext := range featureFileExtensions {
+		featureFile := filepath.Join(featuresDir, featureName+ext)
+		if _, err := os.Stat(featureFile); err == nil {
+			found = append(found, featureFile)
+		}
+	}
+
+	return found
+}
+
+// unmarshalFeatureFile unmarshals the content of a feature file. The format is decided by the file extension.
+func unmarshalFeatureFile(path string, data []byte, v any) error {
+	if filepath.Ext(path) != ".json" {
+		return unmarshalYAML(data, v)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func isFeatureFile(name string) bool {
+	return slices.Contains(featureFileExtensions, filepath.Ext(name))
+}
+
+// ErrFeatureNotFound is matched by errors of Get if the project or the feature is not defined.
+var ErrFeatureNotFound = errors.New("feature not found")
+
+// notFoundError is an error that matches ErrFeatureNotFound, with the message that is returned to clients.
+type notFoundError struct {
+	message string
+}
+
+func newNotFoundError(format string, args ...any) error {
+	return &notFoundError{message: fmt.Sprintf(format, args...)}
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrFeatureNotFound
+}
+
+func newDuplicatedFeatureError(featureName string, files []string) error {
+	return fmt.Errorf("Feature %s is defined in multiple files: %s", featureName, strings.Join(files, ", "))
+}
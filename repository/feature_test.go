@@ -1,7 +1,10 @@
 package repository_test
 
 import (
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/michimani/evidentlylocal/logger"
@@ -77,7 +80,9 @@ func Test_FeatureRepositoryWithJSONFile_Get(t *testing.T) {
 		project     string
 		featureName string
 		wantErr     bool
-		expect      *models.Feature
+		// wantNotFound is true if the error matches ErrFeatureNotFound
+		wantNotFound bool
+		expect       *models.Feature
 	}{
 		{
 			name:        "repo is nil",
@@ -88,18 +93,36 @@ func Test_FeatureRepositoryWithJSONFile_Get(t *testing.T) {
 			expect:      nil,
 		},
 		{
-			name:        "project not found",
+			name:         "project not found",
+			repo:         testRepo,
+			project:      "not-exists-project",
+			featureName:  "test-feature-1",
+			wantErr:      true,
+			wantNotFound: true,
+			expect:       nil,
+		},
+		{
+			name:         "feature not found",
+			repo:         testRepo,
+			project:      "test-project",
+			featureName:  "not-exists-feature",
+			wantErr:      true,
+			wantNotFound: true,
+			expect:       nil,
+		},
+		{
+			name:        "invalid project name",
 			repo:        testRepo,
-			project:     "not-exists-project",
+			project:     "..",
 			featureName: "test-feature-1",
 			wantErr:     true,
 			expect:      nil,
 		},
 		{
-			name:        "feature not found",
+			name:        "invalid feature name",
 			repo:        testRepo,
 			project:     "test-project",
-			featureName: "not-exists-feature",
+			featureName: "../../test-project/features/test-feature-1",
 			wantErr:     true,
 			expect:      nil,
 		},
@@ -161,6 +184,71 @@ func Test_FeatureRepositoryWithJSONFile_Get(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "success: yaml file",
+			repo:        testRepo,
+			project:     "has-yaml-features-project",
+			featureName: "yaml-feature-1",
+			wantErr:     false,
+			expect: &models.Feature{
+				Name:             "yaml-feature-1",
+				DefaultVariation: "False",
+				EntityOverrides: models.EntityOverride{
+					"force-true": "True",
+					"12345":      "True",
+				},
+				Project:   "has-yaml-features-project",
+				Status:    "AVAILABLE",
+				ValueType: "BOOLEAN",
+				Variations: []models.Variation{
+					{
+						Name: "True", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeBool: true,
+						},
+					},
+					{
+						Name: "False", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeBool: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "success: yml file",
+			repo:        testRepo,
+			project:     "has-yaml-features-project",
+			featureName: "yml-feature-2",
+			wantErr:     false,
+			expect: &models.Feature{
+				Name:             "yml-feature-2",
+				DefaultVariation: "Long1",
+				EntityOverrides:  models.EntityOverride{},
+				Project:          "has-yaml-features-project",
+				Status:           "AVAILABLE",
+				ValueType:        "LONG",
+				Variations: []models.Variation{
+					{
+						Name: "Long1", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeLong: 1.0,
+						},
+					},
+					{
+						Name: "Long2", Value: map[types.VariableValueType]any{
+							types.VariableValueTypeLong: 2.0,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "feature defined in both json and yaml",
+			repo:        testRepo,
+			project:     "has-yaml-features-project",
+			featureName: "duplicated-feature",
+			wantErr:     true,
+			expect:      nil,
+		},
 	}
 
 	for _, c := range cases {
@@ -170,6 +258,7 @@ func Test_FeatureRepositoryWithJSONFile_Get(t *testing.T) {
 			if c.wantErr {
 				asst.Nil(got)
 				asst.Error(err)
+				asst.Equal(c.wantNotFound, errors.Is(err, repository.ErrFeatureNotFound))
 				return
 			}
 
@@ -206,6 +295,13 @@ func Test_FeatureRepositoryWithJSONFile_List(t *testing.T) {
 			wantErr: true,
 			expect:  nil,
 		},
+		{
+			name:    "invalid project name",
+			repo:    testRepo,
+			project: "../testdata/projects/test-project",
+			wantErr: true,
+			expect:  nil,
+		},
 		{
 			name:    "has no features directory project",
 			repo:    testRepo,
@@ -227,6 +323,57 @@ func Test_FeatureRepositoryWithJSONFile_List(t *testing.T) {
 			wantErr: false,
 			expect:  []*models.Feature{},
 		},
+		{
+			name:    "success: yaml files",
+			repo:    testRepo,
+			project: "has-yaml-features-project",
+			wantErr: false,
+			expect: []*models.Feature{
+				{
+					Name:             "yaml-feature-1",
+					DefaultVariation: "False",
+					EntityOverrides: models.EntityOverride{
+						"force-true": "True",
+						"12345":      "True",
+					},
+					Project:   "has-yaml-features-project",
+					Status:    "AVAILABLE",
+					ValueType: "BOOLEAN",
+					Variations: []models.Variation{
+						{
+							Name: "True", Value: map[types.VariableValueType]any{
+								types.VariableValueTypeBool: true,
+							},
+						},
+						{
+							Name: "False", Value: map[types.VariableValueType]any{
+								types.VariableValueTypeBool: false,
+							},
+						},
+					},
+				},
+				{
+					Name:             "yml-feature-2",
+					DefaultVariation: "Long1",
+					EntityOverrides:  models.EntityOverride{},
+					Project:          "has-yaml-features-project",
+					Status:           "AVAILABLE",
+					ValueType:        "LONG",
+					Variations: []models.Variation{
+						{
+							Name: "Long1", Value: map[types.VariableValueType]any{
+								types.VariableValueTypeLong: 1.0,
+							},
+						},
+						{
+							Name: "Long2", Value: map[types.VariableValueType]any{
+								types.VariableValueTypeLong: 2.0,
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:    "success",
 			repo:    testRepo,
@@ -300,6 +447,115 @@ func Test_FeatureRepositoryWithJSONFile_List(t *testing.T) {
 	}
 }
 
+func Test_FeatureRepositoryWithJSONFile_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	// the data directory written from the bundle file has the same resources
+	bundleRepo, err := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	assert.NoError(t, err)
+	testBundle, err := bundleRepo.Snapshot()
+	assert.NoError(t, err)
+	writtenDataDir := t.TempDir()
+	assert.NoError(t, repository.WriteBundleToDataDir(writtenDataDir, testBundle))
+
+	testRepo, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata", testLogger)
+	writtenRepo, _ := repository.NewFeatureRepositoryWithJSONFile(writtenDataDir, testLogger)
+	emptyRepo, _ := repository.NewFeatureRepositoryWithJSONFile(t.TempDir(), testLogger)
+
+	// the feature file has the project and the name that are different from its directory and file name
+	mismatchedDataDir := t.TempDir()
+	mismatchedFeaturesDir := filepath.Join(mismatchedDataDir, "projects", "p2", "features")
+	assert.NoError(t, os.MkdirAll(mismatchedFeaturesDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(mismatchedFeaturesDir, "test-feature-1.json"),
+		[]byte(`{"project": "p1", "name": "other-feature", "defaultVariation": "v1", "variations": [{"name": "v1", "value": {"boolValue": true}}]}`), 0o644))
+	mismatchedRepo, _ := repository.NewFeatureRepositoryWithJSONFile(mismatchedDataDir, testLogger)
+
+	cases := []struct {
+		name              string
+		repo              *repository.FeatureRepositoryWithJSONFile
+		wantErr           bool
+		expect            *models.Bundle
+		expectProjects    []string
+		expectFeatures    int
+		expectFeatureKeys []string
+	}{
+		{
+			name:    "repo is nil",
+			repo:    nil,
+			wantErr: true,
+		},
+		{
+			name:           "empty data directory",
+			repo:           emptyRepo,
+			wantErr:        false,
+			expectProjects: []string{},
+			expectFeatures: 0,
+		},
+		{
+			name:    "testdata",
+			repo:    testRepo,
+			wantErr: false,
+			expectProjects: []string{
+				"has-invalid-json-project",
+				"has-no-feature-project",
+				"has-no-features-dir-project",
+				"has-yaml-features-project",
+				"test-project",
+			},
+			expectFeatures: 4,
+		},
+		{
+			name:    "written from bundle",
+			repo:    writtenRepo,
+			wantErr: false,
+			expect:  testBundle,
+		},
+		{
+			name:              "feature file with different project and name",
+			repo:              mismatchedRepo,
+			wantErr:           false,
+			expectProjects:    []string{"p2"},
+			expectFeatures:    1,
+			expectFeatureKeys: []string{"p2/test-feature-1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.Snapshot()
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			if c.expect != nil {
+				asst.Equal(c.expect, got)
+				return
+			}
+
+			names := []string{}
+			for _, p := range got.Projects {
+				names = append(names, p.Name)
+			}
+			asst.Equal(c.expectProjects, names)
+			asst.Len(got.Features, c.expectFeatures)
+
+			if c.expectFeatureKeys != nil {
+				keys := []string{}
+				for _, f := range got.Features {
+					keys = append(keys, f.Project+"/"+f.Name)
+				}
+				asst.Equal(c.expectFeatureKeys, keys)
+			}
+		})
+	}
+}
+
 func Test_SetAndGetFeatureRepositoryInstance(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+)
+
+var (
+	_ FeatureRepository     = (*FeatureRepositoryWithLayers)(nil)
+	_ FeatureOriginReporter = (*FeatureRepositoryWithLayers)(nil)
+)
+
+// FeatureOriginReporter is implemented by repositories that load features from multiple layers.
+type FeatureOriginReporter interface {
+	FeatureOrigins() ([]FeatureOrigin, error)
+}
+
+// FeatureRepositoryWithLayers is a FeatureRepository that loads resources from multiple ordered data directories.
+// Later layers override earlier ones.
+//
+// A feature file in a later layer is merged into the same feature of earlier layers like JSON Merge Patch (RFC 7386),
+// so that it can override only some fields (e.g. defaultVariation or an entry of entityOverrides).
+// A field set to null is removed. Projects, launches, experiments and segments are overridden per resource.
+type FeatureRepositoryWithLayers struct {
+	layers []*FeatureRepositoryWithJSONFile
+	l      logger.Logger
+}
+
+// FeatureOrigin shows layers that define a feature, from the base layer to the top one.
+type FeatureOrigin struct {
+	Project string         `json:"project"`
+	Name    string         `json:"name"`
+	Layers  []FeatureLayer `json:"layers"`
+}
+
+// FeatureLayer is a layer that defines a feature. Fields are the top-level fields defined in the layer.
+type FeatureLayer struct {
+	DataDir string   `json:"dataDir"`
+	File    string   `json:"file"`
+	Fields  []string `json:"fields"`
+}
+
+func NewFeatureRepositoryWithLayers(dataDirs []string, l logger.Logger) (*FeatureRepositoryWithLayers, error) {
+	if len(dataDirs) == 0 {
+		return nil, errors.New("dataDirs is empty")
+	}
+
+	if l == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	layers := make([]*FeatureRepositoryWithJSONFile, 0, len(dataDirs))
+	for _, dir := range dataDirs {
+		layer, err := NewFeatureRepositoryWithJSONFile(dir, l)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	return &FeatureRepositoryWithLayers{
+		layers: layers,
+		l:      l,
+	}, nil
+}
+
+func (r *FeatureRepositoryWithLayers) Get(project, featureName string) (*models.Feature, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	if err := internal.ValidateFeatureName(featureName); err != nil {
+		return nil, err
+	}
+
+	if !r.hasProject(project) {
+		r.l.Error("project directory not found in any layers", nil)
+		return nil, newNotFoundError("Project not found: %s", project)
+	}
+
+	feature, _, err := r.getFeature(project, featureName)
+	if err != nil {
+		r.l.Error("failed to get feature", err)
+		return nil, err
+	}
+
+	return feature, nil
+}
+
+func (r *FeatureRepositoryWithLayers) List(project string) ([]*models.Feature, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	if err := internal.ValidateProjectName(project); err != nil {
+		return nil, err
+	}
+
+	if !r.hasProject(project) {
+		r.l.Error("project directory not found in any layers", nil)
+		return nil, fmt.Errorf("Project not found: %s", project)
+	}
+
+	res := []*models.Feature{}
+	for _, name := range r.featureNames(project) {
+		feature, _, err := r.getFeature(project, name)
+		if err != nil {
+			r.l.Error("failed to get feature", err)
+			continue
+		}
+
+		res = append(res, feature)
+	}
+
+	return res, nil
+}
+
+// Snapshot returns all resources merged from all layers.
+func (r *FeatureRepositoryWithLayers) Snapshot() (*models.Bundle, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	projects := newNamedSet[models.Project]()
+	launches := newNamedSet[models.Launch]()
+	experiments := newNamedSet[models.Experiment]()
+	segments := newNamedSet[models.Segment]()
+
+	for _, layer := range r.layers {
+		b, err := layer.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range b.Projects {
+			// a project directory without project.json does not override the project
+			if _, ok := projects.items[p.Name]; ok && isEmptyProject(p) {
+				continue
+			}
+			projects.set(p.Name, p)
+		}
+		for _, l := range b.Launches {
+			launches.set(l.Project+"/"+l.Name, l)
+		}
+		for _, e := range b.Experiments {
+			experiments.set(e.Project+"/"+e.Name, e)
+		}
+		for _, s := range b.Segments {
+			segments.set(s.Name, s)
+		}
+	}
+
+	// features are looked up by the directory and the file name, as Get does
+	features := []models.Feature{}
+	for _, p := range projects.list() {
+		for _, name := range r.featureNames(p.Name) {
+			f, _, err := r.getFeature(p.Name, name)
+			if err != nil {
+				r.l.Error("failed to get feature", err)
+				continue
+			}
+			f.Project = p.Name
+			f.Name = name
+			features = append(features, *f)
+		}
+	}
+
+	return &models.Bundle{
+		Projects:    projects.list(),
+		Features:    features,
+		Launches:    launches.list(),
+		Experiments: experiments.list(),
+		Segments:    segments.list(),
+	}, nil
+}
+
+// FeatureOrigins returns layers that define each feature.
+func (r *FeatureRepositoryWithLayers) FeatureOrigins() ([]FeatureOrigin, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	res := []FeatureOrigin{}
+	for _, project := range r.projectNames() {
+		for _, name := range r.featureNames(project) {
+			_, layers, err := r.getFeature(project, name)
+			if err != nil {
+				r.l.Error("failed to get feature", err)
+				continue
+			}
+
+			res = append(res, FeatureOrigin{
+				Project: project,
+				Name:    name,
+				Layers:  layers,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// getFeature merges the feature files in all layers, and returns the feature and the layers that define it.
+func (r *FeatureRepositoryWithLayers) getFeature(project, featureName string) (*models.Feature, []FeatureLayer, error) {
+	var doc any
+	layers := []FeatureLayer{}
+
+	for _, layer := range r.layers {
+		files := findFeatureFiles(filepath.Join(layer.dataDir, projectsDirName, project, featuresDirName), featureName)
+		switch len(files) {
+		case 0:
+			continue
+		case 1:
+			// noop
+		default:
+			return nil, nil, newDuplicatedFeatureError(featureName, files)
+		}
+
+		b, err := os.ReadFile(files[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var layerDoc any
+		if err := unmarshalFeatureFile(files[0], b, &layerDoc); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal %s: %w", files[0], err)
+		}
+
+		fields := []string{}
+		if m, ok := layerDoc.(map[string]any); ok {
+			for k := range m {
+				fields = append(fields, k)
+			}
+			slices.Sort(fields)
+		}
+
+		doc = mergeDocument(doc, layerDoc)
+		layers = append(layers, FeatureLayer{
+			DataDir: layer.dataDir,
+			File:    files[0],
+			Fields:  fields,
+		})
+	}
+
+	if len(layers) == 0 {
+		return nil, nil, newNotFoundError("Feature not found: %s", featureName)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feature := &models.Feature{}
+	if err := json.Unmarshal(b, feature); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal merged feature %s: %w", featureName, err)
+	}
+
+	return feature, layers, nil
+}
+
+func (r *FeatureRepositoryWithLayers) hasProject(project string) bool {
+	for _, layer := range r.layers {
+		if _, err := os.Stat(filepath.Join(layer.dataDir, projectsDirName, project)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// projectNames returns names of projects in all layers, in order of appearance.
+func (r *FeatureRepositoryWithLayers) projectNames() []string {
+	names := []string{}
+	for _, layer := range r.layers {
+		dirs, err := os.ReadDir(filepath.Join(layer.dataDir, projectsDirName))
+		if err != nil {
+			continue
+		}
+
+		for _, d := range dirs {
+			if d.IsDir() && internal.ValidateProjectName(d.Name()) == nil && !slices.Contains(names, d.Name()) {
+				names = append(names, d.Name())
+			}
+		}
+	}
+
+	return names
+}
+
+// featureNames returns names of features of the project in all layers, in order of appearance.
+func (r *FeatureRepositoryWithLayers) featureNames(project string) []string {
+	names := []string{}
+	for _, layer := range r.layers {
+		layerNames, _, err := listFeatureFiles(filepath.Join(layer.dataDir, projectsDirName, project, featuresDirName))
+		if err != nil {
+			continue
+		}
+
+		for _, name := range layerNames {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// mergeDocument merges overlay into base like JSON Merge Patch (RFC 7386).
+// Objects are merged recursively, null removes the field, and other values replace the base value.
+func mergeDocument(base, overlay any) any {
+	o, ok := overlay.(map[string]any)
+	if !ok {
+		return overlay
+	}
+
+	b, ok := base.(map[string]any)
+	if !ok {
+		b = map[string]any{}
+	}
+
+	for k, v := range o {
+		if v == nil {
+			delete(b, k)
+			continue
+		}
+		b[k] = mergeDocument(b[k], v)
+	}
+
+	return b
+}
+
+func isEmptyProject(p models.Project) bool {
+	return len(p.Description) == 0 && len(p.Status) == 0 && len(p.Tags) == 0
+}
+
+// namedSet keeps resources by key in order of appearance. A later resource replaces the earlier one.
+type namedSet[T any] struct {
+	keys  []string
+	items map[string]T
+}
+
+func newNamedSet[T any]() *namedSet[T] {
+	return &namedSet[T]{
+		keys:  []string{},
+		items: map[string]T{},
+	}
+}
+
+func (s *namedSet[T]) set(key string, item T) {
+	if _, ok := s.items[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.items[key] = item
+}
+
+func (s *namedSet[T]) list() []T {
+	res := make([]T, 0, len(s.keys))
+	for _, k := range s.keys {
+		res = append(res, s.items[k])
+	}
+
+	return res
+}
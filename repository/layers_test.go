@@ -0,0 +1,315 @@
+package repository_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLayers = []string{"../testdata/layers/base", "../testdata/layers/overlay"}
+
+func Test_NewFeatureRepositoryWithLayers(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	cases := []struct {
+		name     string
+		dataDirs []string
+		l        logger.Logger
+		wantErr  bool
+	}{
+		{
+			name:     "dataDirs is empty",
+			dataDirs: []string{},
+			l:        testLogger,
+			wantErr:  true,
+		},
+		{
+			name:     "dataDirs has an empty dir",
+			dataDirs: []string{"../testdata/layers/base", ""},
+			l:        testLogger,
+			wantErr:  true,
+		},
+		{
+			name:     "logger is nil",
+			dataDirs: testLayers,
+			l:        nil,
+			wantErr:  true,
+		},
+		{
+			name:     "success",
+			dataDirs: testLayers,
+			l:        testLogger,
+			wantErr:  false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := repository.NewFeatureRepositoryWithLayers(c.dataDirs, c.l)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(got)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithLayers_Get(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithLayers(testLayers, testLogger)
+	assert.NoError(t, err)
+
+	boolVariations := []models.Variation{
+		{Name: "True", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: true}},
+		{Name: "False", Value: map[types.VariableValueType]any{types.VariableValueTypeBool: false}},
+	}
+
+	cases := []struct {
+		name        string
+		repo        *repository.FeatureRepositoryWithLayers
+		project     string
+		featureName string
+		wantErr     bool
+		// wantNotFound is true if the error matches ErrFeatureNotFound
+		wantNotFound bool
+		expect       *models.Feature
+	}{
+		{
+			name:        "repo is nil",
+			repo:        nil,
+			project:     "test-project",
+			featureName: "layered-feature",
+			wantErr:     true,
+		},
+		{
+			name:        "invalid feature name",
+			repo:        testRepo,
+			project:     "test-project",
+			featureName: "..",
+			wantErr:     true,
+		},
+		{
+			name:         "project not found",
+			repo:         testRepo,
+			project:      "not-exists-project",
+			featureName:  "layered-feature",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:         "feature not found",
+			repo:         testRepo,
+			project:      "test-project",
+			featureName:  "not-exists-feature",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:        "merged: overlay overrides some fields",
+			repo:        testRepo,
+			project:     "test-project",
+			featureName: "layered-feature",
+			wantErr:     false,
+			expect: &models.Feature{
+				DefaultVariation: "True",
+				EntityOverrides:  models.EntityOverride{"force-true": "True", "my-entity": "False"},
+				Name:             "layered-feature",
+				Project:          "test-project",
+				Status:           "AVAILABLE",
+				ValueType:        types.FeatureValueTypeBoolean,
+				Variations:       boolVariations,
+			},
+		},
+		{
+			name:        "only in base",
+			repo:        testRepo,
+			project:     "test-project",
+			featureName: "base-feature",
+			wantErr:     false,
+			expect: &models.Feature{
+				DefaultVariation: "String1",
+				EntityOverrides:  models.EntityOverride{},
+				Name:             "base-feature",
+				Project:          "test-project",
+				Status:           "AVAILABLE",
+				ValueType:        types.FeatureValueTypeString,
+				Variations: []models.Variation{
+					{Name: "String1", Value: map[types.VariableValueType]any{types.VariableValueTypeString: "string-1"}},
+				},
+			},
+		},
+		{
+			name:        "only in overlay",
+			repo:        testRepo,
+			project:     "test-project",
+			featureName: "overlay-feature",
+			wantErr:     false,
+			expect: &models.Feature{
+				DefaultVariation: "Long1",
+				EntityOverrides:  models.EntityOverride{},
+				Name:             "overlay-feature",
+				Project:          "test-project",
+				Status:           "AVAILABLE",
+				ValueType:        types.FeatureValueTypeLong,
+				Variations: []models.Variation{
+					{Name: "Long1", Value: map[types.VariableValueType]any{types.VariableValueTypeLong: float64(1)}},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.Get(c.project, c.featureName)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				asst.Equal(c.wantNotFound, errors.Is(err, repository.ErrFeatureNotFound))
+				return
+			}
+
+			asst.NoError(err)
+			asst.Equal(*c.expect, *got)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithLayers_List(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithLayers(testLayers, testLogger)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		repo        *repository.FeatureRepositoryWithLayers
+		project     string
+		wantErr     bool
+		expectNames []string
+	}{
+		{
+			name:    "repo is nil",
+			repo:    nil,
+			project: "test-project",
+			wantErr: true,
+		},
+		{
+			name:    "project not found",
+			repo:    testRepo,
+			project: "not-exists-project",
+			wantErr: true,
+		},
+		{
+			name:        "success",
+			repo:        testRepo,
+			project:     "test-project",
+			wantErr:     false,
+			expectNames: []string{"base-feature", "layered-feature", "overlay-feature"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := c.repo.List(c.project)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			names := []string{}
+			for _, f := range got {
+				names = append(names, f.Name)
+			}
+			asst.Equal(c.expectNames, names)
+		})
+	}
+}
+
+func Test_FeatureRepositoryWithLayers_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithLayers(testLayers, testLogger)
+	assert.NoError(t, err)
+
+	asst := assert.New(t)
+	got, err := testRepo.Snapshot()
+	asst.NoError(err)
+
+	// the overlay has the project directory without project.json
+	asst.Equal([]models.Project{{Description: "base project", Name: "test-project", Status: "AVAILABLE"}}, got.Projects)
+	asst.Len(got.Features, 3)
+	asst.Equal([]models.Segment{{Name: "test-segment", Pattern: `{"Price":[{"numeric":[">",100]}]}`}}, got.Segments)
+}
+
+func Test_FeatureRepositoryWithLayers_FeatureOrigins(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, err := repository.NewFeatureRepositoryWithLayers(testLayers, testLogger)
+	assert.NoError(t, err)
+
+	asst := assert.New(t)
+	got, err := testRepo.FeatureOrigins()
+	asst.NoError(err)
+	asst.Equal([]repository.FeatureOrigin{
+		{
+			Project: "test-project",
+			Name:    "base-feature",
+			Layers: []repository.FeatureLayer{
+				{
+					DataDir: "../testdata/layers/base",
+					File:    "../testdata/layers/base/projects/test-project/features/base-feature.json",
+					Fields:  []string{"defaultVariation", "entityOverrides", "name", "project", "status", "valueType", "variations"},
+				},
+			},
+		},
+		{
+			Project: "test-project",
+			Name:    "layered-feature",
+			Layers: []repository.FeatureLayer{
+				{
+					DataDir: "../testdata/layers/base",
+					File:    "../testdata/layers/base/projects/test-project/features/layered-feature.json",
+					Fields:  []string{"defaultVariation", "entityOverrides", "name", "project", "status", "valueType", "variations"},
+				},
+				{
+					DataDir: "../testdata/layers/overlay",
+					File:    "../testdata/layers/overlay/projects/test-project/features/layered-feature.yaml",
+					Fields:  []string{"defaultVariation", "entityOverrides"},
+				},
+			},
+		},
+		{
+			Project: "test-project",
+			Name:    "overlay-feature",
+			Layers: []repository.FeatureLayer{
+				{
+					DataDir: "../testdata/layers/overlay",
+					File:    "../testdata/layers/overlay/projects/test-project/features/overlay-feature.json",
+					Fields:  []string{"defaultVariation", "entityOverrides", "name", "project", "status", "valueType", "variations"},
+				},
+			},
+		},
+	}, got)
+}
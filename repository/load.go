@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/michimani/evidentlylocal/internal"
+	"github.com/michimani/evidentlylocal/models"
+)
+
+var (
+	_ Loader = (*FeatureRepositoryWithJSONFile)(nil)
+	_ Loader = (*FeatureRepositoryWithLayers)(nil)
+	_ Loader = (*FeatureRepositoryWithBundleFile)(nil)
+)
+
+// Loader is implemented by repositories that can load all resources at once
+// to report files that cannot be loaded. Get and List skip such files with an error log.
+type Loader interface {
+	Load() (*LoadReport, error)
+}
+
+// LoadReport is the result of loading all resources in a repository.
+type LoadReport struct {
+	Projects int         `json:"projects"`
+	Features int         `json:"features"`
+	Errors   []LoadError `json:"errors"`
+}
+
+// LoadError is a file that cannot be loaded.
+type LoadError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// featureLookup returns the feature of the project, or nil if it is not defined.
+type featureLookup func(project, name string) *models.Feature
+
+// Load loads all files in the data directory, and validates references in features, launches and experiments.
+// It returns an error if the data directory cannot be read.
+func (r *FeatureRepositoryWithJSONFile) Load() (*LoadReport, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithJSONFile is nil")
+	}
+
+	return r.load(nil)
+}
+
+// load loads all files in the data directory. If merged is nil, features are validated and launches and experiments
+// reference features in the data directory. Otherwise, the data directory is a layer, that can have partial features,
+// so features are only parsed and launches and experiments reference merged features.
+func (r *FeatureRepositoryWithJSONFile) load(merged featureLookup) (*LoadReport, error) {
+	if _, err := os.Stat(r.dataDir); err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	report := &LoadReport{Errors: []LoadError{}}
+
+	projectDirs, err := os.ReadDir(filepath.Join(r.dataDir, projectsDirName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, d := range projectDirs {
+		if !d.IsDir() || internal.ValidateProjectName(d.Name()) != nil {
+			continue
+		}
+
+		projectDir := filepath.Join(r.dataDir, projectsDirName, d.Name())
+		report.Projects++
+
+		projectFile := filepath.Join(projectDir, projectFileName)
+		if err := readJSONFile(projectFile, &models.Project{}); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			report.addError(projectFile, err)
+		}
+
+		names, featureFiles, err := listFeatureFiles(filepath.Join(projectDir, featuresDirName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			report.addError(filepath.Join(projectDir, featuresDirName), err)
+		}
+
+		features := map[string]*models.Feature{}
+		for _, name := range names {
+			files := featureFiles[name]
+			if len(files) > 1 {
+				report.addError(files[0], newDuplicatedFeatureError(name, files))
+				continue
+			}
+
+			feature, err := loadFeatureFile(files[0])
+			if err == nil && merged == nil {
+				err = feature.Validate()
+			}
+			if err != nil {
+				report.addError(files[0], err)
+				continue
+			}
+
+			features[name] = feature
+			report.Features++
+		}
+
+		lookup := merged
+		if lookup == nil {
+			lookup = func(_, name string) *models.Feature { return features[name] }
+		}
+		project := d.Name()
+
+		report.Errors = append(report.Errors, checkJSONFiles(filepath.Join(projectDir, launchesDirName), func(l models.Launch) error {
+			errs := []error{}
+			for _, g := range l.Groups {
+				errs = append(errs, checkFeatureVariations(project, "group "+g.Name, g.FeatureVariations, lookup))
+			}
+			return errors.Join(errs...)
+		})...)
+		report.Errors = append(report.Errors, checkJSONFiles(filepath.Join(projectDir, experimentsDirName), func(e models.Experiment) error {
+			errs := []error{}
+			for _, t := range e.Treatments {
+				errs = append(errs, checkFeatureVariations(project, "treatment "+t.Name, t.FeatureVariations, lookup))
+			}
+			return errors.Join(errs...)
+		})...)
+	}
+
+	report.Errors = append(report.Errors, checkJSONFiles(filepath.Join(r.dataDir, segmentsDirName), func(models.Segment) error { return nil })...)
+
+	return report, nil
+}
+
+// Load loads all layers, and merges features in them.
+func (r *FeatureRepositoryWithLayers) Load() (*LoadReport, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	report := &LoadReport{Errors: []LoadError{}}
+
+	// launches and experiments in each layer reference features merged from all layers
+	merged := map[string]*models.Feature{}
+	lookup := func(project, name string) *models.Feature {
+		key := project + "/" + name
+		if f, ok := merged[key]; ok {
+			return f
+		}
+
+		f, _, err := r.getFeature(project, name)
+		if err != nil {
+			f = nil
+		}
+		merged[key] = f
+		return f
+	}
+
+	for _, layer := range r.layers {
+		lr, err := layer.load(lookup)
+		if err != nil {
+			return nil, err
+		}
+		report.Errors = append(report.Errors, lr.Errors...)
+	}
+
+	for _, project := range r.projectNames() {
+		report.Projects++
+
+		for _, name := range r.featureNames(project) {
+			feature, layers, err := r.getFeature(project, name)
+			if err != nil {
+				// errors of each file are already reported by the layer
+				continue
+			}
+
+			// a partial feature in a layer can be valid after it is merged, so the merged feature is validated
+			if err := feature.Validate(); err != nil {
+				report.addError(layers[len(layers)-1].File, err)
+				continue
+			}
+			report.Features++
+		}
+	}
+
+	return report, nil
+}
+
+// Load returns the number of resources in the bundle, and validates references in features.
+// The bundle is already loaded, and names of resources are validated.
+func (r *FeatureRepositoryWithBundleFile) Load() (*LoadReport, error) {
+	if r == nil {
+		return nil, errors.New("FeatureRepositoryWithBundleFile is nil")
+	}
+
+	report := &LoadReport{
+		Projects: len(r.bundle.Projects),
+		Errors:   []LoadError{},
+	}
+
+	for i := range r.bundle.Features {
+		f := &r.bundle.Features[i]
+		if err := f.Validate(); err != nil {
+			report.addError(r.bundleFile, fmt.Errorf("feature %s/%s: %w", f.Project, f.Name, err))
+			continue
+		}
+		report.Features++
+	}
+
+	return report, nil
+}
+
+func (r *LoadReport) addError(file string, err error) {
+	r.Errors = append(r.Errors, LoadError{
+		File:  file,
+		Error: err.Error(),
+	})
+}
+
+func loadFeatureFile(path string) (*models.Feature, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	feature := &models.Feature{}
+	if err := unmarshalFeatureFile(path, b, feature); err != nil {
+		return nil, err
+	}
+
+	return feature, nil
+}
+
+// checkFeatureVariations returns an error if a feature or a variation that a group or a treatment uses is not defined.
+func checkFeatureVariations(project, user string, featureVariations map[string]string, feature featureLookup) error {
+	names := make([]string, 0, len(featureVariations))
+	for name := range featureVariations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	errs := []error{}
+	for _, name := range names {
+		f := feature(project, name)
+		switch {
+		case f == nil:
+			errs = append(errs, fmt.Errorf("%s uses feature %s that is not defined", user, name))
+		case !f.HasVariation(featureVariations[name]):
+			errs = append(errs, fmt.Errorf("%s uses variation %s that is not defined in feature %s", user, featureVariations[name], name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkJSONFiles returns errors of JSON files in the directory that cannot be read in the same way as readJSONFiles,
+// or that check returns an error for.
+func checkJSONFiles[T models.Launch | models.Experiment | models.Segment](dir string, check func(T) error) []LoadError {
+	res := []LoadError{}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return res
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		var v T
+		err := readJSONFile(path, &v)
+		if err == nil {
+			err = check(v)
+		}
+		if err != nil {
+			res = append(res, LoadError{File: path, Error: err.Error()})
+		}
+	}
+
+	return res
+}
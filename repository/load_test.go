@@ -0,0 +1,134 @@
+package repository_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Load(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+
+	newJSON := func(dir string) repository.Loader {
+		r, _ := repository.NewFeatureRepositoryWithJSONFile(dir, testLogger)
+		return r
+	}
+	layers, _ := repository.NewFeatureRepositoryWithLayers(testLayers, testLogger)
+	bundle, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.json", testLogger)
+	invalidRefs := writeInvalidReferences(t)
+
+	cases := []struct {
+		name         string
+		loader       repository.Loader
+		wantProjects int
+		wantFeatures int
+		wantErrFiles []string
+		wantErr      bool
+	}{
+		{
+			name:         "data directory with invalid files",
+			loader:       newJSON("../testdata"),
+			wantProjects: 5,
+			wantFeatures: 4,
+			wantErrFiles: []string{
+				"../testdata/projects/has-invalid-json-project/features/invalid-json-feature.json",
+				"../testdata/projects/has-invalid-json-project/features/invalid-yaml-feature.yaml",
+				"../testdata/projects/has-yaml-features-project/features/duplicated-feature.json",
+			},
+		},
+		{
+			name:         "data directory with invalid references",
+			loader:       newJSON(invalidRefs),
+			wantProjects: 1,
+			wantFeatures: 1,
+			wantErrFiles: []string{
+				filepath.Join(invalidRefs, "projects/p/features/unknown-default.json"),
+				filepath.Join(invalidRefs, "projects/p/features/unknown-override.json"),
+				filepath.Join(invalidRefs, "projects/p/features/unknown-value-type.json"),
+				filepath.Join(invalidRefs, "projects/p/launches/invalid-launch.json"),
+				filepath.Join(invalidRefs, "projects/p/experiments/invalid-experiment.json"),
+			},
+		},
+		{
+			name:         "layers",
+			loader:       layers,
+			wantProjects: 1,
+			wantFeatures: 3,
+			wantErrFiles: []string{},
+		},
+		{
+			name:         "bundle",
+			loader:       bundle,
+			wantProjects: 1,
+			wantFeatures: 1,
+			wantErrFiles: []string{},
+		},
+		{
+			name:    "data directory not found",
+			loader:  newJSON("../testdata/not-exists"),
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			tt.Parallel()
+			asst := assert.New(tt)
+
+			report, err := c.loader.Load()
+			if c.wantErr {
+				asst.Nil(report)
+				asst.Error(err)
+				return
+			}
+
+			if !asst.NoError(err) {
+				return
+			}
+			asst.Equal(c.wantProjects, report.Projects)
+			asst.Equal(c.wantFeatures, report.Features)
+
+			files := []string{}
+			for _, e := range report.Errors {
+				files = append(files, e.File)
+				asst.NotEmpty(e.Error)
+			}
+			asst.Equal(c.wantErrFiles, files)
+		})
+	}
+}
+
+// writeInvalidReferences writes a data directory whose files can be parsed but have references that are not defined.
+func writeInvalidReferences(t *testing.T) string {
+	t.Helper()
+
+	dataDir := t.TempDir()
+	files := map[string]string{
+		"projects/p/features/valid.json":                 `{"valueType":"BOOLEAN","defaultVariation":"off","entityOverrides":{"u":"on"},"variations":[{"name":"on","value":{"boolValue":true}},{"name":"off","value":{"boolValue":false}}]}`,
+		"projects/p/features/unknown-default.json":       `{"valueType":"BOOLEAN","defaultVariation":"unknown","variations":[{"name":"on","value":{"boolValue":true}}]}`,
+		"projects/p/features/unknown-override.json":      `{"valueType":"BOOLEAN","defaultVariation":"on","entityOverrides":{"u":"unknown"},"variations":[{"name":"on","value":{"boolValue":true}}]}`,
+		"projects/p/features/unknown-value-type.json":    `{"valueType":"NUMBER","defaultVariation":"on","variations":[{"name":"on","value":{"longValue":1}}]}`,
+		"projects/p/launches/valid-launch.json":          `{"name":"valid-launch","groups":[{"name":"g","featureVariations":{"valid":"on"}}]}`,
+		"projects/p/launches/invalid-launch.json":        `{"name":"invalid-launch","groups":[{"name":"g","featureVariations":{"unknown":"on"}}]}`,
+		"projects/p/experiments/invalid-experiment.json": `{"name":"invalid-experiment","treatments":[{"name":"t","featureVariations":{"valid":"unknown"}}]}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dataDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dataDir
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/michimani/evidentlylocal/models"
+)
+
+// Types of resources that reference features and segments, as ListSegmentReferences reports.
+const (
+	ReferenceTypeLaunch     = "LAUNCH"
+	ReferenceTypeExperiment = "EXPERIMENT"
+)
+
+// Reference is a launch or an experiment that references a feature, a variation or a segment.
+type Reference struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Status  string `json:"status,omitempty"`
+}
+
+// ReferenceIndex indexes launches and experiments by features, variations and segments that they reference.
+// It is built from a snapshot, so that it works with every FeatureRepository, and rebuilt when the repository is loaded.
+type ReferenceIndex struct {
+	mu           sync.RWMutex
+	built        bool
+	segmentNames map[string]bool
+	features     map[string][]Reference
+	variations   map[string][]Reference
+	segments     map[string][]Reference
+}
+
+func NewReferenceIndex() *ReferenceIndex {
+	return &ReferenceIndex{
+		segmentNames: map[string]bool{},
+		features:     map[string][]Reference{},
+		variations:   map[string][]Reference{},
+		segments:     map[string][]Reference{},
+	}
+}
+
+// Build indexes references in the bundle, and replaces the index at once.
+func (x *ReferenceIndex) Build(bundle *models.Bundle) {
+	b := NewReferenceIndex()
+
+	for _, s := range bundle.Segments {
+		b.segmentNames[s.Name] = true
+	}
+
+	for _, l := range bundle.Launches {
+		ref := Reference{Project: l.Project, Name: l.Name, Type: ReferenceTypeLaunch, Status: l.Status}
+		for _, g := range l.Groups {
+			b.addFeatureVariations(ref, g.FeatureVariations)
+		}
+
+		if l.ScheduledSplitsDefinition != nil {
+			for _, s := range l.ScheduledSplitsDefinition.Steps {
+				for _, o := range s.SegmentOverrides {
+					b.segments[o.Segment] = appendReference(b.segments[o.Segment], ref)
+				}
+			}
+		}
+	}
+
+	for _, e := range bundle.Experiments {
+		ref := Reference{Project: e.Project, Name: e.Name, Type: ReferenceTypeExperiment, Status: e.Status}
+		for _, t := range e.Treatments {
+			b.addFeatureVariations(ref, t.FeatureVariations)
+		}
+
+		if len(e.Segment) > 0 {
+			b.segments[e.Segment] = appendReference(b.segments[e.Segment], ref)
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.segmentNames = b.segmentNames
+	x.features = b.features
+	x.variations = b.variations
+	x.segments = b.segments
+	x.built = true
+}
+
+// Built reports whether the index has been built.
+func (x *ReferenceIndex) Built() bool {
+	if x == nil {
+		return false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.built
+}
+
+func (x *ReferenceIndex) addFeatureVariations(ref Reference, featureVariations map[string]string) {
+	for feature, variation := range featureVariations {
+		fk := featureKey(ref.Project, feature)
+		x.features[fk] = appendReference(x.features[fk], ref)
+
+		vk := fk + "/" + variation
+		x.variations[vk] = appendReference(x.variations[vk], ref)
+	}
+}
+
+// HasSegment reports whether the segment is in the index.
+func (x *ReferenceIndex) HasSegment(segment string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.segmentNames[segment]
+}
+
+// FeatureReferences returns launches and experiments that reference the feature.
+func (x *ReferenceIndex) FeatureReferences(project, feature string) []Reference {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return sortedReferences(x.features[featureKey(project, feature)])
+}
+
+// VariationReferences returns launches and experiments whose groups or treatments use the variation of the feature.
+func (x *ReferenceIndex) VariationReferences(project, feature, variation string) []Reference {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return sortedReferences(x.variations[featureKey(project, feature)+"/"+variation])
+}
+
+// SegmentReferences returns launches and experiments that reference the segment.
+// If refType is not empty, only references of the type are returned.
+func (x *ReferenceIndex) SegmentReferences(segment, refType string) []Reference {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	refs := []Reference{}
+	for _, r := range x.segments[segment] {
+		if len(refType) == 0 || r.Type == refType {
+			refs = append(refs, r)
+		}
+	}
+
+	return sortedReferences(refs)
+}
+
+func featureKey(project, feature string) string {
+	return project + "/" + feature
+}
+
+// appendReference appends the reference unless it is already in refs,
+// because a launch or an experiment can reference the same resource from multiple groups or steps.
+func appendReference(refs []Reference, ref Reference) []Reference {
+	if slices.Contains(refs, ref) {
+		return refs
+	}
+
+	return append(refs, ref)
+}
+
+// sortedReferences returns a sorted copy of refs, so that the index is never changed by callers.
+func sortedReferences(refs []Reference) []Reference {
+	res := slices.Clone(refs)
+	if res == nil {
+		res = []Reference{}
+	}
+
+	slices.SortFunc(res, func(a, b Reference) int {
+		return strings.Compare(a.Type+"/"+a.Project+"/"+a.Name, b.Type+"/"+b.Project+"/"+b.Name)
+	})
+
+	return res
+}
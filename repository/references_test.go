@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReferenceIndex(t *testing.T) {
+	t.Parallel()
+
+	bundle := &models.Bundle{
+		Launches: []models.Launch{
+			{
+				Project: "p",
+				Name:    "running-launch",
+				Status:  "RUNNING",
+				Groups: []models.LaunchGroup{
+					{Name: "control", FeatureVariations: map[string]string{"f": "off"}},
+					{Name: "treatment", FeatureVariations: map[string]string{"f": "on"}},
+				},
+				ScheduledSplitsDefinition: &models.ScheduledSplitsDefinition{
+					Steps: []models.ScheduledSplit{
+						{SegmentOverrides: []models.SegmentOverride{{Segment: "s"}}},
+						{SegmentOverrides: []models.SegmentOverride{{Segment: "s"}}},
+					},
+				},
+			},
+			{
+				Project: "p",
+				Name:    "completed-launch",
+				Status:  "COMPLETED",
+				Groups:  []models.LaunchGroup{{Name: "control", FeatureVariations: map[string]string{"g": "off"}}},
+			},
+		},
+		Experiments: []models.Experiment{
+			{
+				Project:    "q",
+				Name:       "created-experiment",
+				Status:     "CREATED",
+				Segment:    "s",
+				Treatments: []models.Treatment{{Name: "control", FeatureVariations: map[string]string{"f": "on"}}},
+			},
+		},
+	}
+
+	runningLaunch := repository.Reference{Project: "p", Name: "running-launch", Type: repository.ReferenceTypeLaunch, Status: "RUNNING"}
+	createdExperiment := repository.Reference{Project: "q", Name: "created-experiment", Type: repository.ReferenceTypeExperiment, Status: "CREATED"}
+
+	cases := []struct {
+		name   string
+		refs   func(x *repository.ReferenceIndex) []repository.Reference
+		expect []repository.Reference
+	}{
+		{
+			name:   "feature used by a running launch",
+			refs:   func(x *repository.ReferenceIndex) []repository.Reference { return x.FeatureReferences("p", "f") },
+			expect: []repository.Reference{runningLaunch},
+		},
+		{
+			name:   "feature used by a completed launch",
+			refs:   func(x *repository.ReferenceIndex) []repository.Reference { return x.FeatureReferences("p", "g") },
+			expect: []repository.Reference{{Project: "p", Name: "completed-launch", Type: repository.ReferenceTypeLaunch, Status: "COMPLETED"}},
+		},
+		{
+			name:   "feature of another project with the same name",
+			refs:   func(x *repository.ReferenceIndex) []repository.Reference { return x.FeatureReferences("q", "f") },
+			expect: []repository.Reference{createdExperiment},
+		},
+		{
+			name: "variation used by a running launch",
+			refs: func(x *repository.ReferenceIndex) []repository.Reference {
+				return x.VariationReferences("p", "f", "on")
+			},
+			expect: []repository.Reference{runningLaunch},
+		},
+		{
+			name: "unused variation",
+			refs: func(x *repository.ReferenceIndex) []repository.Reference {
+				return x.VariationReferences("p", "f", "unused")
+			},
+			expect: []repository.Reference{},
+		},
+		{
+			name:   "segment used by a launch and an experiment that is not running",
+			refs:   func(x *repository.ReferenceIndex) []repository.Reference { return x.SegmentReferences("s", "") },
+			expect: []repository.Reference{createdExperiment, runningLaunch},
+		},
+		{
+			name: "segment references of a type",
+			refs: func(x *repository.ReferenceIndex) []repository.Reference {
+				return x.SegmentReferences("s", repository.ReferenceTypeLaunch)
+			},
+			expect: []repository.Reference{runningLaunch},
+		},
+		{
+			name:   "unused segment",
+			refs:   func(x *repository.ReferenceIndex) []repository.Reference { return x.SegmentReferences("unused", "") },
+			expect: []repository.Reference{},
+		},
+	}
+
+	x := repository.NewReferenceIndex()
+	assert.False(t, x.Built())
+	x.Build(bundle)
+	assert.True(t, x.Built())
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			asst.Equal(c.expect, c.refs(x))
+		})
+	}
+}
+
+func Test_ReferenceIndex_Build(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	var nilIndex *repository.ReferenceIndex
+	asst.False(nilIndex.Built())
+
+	x := repository.NewReferenceIndex()
+	x.Build(&models.Bundle{
+		Experiments: []models.Experiment{{Project: "p", Name: "e", Segment: "s"}},
+		Segments:    []models.Segment{{Name: "s"}},
+	})
+	asst.True(x.HasSegment("s"))
+	asst.Len(x.SegmentReferences("s", ""), 1)
+
+	// the index is replaced by the next build
+	x.Build(&models.Bundle{Segments: []models.Segment{{Name: "t"}}})
+	asst.False(x.HasSegment("s"))
+	asst.True(x.HasSegment("t"))
+	asst.Empty(x.SegmentReferences("s", ""))
+}
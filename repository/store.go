@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/michimani/evidentlylocal/internal"
+)
+
+// ErrModifiedExternally is returned when a file is changed by another writer (e.g. an editor)
+// between reading and writing it. The file is left as the other writer changed it.
+var ErrModifiedExternally = errors.New("file is modified externally")
+
+// FileStore writes JSON files of a data directory. import subcommand writes resources with it.
+//
+// Files are written to a temporary file in the same directory and renamed, so that readers (e.g. the server)
+// never see a partially written file even if the process crashes. Writes are serialized per project
+// (and for segments) with a lock in the process and a lock file (.lock) that other writers also lock,
+// e.g. two import subcommands at once. Readers do not lock. Modify fails with ErrModifiedExternally
+// instead of overwriting changes made after the file was read by writers that do not lock, e.g. editors.
+type FileStore struct {
+	dataDir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// fileStores are stores of data directories, so that all writers of a data directory in the process share locks.
+var fileStores sync.Map
+
+// OpenFileStore returns the store of the data directory. The same store is returned for the same directory.
+func OpenFileStore(dataDir string) (*FileStore, error) {
+	if len(dataDir) == 0 {
+		return nil, errors.New("dataDir is empty")
+	}
+
+	abs, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s, _ := fileStores.LoadOrStore(abs, &FileStore{
+		dataDir: abs,
+		locks:   map[string]*sync.Mutex{},
+	})
+
+	return s.(*FileStore), nil
+}
+
+// Modify reads the JSON file at path into v, calls fn to change v and writes v back.
+// exists is false if the file does not exist yet, and v is left as it is.
+// Nothing is written if fn returns an error, or if the file is changed by others while fn is called.
+func (s *FileStore) Modify(path string, v any, fn func(exists bool) error) error {
+	unlock, err := s.lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	full := filepath.Join(s.dataDir, path)
+
+	base, err := readFileIfExists(full)
+	if err != nil {
+		return err
+	}
+
+	if base != nil {
+		if err := json.Unmarshal(base, v); err != nil {
+			return fmt.Errorf("failed to unmarshal %s: %w", full, err)
+		}
+	}
+
+	if err := fn(base != nil); err != nil {
+		return err
+	}
+
+	b, err := marshalJSONFile(v)
+	if err != nil {
+		return err
+	}
+
+	current, err := readFileIfExists(full)
+	if err != nil {
+		return err
+	}
+
+	if (base == nil) != (current == nil) || !bytes.Equal(base, current) {
+		return fmt.Errorf("%w: %s", ErrModifiedExternally, full)
+	}
+
+	return internal.WriteFileAtomic(full, b)
+}
+
+// lockFileName is the name of the lock file in the directory of a project, and in the segments directory.
+const lockFileName = ".lock"
+
+// lock locks the project of the path, or segments. It returns the function to unlock.
+func (s *FileStore) lock(path string) (func(), error) {
+	key, err := lockKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[key] = l
+	}
+	s.mu.Unlock()
+
+	l.Lock()
+
+	unlockFile, err := lockFile(filepath.Join(s.dataDir, filepath.FromSlash(key), lockFileName))
+	if err != nil {
+		l.Unlock()
+		return nil, fmt.Errorf("failed to lock %s: %w", key, err)
+	}
+
+	return func() {
+		unlockFile()
+		l.Unlock()
+	}, nil
+}
+
+// lockKey returns the key of the lock of the path, e.g. projects/<project> or segments.
+func lockKey(path string) (string, error) {
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("path must be in the data directory: %s", path)
+	}
+
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == projectsDirName:
+		return parts[0] + "/" + parts[1], nil
+	case len(parts) == 2 && parts[0] == segmentsDirName:
+		return parts[0], nil
+	default:
+		return "", fmt.Errorf("path is not a resource file: %s", path)
+	}
+}
+
+func marshalJSONFile(v any) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// readFileIfExists returns nil if the file does not exist. An empty file is returned as an empty slice.
+func readFileIfExists(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		b = []byte{}
+	}
+
+	return b, nil
+}
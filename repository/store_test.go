@@ -0,0 +1,182 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileStore_Modify(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join("projects", "test-project", "features", "test-feature.json")
+
+	cases := []struct {
+		name        string
+		initial     *models.Feature
+		fn          func(dataDir string, f *models.Feature, exists bool) error
+		wantErr     error
+		expected    *models.Feature
+		expectedRaw string
+	}{
+		{
+			name: "create",
+			fn: func(_ string, f *models.Feature, exists bool) error {
+				if exists {
+					return errors.New("unexpected file")
+				}
+				f.Name = "test-feature"
+				return nil
+			},
+			expected: &models.Feature{Name: "test-feature"},
+		},
+		{
+			name:    "update",
+			initial: &models.Feature{Name: "test-feature", DefaultVariation: "False"},
+			fn: func(_ string, f *models.Feature, exists bool) error {
+				f.DefaultVariation = "True"
+				return nil
+			},
+			expected: &models.Feature{Name: "test-feature", DefaultVariation: "True"},
+		},
+		{
+			name:    "error of fn",
+			initial: &models.Feature{Name: "test-feature", DefaultVariation: "False"},
+			fn: func(_ string, f *models.Feature, exists bool) error {
+				f.DefaultVariation = "True"
+				return errors.New("rejected")
+			},
+			wantErr:  errors.New("rejected"),
+			expected: &models.Feature{Name: "test-feature", DefaultVariation: "False"},
+		},
+		{
+			name:    "modified externally",
+			initial: &models.Feature{Name: "test-feature", DefaultVariation: "False"},
+			fn: func(dataDir string, f *models.Feature, exists bool) error {
+				f.DefaultVariation = "True"
+				return os.WriteFile(filepath.Join(dataDir, path), []byte(`{"name":"edited"}`), 0o644)
+			},
+			wantErr:     repository.ErrModifiedExternally,
+			expectedRaw: `{"name":"edited"}`,
+		},
+		{
+			name: "created externally",
+			fn: func(dataDir string, f *models.Feature, exists bool) error {
+				f.Name = "test-feature"
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dataDir, path)), 0o755); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dataDir, path), []byte(`{"name":"edited"}`), 0o644)
+			},
+			wantErr:     repository.ErrModifiedExternally,
+			expectedRaw: `{"name":"edited"}`,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(tt *testing.T) {
+			tt.Parallel()
+			asst := assert.New(tt)
+
+			dataDir := tt.TempDir()
+			store, err := repository.OpenFileStore(dataDir)
+			asst.NoError(err)
+
+			if c.initial != nil {
+				b, err := json.Marshal(c.initial)
+				asst.NoError(err)
+				asst.NoError(os.MkdirAll(filepath.Dir(filepath.Join(dataDir, path)), 0o755))
+				asst.NoError(os.WriteFile(filepath.Join(dataDir, path), b, 0o644))
+			}
+
+			f := &models.Feature{}
+			err = store.Modify(path, f, func(exists bool) error {
+				return c.fn(dataDir, f, exists)
+			})
+			if c.wantErr != nil {
+				asst.Error(err)
+				if errors.Is(c.wantErr, repository.ErrModifiedExternally) {
+					asst.ErrorIs(err, repository.ErrModifiedExternally)
+				}
+			} else {
+				asst.NoError(err)
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dataDir, path))
+			asst.NoError(err)
+			if len(c.expectedRaw) > 0 {
+				asst.Equal(c.expectedRaw, string(raw))
+			}
+			if c.expected != nil {
+				got := &models.Feature{}
+				asst.NoError(json.Unmarshal(raw, got))
+				asst.Equal(c.expected, got)
+			}
+
+			// no temporary files are left
+			files, err := os.ReadDir(filepath.Dir(filepath.Join(dataDir, path)))
+			asst.NoError(err)
+			asst.Len(files, 1)
+		})
+	}
+}
+
+func Test_FileStore_ConcurrentModify(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	dataDir := t.TempDir()
+	path := filepath.Join("projects", "test-project", "launches", "test-launch.json")
+
+	// stores of the same data directory share locks
+	stores := make([]*repository.FileStore, 2)
+	for i := range stores {
+		s, err := repository.OpenFileStore(dataDir)
+		asst.NoError(err)
+		stores[i] = s
+	}
+	asst.Same(stores[0], stores[1])
+
+	const n = 50
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(s *repository.FileStore) {
+			defer wg.Done()
+			l := &models.Launch{}
+			asst.NoError(s.Modify(path, l, func(bool) error {
+				l.Groups = append(l.Groups, models.LaunchGroup{})
+				return nil
+			}))
+		}(stores[i%2])
+	}
+	wg.Wait()
+
+	raw, err := os.ReadFile(filepath.Join(dataDir, path))
+	asst.NoError(err)
+	got := &models.Launch{}
+	asst.NoError(json.Unmarshal(raw, got))
+	asst.Len(got.Groups, n)
+}
+
+func Test_FileStore_InvalidPath(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	store, _ := repository.OpenFileStore(dataDir)
+
+	for _, path := range []string{"../outside.json", "/abs.json", "projects/test-project", "segments/a/b.json", "other.json"} {
+		t.Run(path, func(tt *testing.T) {
+			asst := assert.New(tt)
+			asst.Error(store.Modify(path, &models.Segment{}, func(bool) error { return nil }))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+//go:build unix
+
+package repository_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_FileStore_LockFile checks that writes wait for the lock file locked by another process.
+// Another open file description of the lock file is locked, in the same way as another process does.
+func Test_FileStore_LockFile(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	dataDir := t.TempDir()
+	lockPath := filepath.Join(dataDir, "projects", "test-project", ".lock")
+	asst.NoError(os.MkdirAll(filepath.Dir(lockPath), 0o755))
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	asst.NoError(err)
+	defer f.Close()
+	asst.NoError(syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+
+	store, err := repository.OpenFileStore(dataDir)
+	asst.NoError(err)
+
+	done := make(chan error, 1)
+	go func() {
+		p := &models.Project{}
+		done <- store.Modify(filepath.Join("projects", "test-project", "project.json"), p, func(bool) error {
+			p.Name = "test-project"
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+		asst.Fail("the file is written while the lock file is locked")
+	case <-time.After(100 * time.Millisecond):
+		// waiting for the lock
+	}
+
+	asst.NoError(syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+
+	select {
+	case err := <-done:
+		asst.NoError(err)
+	case <-time.After(5 * time.Second):
+		asst.Fail("the file is not written after the lock file is unlocked")
+	}
+
+	asst.FileExists(filepath.Join(dataDir, "projects", "test-project", "project.json"))
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	_ Watcher = (*FeatureRepositoryWithJSONFile)(nil)
+	_ Watcher = (*FeatureRepositoryWithLayers)(nil)
+)
+
+// Watcher is implemented by repositories whose files can be changed while the server is running.
+// Repositories of a bundle are not changed after they are created.
+type Watcher interface {
+	// Fingerprint returns a value that changes when a file in the repository is added, removed or modified.
+	Fingerprint() (string, error)
+}
+
+// Fingerprint returns a hash of paths, sizes and modification times of all files in the data directory.
+func (r *FeatureRepositoryWithJSONFile) Fingerprint() (string, error) {
+	if r == nil {
+		return "", errors.New("FeatureRepositoryWithJSONFile is nil")
+	}
+
+	h := fnv.New64a()
+	err := filepath.WalkDir(r.dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// lock files are created by writers, and do not change resources
+		if d.Name() == lockFileName {
+			return nil
+		}
+
+		// added and removed files are detected by their paths, so times of directories are not used
+		if d.IsDir() {
+			fmt.Fprintf(h, "%s\x00", path)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
+// Fingerprint returns a value that changes when a file in any layer is changed.
+func (r *FeatureRepositoryWithLayers) Fingerprint() (string, error) {
+	if r == nil {
+		return "", errors.New("FeatureRepositoryWithLayers is nil")
+	}
+
+	h := fnv.New64a()
+	for _, layer := range r.layers {
+		fp, err := layer.Fingerprint()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", fp)
+	}
+
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
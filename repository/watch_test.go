@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Fingerprint(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	dataDir := t.TempDir()
+	featuresDir := filepath.Join(dataDir, "projects", "p", "features")
+	featureFile := filepath.Join(featuresDir, "f.json")
+	asst.NoError(os.MkdirAll(featuresDir, 0o755))
+	asst.NoError(os.WriteFile(featureFile, []byte(`{"defaultVariation":"a"}`), 0o644))
+
+	repo, _ := repository.NewFeatureRepositoryWithJSONFile(dataDir, testLogger)
+	layers, _ := repository.NewFeatureRepositoryWithLayers([]string{"../testdata", dataDir}, testLogger)
+
+	cases := []struct {
+		name    string
+		change  func() error
+		changed bool
+	}{
+		{
+			name:    "not changed",
+			change:  func() error { return nil },
+			changed: false,
+		},
+		{
+			name:    "lock file is created",
+			change:  func() error { return os.WriteFile(filepath.Join(dataDir, "projects", "p", ".lock"), nil, 0o644) },
+			changed: false,
+		},
+		{
+			name:    "file is modified",
+			change:  func() error { return os.WriteFile(featureFile, []byte(`{"defaultVariation":"b"}`), 0o644) },
+			changed: true,
+		},
+		{
+			name: "file is modified without changing the size",
+			change: func() error {
+				return os.Chtimes(featureFile, time.Time{}, time.Now().Add(time.Hour))
+			},
+			changed: true,
+		},
+		{
+			name:    "file is added",
+			change:  func() error { return os.WriteFile(filepath.Join(featuresDir, "g.json"), []byte(`{}`), 0o644) },
+			changed: true,
+		},
+		{
+			name:    "file is removed",
+			change:  func() error { return os.Remove(featureFile) },
+			changed: true,
+		},
+	}
+
+	watchers := []repository.Watcher{repo, layers}
+	for _, c := range cases {
+		// cases change the same directory in order
+		before := make([]string, len(watchers))
+		for i, w := range watchers {
+			fp, err := w.Fingerprint()
+			asst.NoError(err, c.name)
+			before[i] = fp
+		}
+
+		asst.NoError(c.change(), c.name)
+
+		for i, w := range watchers {
+			after, err := w.Fingerprint()
+			asst.NoError(err, c.name)
+
+			if c.changed {
+				asst.NotEqual(before[i], after, c.name)
+			} else {
+				asst.Equal(before[i], after, c.name)
+			}
+		}
+	}
+
+	// a data directory that does not exist has no files
+	notExists, _ := repository.NewFeatureRepositoryWithJSONFile(filepath.Join(dataDir, "not-exists"), testLogger)
+	_, err := notExists.Fingerprint()
+	asst.NoError(err)
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unmarshalYAML unmarshals YAML data into v via JSON,
+// so that the JSON field names of models are also used for YAML files.
+func unmarshalYAML(data []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(normalizeYAMLValue(doc))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bytes, v)
+}
+
+// marshalYAML marshals v into YAML via JSON, so that the JSON field names of models are also used for YAML files.
+func marshalYAML(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are decoded as json.Number to keep large long values as they are
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var doc any
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	e := yaml.NewEncoder(buf)
+	e.SetIndent(2)
+	if err := e.Encode(jsonNumbersToYAMLValues(doc)); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// jsonNumbersToYAMLValues converts json.Number values into int64 or float64,
+// because yaml.v3 marshals json.Number as a string.
+func jsonNumbersToYAMLValues(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, vv := range t {
+			t[k] = jsonNumbersToYAMLValues(vv)
+		}
+		return t
+	case []any:
+		for i, vv := range t {
+			t[i] = jsonNumbersToYAMLValues(vv)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	default:
+		return v
+	}
+}
+
+// normalizeYAMLValue converts mappings that have non-string keys (e.g. numeric entity IDs)
+// into map[string]any, because they cannot be marshaled into JSON.
+func normalizeYAMLValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, vv := range t {
+			t[k] = normalizeYAMLValue(vv)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, vv := range t {
+			m[fmt.Sprint(k)] = normalizeYAMLValue(vv)
+		}
+		return m
+	case []any:
+		for i, vv := range t {
+			t[i] = normalizeYAMLValue(vv)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -1,24 +1,465 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/components"
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/fault"
 	"github.com/michimani/evidentlylocal/handler"
+	"github.com/michimani/evidentlylocal/internal"
 	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/metrics"
+	"github.com/michimani/evidentlylocal/models"
+	"github.com/michimani/evidentlylocal/proxy"
 	"github.com/michimani/evidentlylocal/repository"
 )
 
-func Start(port string, l logger.Logger, repoInstance repository.FeatureRepository) {
-	repository.SetFeatureRepositoryInstance(repoInstance)
+// ShutdownTimeout is the time to wait for in-flight requests when Run shuts down the server.
+const ShutdownTimeout = 10 * time.Second
 
-	ph := handler.NewProjectHandler(l)
+// ReloadInterval is the interval to check changes of files to reload the repository.
+const ReloadInterval = time.Second
 
-	http.HandleFunc("/projects/", ph.Projects)
+// ShutdownHook is called when the server shuts down, after in-flight requests are drained.
+// It is used to flush writers of events or audit logs.
+type ShutdownHook func(ctx context.Context) error
 
-	l.Info(fmt.Sprintf("Server started on port %s", port))
-	err := http.ListenAndServe(":"+port, nil)
+// Server is an Evidently-Local server. Each server has its own mux and repository,
+// so multiple servers can run in one process.
+// A server cannot be started again after it is shut down. Create a new one instead.
+type Server struct {
+	cfg        *config.Config
+	l          logger.Logger
+	handler    http.Handler
+	httpServer *http.Server
+	health     *handler.HealthHandler
+	metrics    *metrics.Metrics
+	auditLog   *audit.Log
+	coverage   *coverage.Tracker
+	faults     *fault.Injector
+	repo       repository.FeatureRepository
+	index      *components.Index
+	references *repository.ReferenceIndex
+	loadOnce   sync.Once
+	stopOnce   sync.Once
+	stop       chan struct{}
+
+	mu       sync.Mutex
+	listener net.Listener
+	hooks    []ShutdownHook
+	done     chan error
+}
+
+func New(cfg *config.Config, l logger.Logger, repo repository.FeatureRepository) (*Server, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
+	}
+
+	if l == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	if repo == nil {
+		return nil, errors.New("repository is nil")
+	}
+
+	al, err := audit.New(audit.Options{
+		File:       cfg.Audit.File,
+		MaxSizeMB:  cfg.Audit.MaxSizeMB,
+		MaxBackups: cfg.Audit.MaxBackups,
+		BufferSize: cfg.Audit.BufferSize,
+		Logger:     l,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	ct := coverage.NewTracker(repo)
+
+	inj, err := fault.NewInjector(cfg.Faults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid faults: %w", err)
+	}
+
+	m := metrics.New()
+	var ix *components.Index
+	if cfg.Evaluation.Index {
+		ix = components.NewIndex()
+	}
+
+	ph := handler.NewProjectHandler(l, repo, handler.ProjectHandlerOptions{
+		Metrics:  m,
+		Recorder: audit.Recorders(al, ct),
+		Index:    ix,
+	})
+	ah := handler.NewAdminHandler(l, repo, al)
+	ch := handler.NewCoverageHandler(l, ct)
+	fh := handler.NewFaultHandler(l, inj)
+	refs := repository.NewReferenceIndex()
+	sh := handler.NewSegmentHandler(l, repo, refs, internal.ARNs{Region: cfg.Region, AccountID: cfg.AccountID})
+
+	projects, err := projectsHandler(cfg, l, ph)
+	if err != nil {
+		return nil, err
+	}
+	hh := handler.NewHealthHandler(l, repo, m, cfg.Quotas)
+
+	mux := http.NewServeMux()
+	mux.Handle("/projects/", m.Middleware(handler.OperationName, handler.Trace(handler.InjectFaults(l, inj, projects))))
+	mux.Handle("/segments/", m.Middleware(handler.OperationName, handler.Trace(handler.InjectFaults(l, inj, http.HandlerFunc(sh.Segments)))))
+	mux.HandleFunc("/_admin/snapshot", ah.Snapshot)
+	mux.HandleFunc("/_admin/layers", ah.Layers)
+	mux.HandleFunc("/_admin/evaluations", ah.Evaluations)
+	mux.HandleFunc("/_admin/coverage", ch.Report)
+	mux.HandleFunc("/_admin/faults", fh.Faults)
+	mux.HandleFunc("/_health", hh.Health)
+	mux.HandleFunc("/_ready", hh.Ready)
+	mux.HandleFunc("/_version", hh.Version)
+	mux.Handle("/metrics", m.Handler())
+
+	// all requests get a request ID and an access log
+	h := handler.AccessLog(l, mux)
+
+	// hooks are called after in-flight evaluations are recorded
+	hooks := []ShutdownHook{al.Close}
+	if len(cfg.Coverage.ReportDir) > 0 {
+		hooks = append(hooks, func(ctx context.Context) error {
+			return writeCoverageReport(l, ct, cfg.Coverage.ReportDir)
+		})
+	}
+
+	return &Server{
+		cfg:     cfg,
+		l:       l,
+		handler: h,
+		httpServer: &http.Server{
+			Handler:           h,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		health:     hh,
+		metrics:    m,
+		auditLog:   al,
+		coverage:   ct,
+		faults:     inj,
+		repo:       repo,
+		index:      ix,
+		references: refs,
+		stop:       make(chan struct{}),
+		hooks:      hooks,
+	}, nil
+}
+
+// projectsHandler returns the handler of /projects/, that is the proxy in the record or replay mode.
+func projectsHandler(cfg *config.Config, l logger.Logger, ph *handler.ProjectHandler) (http.Handler, error) {
+	if len(cfg.Proxy.Mode) == 0 {
+		return http.HandlerFunc(ph.Projects), nil
+	}
+
+	cassette, err := proxy.LoadCassette(cfg.Proxy.Cassette, cfg.Proxy.Mode == proxy.ModeReplay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette: %w", err)
+	}
+
+	var upstream *proxy.Upstream
+	if cfg.Proxy.Mode == proxy.ModeRecord {
+		// credentials in the environment are used to sign requests to AWS
+		upstream, err = proxy.NewUpstream(cfg.Proxy.Upstream, cfg.Region, proxy.EnvCredentials(os.Getenv))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pxh, err := handler.NewProxyHandler(l, cfg.Proxy.Mode, cassette, upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("Proxy mode is enabled", "mode", cfg.Proxy.Mode, "upstream", cfg.Proxy.Upstream, "cassette", cfg.Proxy.Cassette)
+
+	return http.HandlerFunc(pxh.Projects), nil
+}
+
+func writeCoverageReport(l logger.Logger, ct *coverage.Tracker, dir string) error {
+	report, err := ct.Report()
+	if err != nil {
+		return fmt.Errorf("failed to generate coverage report: %w", err)
+	}
+
+	if err := coverage.WriteReport(dir, report); err != nil {
+		return fmt.Errorf("failed to write coverage report: %w", err)
+	}
+
+	l.Info("Wrote coverage report", "dir", dir,
+		"evaluatedFeatures", report.Summary.EvaluatedFeatures, "features", report.Summary.Features,
+		"servedVariations", report.Summary.ServedVariations, "variations", report.Summary.Variations,
+		"hitOverrides", report.Summary.HitOverrides, "overrides", report.Summary.Overrides)
+
+	return nil
+}
+
+// Handler returns the handler of the server, to serve it with another server (e.g. httptest.Server).
+// It starts loading the repository for readiness in the same way as Start.
+func (s *Server) Handler() http.Handler {
+	s.load()
+	return s.handler
+}
+
+// load loads all resources in the repository in the background. /_ready fails until it is done.
+// Then the repository is loaded again when files in it are changed.
+func (s *Server) load() {
+	s.loadOnce.Do(func() {
+		go func() {
+			fingerprint := s.fingerprint()
+			s.reload()
+			s.watch(fingerprint)
+		}()
+	})
+}
+
+// reload is the only path to load the repository. It rebuilds the evaluation index and the index of references
+// from a snapshot, then checks files and quotas for /_ready and metrics.
+// The indexes are built before, so that the server is ready after requests stop reading files.
+func (s *Server) reload() {
+	bundle, err := s.repo.Snapshot()
+	if err != nil {
+		s.l.Error("Failed to get snapshot of repository", err)
+	} else {
+		s.references.Build(bundle)
+		s.buildIndex(bundle)
+	}
+
+	s.health.Load()
+}
+
+// watch checks the fingerprint of the repository at ReloadInterval, and reloads the repository if it is changed,
+// until the server is shut down. Evaluations use the previous index until it is rebuilt.
+func (s *Server) watch(fingerprint string) {
+	if _, ok := s.repo.(repository.Watcher); !ok {
+		return
+	}
+
+	t := time.NewTicker(ReloadInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			// noop
+		}
+
+		fp := s.fingerprint()
+		if fp == fingerprint {
+			continue
+		}
+
+		fingerprint = fp
+		s.l.Info("Files are changed, reloading repository")
+		s.reload()
+	}
+}
+
+// fingerprint returns the fingerprint of the repository, or an empty string if files of the repository are not watched.
+func (s *Server) fingerprint() string {
+	w, ok := s.repo.(repository.Watcher)
+	if !ok {
+		return ""
+	}
+
+	fp, err := w.Fingerprint()
+	if err != nil {
+		s.l.Error("Failed to check changes of files", err)
+		return ""
+	}
+
+	return fp
+}
+
+// buildIndex compiles all features for evaluations, if the evaluation index is enabled.
+// Features are read from the repository for each evaluation until it is built for the first time.
+func (s *Server) buildIndex(bundle *models.Bundle) {
+	if s.index == nil {
+		return
+	}
+
+	if err := s.index.Build(bundle); err != nil {
+		s.l.Error("Some features are not in evaluation index", err)
+	}
+
+	s.l.Info("Built evaluation index", "features", len(bundle.Features))
+}
+
+// Metrics returns the metrics of the server, e.g. to register collectors of an application that embeds the server.
+func (s *Server) Metrics() *metrics.Metrics {
+	return s.metrics
+}
+
+// AuditLog returns the audit log of evaluations served by the server.
+func (s *Server) AuditLog() *audit.Log {
+	return s.auditLog
+}
+
+// Coverage returns the coverage of features served by the server.
+func (s *Server) Coverage() (*coverage.Report, error) {
+	return s.coverage.Report()
+}
+
+// Faults returns the fault injector of the server, to change rules of fault injection in tests.
+func (s *Server) Faults() *fault.Injector {
+	return s.faults
+}
+
+// AddShutdownHook adds a hook that is called in Shutdown.
+func (s *Server) AddShutdownHook(hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// Start listens on the configured address and serves in the background.
+// It returns after the listener is ready, so that requests can be sent right after it.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return errors.New("server is already started")
+	}
+
+	ln, err := net.Listen("tcp", s.cfg.ListenAddress)
 	if err != nil {
-		panic(err)
+		return err
 	}
+
+	s.listener = ln
+	s.done = make(chan error, 1)
+
+	go func() {
+		var err error
+		if s.cfg.TLSEnabled() {
+			err = s.httpServer.ServeTLS(ln, s.cfg.TLS.CertFile, s.cfg.TLS.KeyFile)
+		} else {
+			err = s.httpServer.Serve(ln)
+		}
+
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		s.done <- err
+		close(s.done)
+	}()
+
+	s.l.Info("Server started", "url", s.url())
+
+	s.load()
+
+	return nil
+}
+
+// Addr returns the address that the server listens on. It is useful when the port is 0.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.listener.Addr().String()
+}
+
+// URL returns the base URL of the server, or an empty string if it is not started.
+func (s *Server) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.url()
+}
+
+func (s *Server) url() string {
+	addr := s.listener.Addr().String()
+	if s.cfg.TLSEnabled() {
+		return "https://" + addr
+	}
+
+	return "http://" + addr
+}
+
+// Done returns a channel that receives the error of serving when the server stops.
+// The error is nil if the server is shut down. It returns nil if the server is not started.
+func (s *Server) Done() <-chan error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.done
+}
+
+// Shutdown stops accepting new requests, waits for in-flight requests until ctx is done,
+// then calls shutdown hooks.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.listener != nil
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	errs := []error{}
+	if started {
+		s.l.Info("Shutting down server")
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run starts the server, and shuts it down when ctx is done (e.g. by SIGTERM or SIGINT).
+// It returns the error of serving if the server stops by itself.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-s.Done():
+		return err
+	case <-ctx.Done():
+		// noop
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	s.l.Info("Server stopped")
+
+	return <-s.Done()
 }
@@ -0,0 +1,373 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michimani/evidentlylocal/audit"
+	"github.com/michimani/evidentlylocal/config"
+	"github.com/michimani/evidentlylocal/coverage"
+	"github.com/michimani/evidentlylocal/logger"
+	"github.com/michimani/evidentlylocal/quota"
+	"github.com/michimani/evidentlylocal/repository"
+	"github.com/michimani/evidentlylocal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.Default()
+	cfg.ListenAddress = "127.0.0.1:0"
+	return cfg
+}
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	testRepo, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata", testLogger)
+
+	cases := []struct {
+		name    string
+		cfg     *config.Config
+		l       logger.Logger
+		repo    repository.FeatureRepository
+		wantErr bool
+	}{
+		{
+			name:    "config is nil",
+			cfg:     nil,
+			l:       testLogger,
+			repo:    testRepo,
+			wantErr: true,
+		},
+		{
+			name:    "logger is nil",
+			cfg:     newTestConfig(),
+			l:       nil,
+			repo:    testRepo,
+			wantErr: true,
+		},
+		{
+			name:    "repository is nil",
+			cfg:     newTestConfig(),
+			l:       testLogger,
+			repo:    nil,
+			wantErr: true,
+		},
+		{
+			name: "cassette not found in the replay mode",
+			cfg: func() *config.Config {
+				cfg := newTestConfig()
+				cfg.Proxy = config.Proxy{Mode: config.ProxyModeReplay, Cassette: "../testdata/not-exists.json"}
+				return cfg
+			}(),
+			l:       testLogger,
+			repo:    testRepo,
+			wantErr: true,
+		},
+		{
+			name: "record mode",
+			cfg: func() *config.Config {
+				cfg := newTestConfig()
+				cfg.Proxy = config.Proxy{Mode: config.ProxyModeRecord, Upstream: "http://localhost:2306", Cassette: filepath.Join(t.TempDir(), "cassette.json")}
+				return cfg
+			}(),
+			l:       testLogger,
+			repo:    testRepo,
+			wantErr: false,
+		},
+		{
+			name:    "success",
+			cfg:     newTestConfig(),
+			l:       testLogger,
+			repo:    testRepo,
+			wantErr: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(tt *testing.T) {
+			asst := assert.New(tt)
+			got, err := server.New(c.cfg, c.l, c.repo)
+			if c.wantErr {
+				asst.Nil(got)
+				asst.Error(err)
+				return
+			}
+
+			asst.NoError(err)
+			asst.NotNil(got)
+		})
+	}
+}
+
+func Test_Server_StartAndShutdown(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	repo1, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata", testLogger)
+	repo2, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+
+	asst := assert.New(t)
+
+	// start and stop servers repeatedly, and run two servers at the same time
+	for i := 0; i < 3; i++ {
+		s1, err := server.New(newTestConfig(), testLogger, repo1)
+		asst.NoError(err)
+		s2, err := server.New(newTestConfig(), testLogger, repo2)
+		asst.NoError(err)
+
+		asst.Empty(s1.URL())
+		asst.NoError(s1.Start())
+		asst.NoError(s2.Start())
+		asst.Error(s1.Start(), "already started")
+		asst.NotEqual(s1.Addr(), s2.Addr())
+
+		flushed := false
+		s1.AddShutdownHook(func(ctx context.Context) error {
+			flushed = true
+			return nil
+		})
+		s2.AddShutdownHook(func(ctx context.Context) error {
+			return errors.New("failed to flush")
+		})
+
+		// each server has its own repository
+		asst.Equal(http.StatusOK, evaluate(t, s1.URL(), "has-yaml-features-project", "yaml-feature-1"))
+		asst.Equal(http.StatusNotFound, evaluate(t, s2.URL(), "has-yaml-features-project", "yaml-feature-1"))
+
+		// testdata has invalid files
+		asst.Equal(http.StatusServiceUnavailable, ready(t, s1.URL()))
+		asst.Equal(http.StatusOK, ready(t, s2.URL()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		asst.NoError(s1.Shutdown(ctx))
+		asst.Error(s2.Shutdown(ctx))
+		cancel()
+
+		asst.True(flushed)
+		asst.NoError(<-s1.Done())
+		asst.NoError(<-s2.Done())
+	}
+}
+
+func Test_Server_Run(t *testing.T) {
+	t.Parallel()
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	repo, _ := repository.NewFeatureRepositoryWithJSONFile("../testdata", testLogger)
+
+	asst := assert.New(t)
+
+	cfg := newTestConfig()
+	cfg.Audit.File = filepath.Join(t.TempDir(), "evaluations.jsonl")
+	cfg.Coverage.ReportDir = filepath.Join(t.TempDir(), "coverage")
+
+	s, err := server.New(cfg, testLogger, repo)
+	asst.NoError(err)
+
+	flushed := make(chan struct{})
+	s.AddShutdownHook(func(ctx context.Context) error {
+		close(flushed)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	// wait until the server is started
+	for i := 0; len(s.URL()) == 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	asst.Equal(http.StatusOK, evaluate(t, s.URL(), "test-project", "test-feature-1"))
+
+	res, err := http.Get(s.URL() + "/metrics")
+	if asst.NoError(err) {
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		asst.Contains(string(b), `evidently_local_evaluations_total{feature="test-feature-1",project="test-project",reason="DEFAULT",variation="False"} 1`)
+		asst.Contains(string(b), `evidently_local_api_calls_total{operation="EvaluateFeature",status="200"} 1`)
+	}
+
+	cancel()
+	asst.NoError(<-runErr)
+	<-flushed
+
+	// the audit log is written to the file
+	b, err := os.ReadFile(cfg.Audit.File)
+	asst.NoError(err)
+	asst.Contains(string(b), `"project":"test-project","feature":"test-feature-1","entityId":"test","variation":"False","reason":"DEFAULT"}`)
+	asst.Len(s.AuditLog().Query(audit.Query{Feature: "test-feature-1"}), 1)
+
+	// the coverage report is written at shutdown
+	b, err = os.ReadFile(filepath.Join(cfg.Coverage.ReportDir, coverage.TextReportFileName))
+	asst.NoError(err)
+	asst.Contains(string(b), "Flag coverage: 1/4 features evaluated")
+	asst.FileExists(filepath.Join(cfg.Coverage.ReportDir, coverage.JSONReportFileName))
+}
+
+func Test_Server_EvaluationIndex(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	bundleRepo, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	bundle, err := bundleRepo.Snapshot()
+	asst.NoError(err)
+
+	dataDir := t.TempDir()
+	asst.NoError(repository.WriteBundleToDataDir(dataDir, bundle))
+	// features are evaluated by the directory and the file name, even if the file has another project and name
+	renamed, err := os.ReadFile(filepath.Join(dataDir, "projects", "test-project", "features", "test-feature-1.json"))
+	asst.NoError(err)
+	feature := map[string]any{}
+	asst.NoError(json.Unmarshal(renamed, &feature))
+	feature["project"] = "other-project"
+	renamed, err = json.Marshal(feature)
+	asst.NoError(err)
+	asst.NoError(os.MkdirAll(filepath.Join(dataDir, "projects", "other-project", "features"), 0o755))
+	asst.NoError(os.WriteFile(filepath.Join(dataDir, "projects", "other-project", "features", "renamed.json"), renamed, 0o644))
+	repo, _ := repository.NewFeatureRepositoryWithJSONFile(dataDir, testLogger)
+
+	cfg := newTestConfig()
+	cfg.Evaluation.Index = true
+	indexed, err := server.New(cfg, testLogger, repo)
+	asst.NoError(err)
+	notIndexed, err := server.New(newTestConfig(), testLogger, repo)
+	asst.NoError(err)
+
+	for _, s := range []*server.Server{indexed, notIndexed} {
+		asst.NoError(s.Start())
+		asst.Equal(http.StatusOK, ready(t, s.URL()))
+		asst.Equal(http.StatusOK, evaluate(t, s.URL(), "test-project", "test-feature-1"))
+		asst.Equal(http.StatusOK, evaluate(t, s.URL(), "other-project", "renamed"))
+		asst.Equal(http.StatusNotFound, evaluate(t, s.URL(), "other-project", "test-feature-1"))
+	}
+
+	// changes of files are applied to the next evaluation without the index, and after the reload with the index
+	asst.NoError(os.Remove(filepath.Join(dataDir, "projects", "test-project", "features", "test-feature-1.json")))
+	asst.NoError(os.WriteFile(filepath.Join(dataDir, "projects", "other-project", "features", "added.json"), renamed, 0o644))
+	asst.Equal(http.StatusNotFound, evaluate(t, notIndexed.URL(), "test-project", "test-feature-1"))
+	asst.Equal(http.StatusOK, evaluate(t, notIndexed.URL(), "other-project", "added"))
+	asst.Eventually(func() bool {
+		return evaluate(t, indexed.URL(), "test-project", "test-feature-1") == http.StatusNotFound &&
+			evaluate(t, indexed.URL(), "other-project", "added") == http.StatusOK
+	}, 5*server.ReloadInterval, 100*time.Millisecond)
+
+	// readiness and metrics are updated by the same reload
+	asst.NoError(os.WriteFile(filepath.Join(dataDir, "projects", "other-project", "features", "invalid.json"), []byte("{"), 0o644))
+	for _, s := range []*server.Server{indexed, notIndexed} {
+		asst.Eventually(func() bool {
+			return ready(t, s.URL()) == http.StatusServiceUnavailable
+		}, 5*server.ReloadInterval, 100*time.Millisecond)
+		asst.Contains(scrape(t, s.URL()), "evidently_local_repository_load_errors 1")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	asst.NoError(indexed.Shutdown(ctx))
+	asst.NoError(notIndexed.Shutdown(ctx))
+}
+
+func Test_Server_QuotaReload(t *testing.T) {
+	t.Parallel()
+	asst := assert.New(t)
+
+	testLogger, _ := logger.NewEvidentlyLocalLogger(io.Discard)
+	bundleRepo, _ := repository.NewFeatureRepositoryWithBundleFile("../testdata/bundles/test-bundle.yaml", testLogger)
+	bundle, err := bundleRepo.Snapshot()
+	asst.NoError(err)
+
+	dataDir := t.TempDir()
+	asst.NoError(repository.WriteBundleToDataDir(dataDir, bundle))
+	repo, _ := repository.NewFeatureRepositoryWithJSONFile(dataDir, testLogger)
+
+	cfg := newTestConfig()
+	cfg.Quotas.FeaturesPerProject = quota.Limit(2)
+	s, err := server.New(cfg, testLogger, repo)
+	asst.NoError(err)
+	asst.NoError(s.Start())
+	asst.Equal(http.StatusOK, ready(t, s.URL()))
+
+	// quotas are checked again when files are changed
+	features := filepath.Join(dataDir, "projects", "test-project", "features")
+	b, err := os.ReadFile(filepath.Join(features, "test-feature-1.json"))
+	asst.NoError(err)
+	asst.NoError(os.WriteFile(filepath.Join(features, "copied.json"), b, 0o644))
+	asst.Eventually(func() bool {
+		return ready(t, s.URL()) == http.StatusServiceUnavailable
+	}, 5*server.ReloadInterval, 100*time.Millisecond)
+
+	asst.NoError(os.Remove(filepath.Join(features, "copied.json")))
+	asst.Eventually(func() bool {
+		return ready(t, s.URL()) == http.StatusOK
+	}, 5*server.ReloadInterval, 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	asst.NoError(s.Shutdown(ctx))
+}
+
+func evaluate(t *testing.T, url, project, feature string) int {
+	t.Helper()
+
+	res, err := http.Post(url+"/projects/"+project+"/evaluations/"+feature, "application/json", strings.NewReader(`{"entityId":"test"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	// the connection is reused by the next request, so that shutdown does not wait for new connections
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return res.StatusCode
+}
+
+// ready returns the status code of /_ready after the repository is loaded.
+func ready(t *testing.T, url string) int {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		res, err := http.Get(url + "/_ready")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if !strings.Contains(string(b), `"status":"loading"`) {
+			return res.StatusCode
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("repository is not loaded")
+	return 0
+}
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+
+	res, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
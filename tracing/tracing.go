@@ -0,0 +1,70 @@
+// Package tracing traces API operations and evaluation steps with OpenTelemetry.
+//
+// Spans are created with the global TracerProvider, so that an application that embeds the server
+// can export them with its own provider. Setup sets a provider that exports spans over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/michimani/evidentlylocal"
+	serviceName         = "evidently-local"
+)
+
+// Attribute keys of spans.
+const (
+	AttributeProject   = attribute.Key("evidently.project")
+	AttributeFeature   = attribute.Key("evidently.feature")
+	AttributeVariation = attribute.Key("evidently.variation")
+	AttributeReason    = attribute.Key("evidently.reason")
+	AttributeRequestID = attribute.Key("aws.request_id")
+	AttributeOperation = attribute.Key("rpc.method")
+)
+
+// Tracer returns the tracer of Evidently-Local.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Setup sets the global TracerProvider that exports spans to the OTLP/HTTP endpoint (e.g. http://localhost:4318),
+// and the W3C Trace Context propagator. It returns a function that flushes and shuts down the provider.
+func Setup(ctx context.Context, endpoint, version string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,38 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/michimani/evidentlylocal/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Setup(t *testing.T) {
+	asst := assert.New(t)
+
+	// a stand-in of an OTLP collector
+	received := atomic.Int32{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			received.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	shutdown, err := tracing.Setup(context.Background(), collector.URL, "test")
+	if !asst.NoError(err) {
+		return
+	}
+
+	_, span := tracing.Start(context.Background(), "test", tracing.AttributeProject.String("p"))
+	span.End()
+
+	// spans are flushed by shutdown
+	asst.NoError(shutdown(context.Background()))
+	asst.Equal(int32(1), received.Load())
+}
@@ -3,6 +3,8 @@ package types
 type VariableValueType string
 type FeatureValueType string
 type EvaluationReason string
+type EventType string
+type SegmentReferenceResourceType string
 
 const (
 	VariableValueTypeString VariableValueType = "stringValue"
@@ -18,4 +20,10 @@ const (
 	EvaluationReasonDefault         EvaluationReason = "DEFAULT"
 	EvaluationReasonOverride        EvaluationReason = "OVERRIDE_RULE"
 	EvaluationReasonLaunchRuleMatch EvaluationReason = "LAUNCH_RULE_MATCH"
+
+	EventTypeEvaluation EventType = "aws.evidently.evaluation"
+	EventTypeCustom     EventType = "aws.evidently.custom"
+
+	SegmentReferenceResourceTypeLaunch     SegmentReferenceResourceType = "LAUNCH"
+	SegmentReferenceResourceTypeExperiment SegmentReferenceResourceType = "EXPERIMENT"
 )
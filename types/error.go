@@ -0,0 +1,30 @@
+package types
+
+type ErrorType string
+type ValidationExceptionReason string
+
+const (
+	ErrorTypeValidationException           ErrorType = "ValidationException"
+	ErrorTypeResourceNotFoundException     ErrorType = "ResourceNotFoundException"
+	ErrorTypeServiceQuotaExceededException ErrorType = "ServiceQuotaExceededException"
+	ErrorTypeInternalServerException       ErrorType = "InternalServerException"
+
+	ValidationExceptionReasonUnknownOperation      ValidationExceptionReason = "unknownOperation"
+	ValidationExceptionReasonCannotParse           ValidationExceptionReason = "cannotParse"
+	ValidationExceptionReasonFieldValidationFailed ValidationExceptionReason = "fieldValidationFailed"
+	ValidationExceptionReasonOther                 ValidationExceptionReason = "other"
+)
+
+type ErrorResponse struct {
+	Message   string                     `json:"message"`
+	Reason    ValidationExceptionReason  `json:"reason,omitempty"`
+	FieldList []ValidationExceptionField `json:"fieldList,omitempty"`
+	// ResourceID and ResourceType are set in ResourceNotFoundException.
+	ResourceID   string `json:"resourceId,omitempty"`
+	ResourceType string `json:"resourceType,omitempty"`
+}
+
+type ValidationExceptionField struct {
+	Message string `json:"message"`
+	Name    string `json:"name"`
+}
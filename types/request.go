@@ -1,8 +1,8 @@
 package types
 
 type EvaluateFeatureRequest struct {
-	EntityID        string `json:"entityId"`
-	EvaluateContext string `json:"evaluateContext"`
+	EntityID          string `json:"entityId"`
+	EvaluationContext string `json:"evaluationContext"`
 }
 
 type BatchEvaluateFeatureRequest struct {
@@ -14,3 +14,21 @@ type EvaluationRequest struct {
 	EvaluationContext string `json:"evaluationContext"`
 	Feature           string `json:"feature"`
 }
+
+type PutProjectEventsRequest struct {
+	Events []Event `json:"events"`
+}
+
+type Event struct {
+	Data      string    `json:"data"`
+	Timestamp float64   `json:"timestamp"`
+	Type      EventType `json:"type"`
+}
+
+// ListSegmentReferencesRequest is the request of ListSegmentReferences, that is given by the path and query parameters.
+type ListSegmentReferencesRequest struct {
+	Segment    string
+	Type       SegmentReferenceResourceType
+	MaxResults int
+	NextToken  string
+}
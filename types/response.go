@@ -22,3 +22,15 @@ type EvaluationResult struct {
 	Variation string           `json:"variation"`
 	Value     VariableValue    `json:"value"`
 }
+
+type ListSegmentReferencesResponse struct {
+	NextToken    string        `json:"nextToken,omitempty"`
+	ReferencedBy []RefResource `json:"referencedBy"`
+}
+
+type RefResource struct {
+	Arn    string                       `json:"arn,omitempty"`
+	Name   string                       `json:"name"`
+	Status string                       `json:"status,omitempty"`
+	Type   SegmentReferenceResourceType `json:"type"`
+}